@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// revisionAnnotation is set on ReplicaSets by the Deployment controller
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+	// changeCauseAnnotation is copied from the Deployment to its ReplicaSets
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// requestRevision is one ReplicaSet revision of a Deployment with the
+// requests of one of its pods
+type requestRevision struct {
+	Revision    int64
+	Created     time.Time
+	Requests    ResourceMetrics
+	ChangeCause string
+}
+
+// requestChange is a revision whose pod requests differ from the revision
+// before it; Previous is nil for the oldest revision still kept
+type requestChange struct {
+	Deployment string
+	Revision   requestRevision
+	Previous   *ResourceMetrics
+}
+
+// loadRequestRevisions lists ReplicaSets and groups their revisions by the
+// namespace/name of the Deployment that owns them. Only name, if set, is
+// kept.
+func loadRequestRevisions(ctx context.Context, clientset *kubernetes.Clientset, namespace, name string) (map[string][]requestRevision, error) {
+	revisions := make(map[string][]requestRevision)
+	listOptions := metav1.ListOptions{Limit: listPageSize}
+	for {
+		replicaSets, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error listing replicasets: %w", err)
+		}
+		for _, rs := range replicaSets.Items {
+			owner := metav1.GetControllerOf(&rs)
+			if owner == nil || owner.Kind != "Deployment" || (name != "" && owner.Name != name) {
+				continue
+			}
+			key := rs.Namespace + "/" + owner.Name
+			revisions[key] = append(revisions[key], replicaSetRevision(rs))
+		}
+		if replicaSets.Continue == "" {
+			return revisions, nil
+		}
+		listOptions.Continue = replicaSets.Continue
+	}
+}
+
+func replicaSetRevision(rs appsv1.ReplicaSet) requestRevision {
+	revision, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	return requestRevision{
+		Revision:    revision,
+		Created:     rs.CreationTimestamp.Time,
+		Requests:    podRequests(corev1.Pod{Spec: rs.Spec.Template.Spec}),
+		ChangeCause: rs.Annotations[changeCauseAnnotation],
+	}
+}
+
+// requestChanges walks a Deployment's revisions oldest first and keeps the
+// oldest one and those that changed pod requests. Revisions that only
+// changed the image or other fields are skipped.
+func requestChanges(deployment string, revisions []requestRevision) []requestChange {
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	var changes []requestChange
+	for i, revision := range revisions {
+		if i == 0 {
+			changes = append(changes, requestChange{Deployment: deployment, Revision: revision})
+			continue
+		}
+		previous := revisions[i-1].Requests
+		if revision.Requests != previous {
+			changes = append(changes, requestChange{Deployment: deployment, Revision: revision, Previous: &previous})
+		}
+	}
+	return changes
+}
+
+// runAuditCommand implements the "audit" subcommand, reconstructing when
+// each Deployment's pod requests changed from its ReplicaSet revisions
+func runAuditCommand(args []string) {
+	var namespace string
+	var allNamespaces bool
+	var kubeconfig string
+	var kubeContext string
+
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.StringVar(&namespace, "namespace", "", "Namespace (defaults to current context or 'default')")
+	fs.BoolVar(&allNamespaces, "A", false, "Audit across all namespaces")
+	fs.BoolVar(&allNamespaces, "all-namespaces", false, "Audit across all namespaces")
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	parseFlags(fs, args)
+
+	if fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: k8s-resource-cli audit [--namespace ns | -A] [deployment]\n")
+		os.Exit(exitUsage)
+	}
+
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	if allNamespaces {
+		namespace = ""
+	} else if namespace == "" {
+		if namespace, err = getNamespaceFromKubeconfig(kubeconfig, kubeContext); err != nil {
+			namespace = "default"
+		}
+	}
+
+	revisions, err := loadRequestRevisions(context.Background(), clientset, namespace, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+	deployments := make([]string, 0, len(revisions))
+	for deployment := range revisions {
+		deployments = append(deployments, deployment)
+	}
+	sort.Strings(deployments)
+	var changes []requestChange
+	for _, deployment := range deployments {
+		changes = append(changes, requestChanges(deployment, revisions[deployment])...)
+	}
+	printRequestChanges(os.Stdout, changes)
+}
+
+func printRequestChanges(out io.Writer, changes []requestChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No Deployment revisions found")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "DEPLOYMENT\tREVISION\tCREATED\tCPU/POD\tMEMORY/POD\tCHANGE-CAUSE\n")
+	for _, change := range changes {
+		requests := change.Revision.Requests
+		cpu, memory := formatCPU(requests.CPU), formatMemory(requests.Memory)
+		if previous := change.Previous; previous != nil {
+			if previous.CPU != requests.CPU {
+				cpu = formatCPU(previous.CPU) + " -> " + cpu
+			}
+			if previous.Memory != requests.Memory {
+				memory = formatMemory(previous.Memory) + " -> " + memory
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", change.Deployment, change.Revision.Revision,
+			change.Revision.Created.UTC().Format(time.RFC3339), cpu, memory, valueOrDash(change.Revision.ChangeCause))
+	}
+	w.Flush()
+}
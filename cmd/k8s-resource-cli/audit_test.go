@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReplicaSetRevision(t *testing.T) {
+	created := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	rs := appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			CreationTimestamp: metav1.NewTime(created),
+			Annotations:       map[string]string{revisionAnnotation: "7", changeCauseAnnotation: "bump memory"},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+		}}}}},
+	}
+	want := requestRevision{Revision: 7, Created: created, Requests: ResourceMetrics{CPU: 250, Memory: 1 << 30}, ChangeCause: "bump memory"}
+	if got := replicaSetRevision(rs); got != want {
+		t.Errorf("replicaSetRevision() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRequestChanges(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 9, d, 0, 0, 0, 0, time.UTC) }
+	revisions := []requestRevision{
+		{Revision: 3, Created: day(3), Requests: ResourceMetrics{CPU: 100, Memory: 512 << 20}},
+		{Revision: 1, Created: day(1), Requests: ResourceMetrics{CPU: 100, Memory: 256 << 20}},
+		// An image-only rollout
+		{Revision: 2, Created: day(2), Requests: ResourceMetrics{CPU: 100, Memory: 256 << 20}},
+	}
+
+	changes := requestChanges("shop/web", revisions)
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want 2: %+v", len(changes), changes)
+	}
+	if changes[0].Revision.Revision != 1 || changes[0].Previous != nil {
+		t.Errorf("first change = %+v, want revision 1 without a previous value", changes[0])
+	}
+	if changes[1].Revision.Revision != 3 || changes[1].Previous == nil || changes[1].Previous.Memory != 256<<20 {
+		t.Errorf("second change = %+v, want revision 3 from 256Mi", changes[1])
+	}
+
+	var buf bytes.Buffer
+	printRequestChanges(&buf, changes)
+	want := "DEPLOYMENT   REVISION   CREATED                CPU/POD   MEMORY/POD               CHANGE-CAUSE\n" +
+		"shop/web     1          2026-09-01T00:00:00Z   100m      256.00 MB                -\n" +
+		"shop/web     3          2026-09-03T00:00:00Z   100m      256.00 MB -> 512.00 MB   -\n"
+	if buf.String() != want {
+		t.Errorf("audit output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	autoscalerStatusNamespace = "kube-system"
+	autoscalerStatusConfigMap = "cluster-autoscaler-status"
+)
+
+// nodeGroupBounds is one autoscaling node group: a cluster-autoscaler node
+// group with node count bounds, or a Karpenter NodePool with resource limits.
+// Unknown values are -1 (counts) or zero (resources).
+type nodeGroupBounds struct {
+	Source   string
+	Name     string
+	MinNodes int
+	MaxNodes int
+	Nodes    int
+	Used     ResourceMetrics
+	Limit    ResourceMetrics
+}
+
+// autoscalerStatusYAML mirrors the structured status written by
+// cluster-autoscaler 1.30 and later
+type autoscalerStatusYAML struct {
+	NodeGroups []struct {
+		Name   string `json:"name"`
+		Health struct {
+			NodeCounts struct {
+				Registered struct {
+					Ready int `json:"ready"`
+				} `json:"registered"`
+			} `json:"nodeCounts"`
+			MinSize int `json:"minSize"`
+			MaxSize int `json:"maxSize"`
+		} `json:"health"`
+	} `json:"nodeGroups"`
+}
+
+var (
+	legacyNodeGroupName = regexp.MustCompile(`^\s*Name:\s+(\S+)`)
+	legacyNodeGroupSize = regexp.MustCompile(`minSize=(\d+), maxSize=(\d+)`)
+	legacyReadyNodes    = regexp.MustCompile(`\bready=(\d+)`)
+)
+
+// parseAutoscalerStatus reads node group bounds from the "status" key of the
+// cluster-autoscaler-status ConfigMap, in either the structured YAML format
+// or the older human-readable one
+func parseAutoscalerStatus(status string) []nodeGroupBounds {
+	var structured autoscalerStatusYAML
+	if err := yaml.Unmarshal([]byte(status), &structured); err == nil && len(structured.NodeGroups) > 0 {
+		groups := make([]nodeGroupBounds, 0, len(structured.NodeGroups))
+		for _, ng := range structured.NodeGroups {
+			groups = append(groups, nodeGroupBounds{
+				Source:   "cluster-autoscaler",
+				Name:     ng.Name,
+				MinNodes: ng.Health.MinSize,
+				MaxNodes: ng.Health.MaxSize,
+				Nodes:    ng.Health.NodeCounts.Registered.Ready,
+			})
+		}
+		return groups
+	}
+
+	// The legacy format lists "Name:" then a "Health:" line per node group,
+	// after the cluster-wide section which has a Health line of its own
+	var groups []nodeGroupBounds
+	var current *nodeGroupBounds
+	for _, line := range strings.Split(status, "\n") {
+		if m := legacyNodeGroupName.FindStringSubmatch(line); m != nil {
+			groups = append(groups, nodeGroupBounds{Source: "cluster-autoscaler", Name: m[1], MinNodes: -1, MaxNodes: -1, Nodes: -1})
+			current = &groups[len(groups)-1]
+			continue
+		}
+		if current == nil || !strings.Contains(line, "Health:") {
+			continue
+		}
+		if m := legacyNodeGroupSize.FindStringSubmatch(line); m != nil {
+			current.MinNodes, _ = strconv.Atoi(m[1])
+			current.MaxNodes, _ = strconv.Atoi(m[2])
+		}
+		if m := legacyReadyNodes.FindStringSubmatch(line); m != nil {
+			current.Nodes, _ = strconv.Atoi(m[1])
+		}
+	}
+	return groups
+}
+
+// karpenterNodePools lists NodePools with their spec.limits and the
+// resources their nodes provide, trying karpenter.sh/v1 before v1beta1
+func karpenterNodePools(ctx context.Context, client dynamic.Interface) ([]nodeGroupBounds, error) {
+	var list *unstructured.UnstructuredList
+	var err error
+	for _, version := range []string{"v1", "v1beta1"} {
+		gvr := schema.GroupVersionResource{Group: "karpenter.sh", Version: version, Resource: "nodepools"}
+		if list, err = client.Resource(gvr).List(ctx, metav1.ListOptions{}); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing Karpenter NodePools: %w", err)
+	}
+
+	groups := make([]nodeGroupBounds, 0, len(list.Items))
+	for _, item := range list.Items {
+		groups = append(groups, karpenterNodePool(item))
+	}
+	return groups, nil
+}
+
+// karpenterNodePool reads the bounds of one NodePool
+func karpenterNodePool(item unstructured.Unstructured) nodeGroupBounds {
+	limits := nestedQuantities(item, "spec", "limits")
+	used := nestedQuantities(item, "status", "resources")
+	group := nodeGroupBounds{
+		Source:   "karpenter",
+		Name:     item.GetName(),
+		MinNodes: -1,
+		MaxNodes: -1,
+		Nodes:    -1,
+		Limit:    quantityMetrics(limits),
+		Used:     quantityMetrics(used),
+	}
+	if nodes, ok := limits["nodes"]; ok {
+		if q, err := resource.ParseQuantity(nodes); err == nil {
+			group.MaxNodes = int(q.Value())
+		}
+	}
+	if nodes, ok := used["nodes"]; ok {
+		if q, err := resource.ParseQuantity(nodes); err == nil {
+			group.Nodes = int(q.Value())
+		}
+	}
+	return group
+}
+
+// nestedQuantities reads a resource list of a NodePool as strings. Values
+// may be numbers as well as quantity strings, since limits like
+// {cpu: 1000} decode as integers.
+func nestedQuantities(item unstructured.Unstructured, fields ...string) map[string]string {
+	values, _, err := unstructured.NestedMap(item.Object, fields...)
+	if err != nil {
+		warnf("NodePool %s: unexpected %s: %v", item.GetName(), strings.Join(fields, "."), err)
+		return nil
+	}
+	quantities := make(map[string]string, len(values))
+	for name, value := range values {
+		quantities[name] = fmt.Sprint(value)
+	}
+	return quantities
+}
+
+// quantityMetrics reads the cpu and memory quantities of a resource list
+// given as strings, skipping any that don't parse
+func quantityMetrics(values map[string]string) ResourceMetrics {
+	var metrics ResourceMetrics
+	if q, err := resource.ParseQuantity(values["cpu"]); err == nil {
+		metrics.CPU = q.MilliValue()
+	}
+	if q, err := resource.ParseQuantity(values["memory"]); err == nil {
+		metrics.Memory = q.Value()
+	}
+	return metrics
+}
+
+// clusterRequests sums the requests of every pod that is scheduled and not finished
+func clusterRequests(ctx context.Context, clientset *kubernetes.Clientset) (ResourceMetrics, error) {
+	var total ResourceMetrics
+	err := forEachScheduledPod(ctx, clientset, func(pod corev1.Pod) {
+		total.add(podRequests(pod))
+	})
+	return total, err
+}
+
+// runAutoscalerCommand implements the "autoscaler" subcommand, showing the
+// node group bounds of cluster-autoscaler or Karpenter next to the cluster's
+// current requests and allocatable
+func runAutoscalerCommand(args []string) {
+	var kubeconfig string
+	var kubeContext string
+
+	fs := flag.NewFlagSet("autoscaler", flag.ContinueOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	parseFlags(fs, args)
+
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	var groups []nodeGroupBounds
+
+	configMap, err := clientset.CoreV1().ConfigMaps(autoscalerStatusNamespace).Get(ctx, autoscalerStatusConfigMap, metav1.GetOptions{})
+	if err == nil {
+		groups = append(groups, parseAutoscalerStatus(configMap.Data["status"])...)
+	}
+
+	caps, _ := detectCapabilities(clientset)
+	if caps.Karpenter {
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err == nil {
+			var pools []nodeGroupBounds
+			pools, err = karpenterNodePools(ctx, dynamicClient)
+			groups = append(groups, pools...)
+		}
+		if err != nil {
+			warnf("%v", err)
+		}
+	}
+
+	allocatable, err := loadNodeAllocatable(ctx, clientset)
+	if err != nil {
+		warnf("%v", err)
+	}
+	requests, err := clusterRequests(ctx, clientset)
+	if err != nil {
+		warnf("%v", err)
+	}
+
+	printAutoscaler(os.Stdout, groups, requests, allocatable)
+}
+
+func printAutoscaler(out io.Writer, groups []nodeGroupBounds, requests, allocatable ResourceMetrics) {
+	fmt.Fprintf(out, "Cluster requests: %s CPU, %s memory of %s CPU, %s memory allocatable\n\n",
+		formatCPU(requests.CPU), formatMemory(requests.Memory), formatCPU(allocatable.CPU), formatMemory(allocatable.Memory))
+
+	if len(groups) == 0 {
+		fmt.Fprintln(out, "No cluster-autoscaler status or Karpenter NodePools found")
+		return
+	}
+
+	count := func(n int) string {
+		if n < 0 {
+			return "-"
+		}
+		return strconv.Itoa(n)
+	}
+	usedOfLimit := func(used, limit int64, format func(int64) string) string {
+		if limit == 0 {
+			return "-"
+		}
+		return format(used) + " / " + format(limit)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "SOURCE\tNODE GROUP\tMIN\tMAX\tNODES\tCPU LIMIT\tMEMORY LIMIT\n")
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			g.Source, g.Name, count(g.MinNodes), count(g.MaxNodes), count(g.Nodes),
+			usedOfLimit(g.Used.CPU, g.Limit.CPU, formatCPU),
+			usedOfLimit(g.Used.Memory, g.Limit.Memory, formatMemory))
+	}
+	w.Flush()
+}
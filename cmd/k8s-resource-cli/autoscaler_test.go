@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const structuredAutoscalerStatus = `time: 2026-10-01 10:00:00.000000000 +0000 UTC
+autoscalerStatus: Running
+clusterWide:
+  health:
+    status: Healthy
+nodeGroups:
+- name: general
+  health:
+    status: Healthy
+    nodeCounts:
+      registered:
+        total: 3
+        ready: 3
+    cloudProviderTarget: 3
+    minSize: 2
+    maxSize: 10
+- name: gpu
+  health:
+    status: Healthy
+    nodeCounts:
+      registered:
+        total: 0
+        ready: 0
+    cloudProviderTarget: 0
+    minSize: 0
+    maxSize: 4
+`
+
+const legacyAutoscalerStatus = `Cluster-autoscaler status at 2026-10-01 10:00:00 +0000 UTC:
+Cluster-wide:
+  Health:      Healthy (ready=4 unready=0 notStarted=0 longNotStarted=0 registered=4 longUnregistered=0)
+  ScaleUp:     NoActivity (ready=4 registered=4)
+
+NodeGroups:
+  Name:        eks-general-abc
+  Health:      Healthy (ready=3 unready=0 notStarted=0 longNotStarted=0 registered=3 longUnregistered=0 cloudProviderTarget=3 (minSize=2, maxSize=10))
+  ScaleUp:     NoActivity (ready=3 cloudProviderTarget=3)
+
+  Name:        eks-batch-def
+  Health:      Healthy (ready=1 unready=0 notStarted=0 longNotStarted=0 registered=1 longUnregistered=0 cloudProviderTarget=1 (minSize=0, maxSize=5))
+`
+
+func TestParseAutoscalerStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   []nodeGroupBounds
+	}{
+		{
+			name:   "structured",
+			status: structuredAutoscalerStatus,
+			want: []nodeGroupBounds{
+				{Source: "cluster-autoscaler", Name: "general", MinNodes: 2, MaxNodes: 10, Nodes: 3},
+				{Source: "cluster-autoscaler", Name: "gpu", MinNodes: 0, MaxNodes: 4, Nodes: 0},
+			},
+		},
+		{
+			name:   "legacy",
+			status: legacyAutoscalerStatus,
+			want: []nodeGroupBounds{
+				{Source: "cluster-autoscaler", Name: "eks-general-abc", MinNodes: 2, MaxNodes: 10, Nodes: 3},
+				{Source: "cluster-autoscaler", Name: "eks-batch-def", MinNodes: 0, MaxNodes: 5, Nodes: 1},
+			},
+		},
+		{
+			name:   "empty",
+			status: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAutoscalerStatus(tt.status)
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseAutoscalerStatus() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parseAutoscalerStatus()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQuantityMetrics(t *testing.T) {
+	got := quantityMetrics(map[string]string{"cpu": "100", "memory": "400Gi", "nodes": "10"})
+	if got.CPU != 100000 || got.Memory != 400<<30 {
+		t.Errorf("quantityMetrics() = %+v", got)
+	}
+	if got := quantityMetrics(nil); got != (ResourceMetrics{}) {
+		t.Errorf("quantityMetrics(nil) = %+v, want zero", got)
+	}
+}
+
+func TestKarpenterNodePool(t *testing.T) {
+	// Karpenter's own examples write limits as {cpu: 1000, memory: 1000Gi},
+	// which the API returns with cpu as an integer
+	item := unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "default"},
+		"spec": map[string]interface{}{"limits": map[string]interface{}{
+			"cpu": int64(1000), "memory": "1000Gi", "nodes": int64(20),
+		}},
+		"status": map[string]interface{}{"resources": map[string]interface{}{
+			"cpu": "48", "memory": "96Gi", "nodes": "3",
+		}},
+	}}
+
+	got := karpenterNodePool(item)
+	if got.Limit != (ResourceMetrics{CPU: 1000000, Memory: 1000 << 30}) {
+		t.Errorf("Limit = %+v, want CPU 1000 cores, Memory 1000Gi", got.Limit)
+	}
+	if got.Used != (ResourceMetrics{CPU: 48000, Memory: 96 << 30}) {
+		t.Errorf("Used = %+v, want CPU 48 cores, Memory 96Gi", got.Used)
+	}
+	if got.MaxNodes != 20 || got.Nodes != 3 {
+		t.Errorf("MaxNodes, Nodes = %d, %d, want 20, 3", got.MaxNodes, got.Nodes)
+	}
+}
+
+func TestPrintAutoscaler(t *testing.T) {
+	var buf bytes.Buffer
+	printAutoscaler(&buf, []nodeGroupBounds{
+		{Source: "cluster-autoscaler", Name: "general", MinNodes: 2, MaxNodes: 10, Nodes: 3},
+		{Source: "karpenter", Name: "default", MinNodes: -1, MaxNodes: -1, Nodes: 4,
+			Used: ResourceMetrics{CPU: 16000}, Limit: ResourceMetrics{CPU: 100000}},
+	}, ResourceMetrics{CPU: 12000}, ResourceMetrics{CPU: 16000})
+
+	output := buf.String()
+	for _, want := range []string{"Cluster requests: 12.00 cores", "general", "karpenter", "16.00 cores / 100.00 cores"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("printAutoscaler() output missing %q:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// baselineDataKey is the ConfigMap key the baseline snapshot is stored under
+const baselineDataKey = "baseline.json"
+
+// parseConfigMapRef splits a namespace/name reference to a ConfigMap
+func parseConfigMapRef(ref string) (namespace, name string, err error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid ConfigMap %q, expected namespace/name", ref)
+	}
+	return namespace, name, nil
+}
+
+// baselineStore keeps a baseline snapshot of the requests per namespace in a
+// ConfigMap, so everyone comparing against it sees the same numbers without
+// passing files around
+type baselineStore struct {
+	clientset *kubernetes.Clientset
+	namespace string
+	name      string
+}
+
+// Load returns the stored baseline, or nil when the ConfigMap doesn't exist yet
+func (s *baselineStore) Load(ctx context.Context) (*historySnapshot, error) {
+	configMap, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := configMap.Data[baselineDataKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no %s key", s.namespace, s.name, baselineDataKey)
+	}
+	var snapshot historySnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid baseline in ConfigMap %s/%s: %w", s.namespace, s.name, err)
+	}
+	return &snapshot, nil
+}
+
+// Save stores the snapshot as the baseline, creating the ConfigMap if needed
+// and leaving any other keys in it alone
+func (s *baselineStore) Save(ctx context.Context, snapshot historySnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+			Data:       map[string]string{baselineDataKey: string(data)},
+		}
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[baselineDataKey] = string(data)
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// printBaselineComparison prints the requests per namespace of this run next to
+// the baseline's, with namespaces that only one of them has shown as "-", and
+// lists the workloads that were recreated since the baseline was recorded
+func printBaselineComparison(out io.Writer, format, ref string, baseline, current historySnapshot) {
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Baseline %s recorded %s\n", ref, baseline.Time.UTC().Format(time.RFC3339))
+
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, snapshot := range []historySnapshot{baseline, current} {
+		for namespace := range snapshot.Namespaces {
+			if !seen[namespace] {
+				seen[namespace] = true
+				namespaces = append(namespaces, namespace)
+			}
+		}
+	}
+	sort.Strings(namespaces)
+
+	table := resultTable{headers: []string{"NAMESPACE", "CPU BASELINE", "CPU NOW", "CPU CHANGE", "MEMORY BASELINE", "MEMORY NOW", "MEMORY CHANGE"}}
+	var before, after ResourceMetrics
+	for _, namespace := range namespaces {
+		was, inBaseline := baseline.Namespaces[namespace]
+		is, inCurrent := current.Namespaces[namespace]
+		before.add(was)
+		after.add(is)
+		row := []string{namespace, "-", "-", formatSignedCPU(is.CPU - was.CPU), "-", "-", formatSignedMemory(is.Memory - was.Memory)}
+		if inBaseline {
+			row[1], row[4] = formatCPU(was.CPU), formatMemory(was.Memory)
+		}
+		if inCurrent {
+			row[2], row[5] = formatCPU(is.CPU), formatMemory(is.Memory)
+		}
+		table.rows = append(table.rows, row)
+	}
+	table.total = []string{"TOTAL",
+		formatCPU(before.CPU), formatCPU(after.CPU), formatSignedCPU(after.CPU - before.CPU),
+		formatMemory(before.Memory), formatMemory(after.Memory), formatSignedMemory(after.Memory - before.Memory),
+	}
+
+	if format == FormatMarkdown {
+		printMarkdownResults(out, table, false)
+	} else {
+		printTableResults(out, table, false)
+	}
+
+	if recreated := recreatedWorkloads(baseline, current); len(recreated) > 0 {
+		fmt.Fprintf(out, "Recreated since the baseline: %s\n", strings.Join(recreated, ", "))
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseConfigMapRef(t *testing.T) {
+	tests := []struct {
+		ref           string
+		wantNamespace string
+		wantName      string
+		wantErr       bool
+	}{
+		{"platform/resource-baseline", "platform", "resource-baseline", false},
+		{"resource-baseline", "", "", true},
+		{"/resource-baseline", "", "", true},
+		{"platform/", "", "", true},
+		{"platform/a/b", "", "", true},
+	}
+	for _, tt := range tests {
+		namespace, name, err := parseConfigMapRef(tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseConfigMapRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			continue
+		}
+		if namespace != tt.wantNamespace || name != tt.wantName {
+			t.Errorf("parseConfigMapRef(%q) = %q, %q, want %q, %q", tt.ref, namespace, name, tt.wantNamespace, tt.wantName)
+		}
+	}
+}
+
+func TestPrintBaselineComparison(t *testing.T) {
+	baseline := historySnapshot{
+		Time: time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC),
+		Namespaces: map[string]ResourceMetrics{
+			"billing": {CPU: 500, Memory: 512 * 1024 * 1024},
+			"legacy":  {CPU: 100, Memory: 128 * 1024 * 1024},
+		},
+		Workloads: map[string]string{"Deployment/billing/api": "a1", "Deployment/billing/worker": "b1"},
+	}
+	current := historySnapshot{Namespaces: map[string]ResourceMetrics{
+		"billing": {CPU: 750, Memory: 256 * 1024 * 1024},
+		"search":  {CPU: 250, Memory: 1024 * 1024 * 1024},
+	}, Workloads: map[string]string{"Deployment/billing/api": "a2", "Deployment/billing/worker": "b1", "Deployment/search/web": "c1"}}
+
+	var buf bytes.Buffer
+	printBaselineComparison(&buf, FormatTable, "platform/resource-baseline", baseline, current)
+
+	want := `
+Baseline platform/resource-baseline recorded 2026-09-01T12:00:00Z
+NAMESPACE   CPU BASELINE   CPU NOW      CPU CHANGE   MEMORY BASELINE   MEMORY NOW   MEMORY CHANGE
+billing     500m           750m         250m         512.00 MB         256.00 MB    -256.00 MB
+legacy      100m           -            -100m        128.00 MB         -            -128.00 MB
+search      -              250m         250m         -                 1.00 GB      1.00 GB
+TOTAL       600m           1.00 cores   400m         640.00 MB         1.25 GB      640.00 MB
+Recreated since the baseline: Deployment/billing/api
+`
+	if got := buf.String(); got != want {
+		t.Errorf("printBaselineComparison() =\n%s\nwant\n%s", got, want)
+	}
+}
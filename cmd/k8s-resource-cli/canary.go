@@ -0,0 +1,26 @@
+package main
+
+// canaryLabels mark canary workloads: track is the label the Kubernetes docs
+// use for canary deployments, role the one Argo Rollouts sets through
+// canaryMetadata in its examples
+var canaryLabels = map[string]string{
+	"track": "canary",
+	"role":  "canary",
+}
+
+// flaggerPrimarySuffix is appended by Flagger to the primary Deployment it
+// clones from a canary's target. The target itself becomes the canary and is
+// scaled up only while an analysis runs.
+const flaggerPrimarySuffix = "-primary"
+
+// isCanary reports whether the workload carries a canary label or is a
+// Flagger target with a primary next to it. deployments holds the
+// namespace/name of every Deployment in scope.
+func isCanary(dm DeploymentMetrics, deployments map[string]bool) bool {
+	for label, value := range canaryLabels {
+		if dm.Labels[label] == value {
+			return true
+		}
+	}
+	return dm.Type == "Deployment" && deployments[dm.Namespace+"/"+dm.Name+flaggerPrimarySuffix]
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestIsCanary(t *testing.T) {
+	deployments := map[string]bool{
+		"shop/podinfo":         true,
+		"shop/podinfo-primary": true,
+		"shop/web":             true,
+	}
+
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want bool
+	}{
+		{"plain deployment", DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment"}, false},
+		{"track label", DeploymentMetrics{Name: "web-canary", Namespace: "shop", Type: "Deployment", Labels: map[string]string{"track": "canary"}}, true},
+		{"role label on cronjob", DeploymentMetrics{Name: "report", Namespace: "shop", Type: "CronJob", Labels: map[string]string{"role": "canary"}}, true},
+		{"stable role", DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", Labels: map[string]string{"role": "stable"}}, false},
+		{"flagger target", DeploymentMetrics{Name: "podinfo", Namespace: "shop", Type: "Deployment"}, true},
+		{"flagger primary", DeploymentMetrics{Name: "podinfo-primary", Namespace: "shop", Type: "Deployment"}, false},
+		{"primary in another namespace", DeploymentMetrics{Name: "podinfo", Namespace: "docs", Type: "Deployment"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCanary(tt.dm, deployments); got != tt.want {
+				t.Errorf("isCanary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// clusterCapabilities records which optional API groups the cluster serves,
+// so features depending on them can be switched off up front
+type clusterCapabilities struct {
+	Metrics       bool // metrics.k8s.io, served by metrics-server
+	AutoscalingV1 bool
+	AutoscalingV2 bool
+	BatchV1       bool
+	VPA           bool // autoscaling.k8s.io CRDs
+	KEDA          bool // keda.sh CRDs
+	Karpenter     bool // karpenter.sh CRDs
+}
+
+type capabilityCheck struct {
+	Name         string
+	GroupVersion string
+	Feature      string
+	Available    func(c clusterCapabilities) bool
+}
+
+var capabilityChecks = []capabilityCheck{
+	{"metrics", "metrics.k8s.io/v1beta1", "usage output", func(c clusterCapabilities) bool { return c.Metrics }},
+	{"autoscaling/v1", "autoscaling/v1", "HPA max-requests", func(c clusterCapabilities) bool { return c.AutoscalingV1 }},
+	{"autoscaling/v2", "autoscaling/v2", "HPA metric targets", func(c clusterCapabilities) bool { return c.AutoscalingV2 }},
+	{"batch/v1", "batch/v1", "--include-cronjobs", func(c clusterCapabilities) bool { return c.BatchV1 }},
+	{"vpa", "autoscaling.k8s.io", "VerticalPodAutoscalers, reported only", func(c clusterCapabilities) bool { return c.VPA }},
+	{"keda", "keda.sh", "KEDA ScaledObjects, reported only", func(c clusterCapabilities) bool { return c.KEDA }},
+	{"karpenter", "karpenter.sh", "autoscaler NodePool limits", func(c clusterCapabilities) bool { return c.Karpenter }},
+}
+
+// allCapabilities is assumed when discovery itself fails, preserving the
+// behavior of trying every feature and warning per call
+var allCapabilities = clusterCapabilities{
+	Metrics:       true,
+	AutoscalingV1: true,
+	AutoscalingV2: true,
+	BatchV1:       true,
+}
+
+func detectCapabilities(clientset *kubernetes.Clientset) (clusterCapabilities, error) {
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return allCapabilities, fmt.Errorf("error discovering API groups: %w", err)
+	}
+
+	groupVersions := make(map[string]bool)
+	for _, group := range groups.Groups {
+		groupVersions[group.Name] = true
+		for _, version := range group.Versions {
+			groupVersions[version.GroupVersion] = true
+		}
+	}
+
+	return capabilitiesFromGroupVersions(groupVersions), nil
+}
+
+func capabilitiesFromGroupVersions(groupVersions map[string]bool) clusterCapabilities {
+	return clusterCapabilities{
+		Metrics:       groupVersions["metrics.k8s.io/v1beta1"],
+		AutoscalingV1: groupVersions["autoscaling/v1"],
+		AutoscalingV2: groupVersions["autoscaling/v2"],
+		BatchV1:       groupVersions["batch/v1"],
+		VPA:           groupVersions["autoscaling.k8s.io"],
+		KEDA:          groupVersions["keda.sh"],
+		Karpenter:     groupVersions["karpenter.sh"],
+	}
+}
+
+func printCapabilities(out io.Writer, caps clusterCapabilities) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "CAPABILITY\tAPI\tAVAILABLE\tFEATURE\n")
+	for _, check := range capabilityChecks {
+		available := "no"
+		if check.Available(caps) {
+			available = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.Name, check.GroupVersion, available, check.Feature)
+	}
+	w.Flush()
+}
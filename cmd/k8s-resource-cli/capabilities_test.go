@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCapabilitiesFromGroupVersions(t *testing.T) {
+	caps := capabilitiesFromGroupVersions(map[string]bool{
+		"autoscaling":            true,
+		"autoscaling/v1":         true,
+		"autoscaling/v2":         true,
+		"batch":                  true,
+		"batch/v1":               true,
+		"keda.sh":                true,
+		"keda.sh/v1alpha1":       true,
+		"karpenter.sh":           true,
+		"karpenter.sh/v1":        true,
+		"metrics.k8s.io":         true,
+		"metrics.k8s.io/v1beta1": true,
+	})
+
+	want := clusterCapabilities{
+		Metrics:       true,
+		AutoscalingV1: true,
+		AutoscalingV2: true,
+		BatchV1:       true,
+		VPA:           false,
+		KEDA:          true,
+		Karpenter:     true,
+	}
+	if caps != want {
+		t.Errorf("capabilitiesFromGroupVersions() = %+v, want %+v", caps, want)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// carbonCoefficients are Cloud Carbon Footprint's energy coefficients for a
+// cloud: the average watts a vCPU draws idle and at full load, and the
+// provider's power usage effectiveness
+type carbonCoefficients struct {
+	MinWatts float64
+	MaxWatts float64
+	PUE      float64
+}
+
+// cloudCarbonCoefficients are the Cloud Carbon Footprint coefficients per
+// provider, picked with --carbon-coefficients
+var cloudCarbonCoefficients = map[string]carbonCoefficients{
+	"aws":   {MinWatts: 0.74, MaxWatts: 3.5, PUE: 1.135},
+	"gcp":   {MinWatts: 0.71, MaxWatts: 4.26, PUE: 1.1},
+	"azure": {MinWatts: 0.78, MaxWatts: 3.76, PUE: 1.185},
+}
+
+// memoryKWhPerGBHour is Cloud Carbon Footprint's memory energy coefficient,
+// the same for every provider
+const memoryKWhPerGBHour = 0.000392
+
+// carbonModel turns a workload's figures into estimated grams of CO2e over
+// a period, assuming its current requests and usage hold for all of it
+type carbonModel struct {
+	coefficients carbonCoefficients
+	intensity    float64 // grid carbon intensity in gCO2e per kWh
+	hours        float64
+}
+
+// newCarbonModel reads --carbon, a grid intensity in gCO2e/kWh or a region
+// named in the config file's gridIntensity, and --carbon-coefficients
+func newCarbonModel(value, provider string, period time.Duration, regions map[string]float64) (*carbonModel, error) {
+	coefficients, ok := cloudCarbonCoefficients[provider]
+	if !ok {
+		return nil, fmt.Errorf("invalid --carbon-coefficients '%s'. Must be one of %s", provider, strings.Join(sortedKeys(cloudCarbonCoefficients), ", "))
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("--carbon-period must be positive")
+	}
+	intensity, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		var found bool
+		if intensity, found = regions[value]; !found {
+			if len(regions) == 0 {
+				return nil, fmt.Errorf("invalid --carbon '%s': not a gCO2e/kWh figure, and the config file has no gridIntensity regions", value)
+			}
+			return nil, fmt.Errorf("invalid --carbon '%s': not a gCO2e/kWh figure or a gridIntensity region of the config file (%s)", value, strings.Join(sortedKeys(regions), ", "))
+		}
+	}
+	if intensity < 0 {
+		return nil, fmt.Errorf("--carbon grid intensity must not be negative")
+	}
+	return &carbonModel{coefficients: coefficients, intensity: intensity, hours: period.Hours()}, nil
+}
+
+// Grams estimates the workload's emissions the way Cloud Carbon Footprint
+// does for compute: its requested vCPUs draw between the idle and full-load
+// watts in proportion to how much of them it uses, and its memory draws a
+// fixed amount per GB. Workloads using more than they request are counted
+// at their usage, and those without requests at their usage, fully loaded.
+func (m *carbonModel) Grams(dm DeploymentMetrics) float64 {
+	cores := float64(dm.Requests.CPU) / 1000
+	used := float64(dm.Usage.CPU) / 1000
+	utilization := 1.0
+	if cores > 0 {
+		utilization = min(used/cores, 1)
+	}
+	cores = max(cores, used)
+	watts := m.coefficients.MinWatts + utilization*(m.coefficients.MaxWatts-m.coefficients.MinWatts)
+	cpuKWh := cores * watts * m.hours / 1000
+
+	gigabytes := float64(max(dm.Requests.Memory, dm.Usage.Memory)) / (1 << 30)
+	memoryKWh := gigabytes * memoryKWhPerGBHour * m.hours
+
+	return (cpuKWh + memoryKWh) * m.coefficients.PUE * m.intensity
+}
+
+// formatCarbon shows grams of CO2e in g, kg or t
+func formatCarbon(grams float64) string {
+	switch {
+	case grams >= 1e6:
+		return fmt.Sprintf("%.2f t", grams/1e6)
+	case grams >= 1e3:
+		return fmt.Sprintf("%.2f kg", grams/1e3)
+	}
+	return fmt.Sprintf("%.0f g", grams)
+}
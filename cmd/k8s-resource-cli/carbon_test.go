@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCarbonModelGrams(t *testing.T) {
+	model, err := newCarbonModel("400", "aws", 1000*time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want float64
+	}{
+		{"half used", DeploymentMetrics{Requests: ResourceMetrics{CPU: 2000, Memory: 1 << 30}, Usage: ResourceMetrics{CPU: 1000, Memory: 1 << 29}}, 2102.928},
+		{"no requests", DeploymentMetrics{Usage: ResourceMetrics{CPU: 500}}, 794.5},
+		{"over requests", DeploymentMetrics{Requests: ResourceMetrics{CPU: 1000}, Usage: ResourceMetrics{CPU: 2000}}, 3178},
+		{"idle", DeploymentMetrics{}, 0},
+	}
+	for _, tt := range tests {
+		if got := model.Grams(tt.dm); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: Grams() = %f, want %f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewCarbonModel(t *testing.T) {
+	regions := map[string]float64{"eu-north-1": 30, "us-east-1": 380}
+	tests := []struct {
+		value     string
+		provider  string
+		period    time.Duration
+		intensity float64
+		wantErr   bool
+	}{
+		{"250.5", "gcp", time.Hour, 250.5, false},
+		{"eu-north-1", "aws", time.Hour, 30, false},
+		{"ap-south-1", "aws", time.Hour, 0, true},
+		{"-5", "aws", time.Hour, 0, true},
+		{"400", "oracle", time.Hour, 0, true},
+		{"400", "aws", 0, 0, true},
+	}
+	for _, tt := range tests {
+		model, err := newCarbonModel(tt.value, tt.provider, tt.period, regions)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newCarbonModel(%q, %q, %v) error = %v, wantErr %v", tt.value, tt.provider, tt.period, err, tt.wantErr)
+			continue
+		}
+		if err == nil && model.intensity != tt.intensity {
+			t.Errorf("newCarbonModel(%q, %q, %v) intensity = %f, want %f", tt.value, tt.provider, tt.period, model.intensity, tt.intensity)
+		}
+	}
+}
+
+func TestFormatCarbon(t *testing.T) {
+	tests := []struct {
+		grams float64
+		want  string
+	}{
+		{0, "0 g"},
+		{794.5, "794 g"},
+		{2102.928, "2.10 kg"},
+		{3.5e6, "3.50 t"},
+	}
+	for _, tt := range tests {
+		if got := formatCarbon(tt.grams); got != tt.want {
+			t.Errorf("formatCarbon(%f) = %q, want %q", tt.grams, got, tt.want)
+		}
+	}
+}
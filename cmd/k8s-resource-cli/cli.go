@@ -1,16 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
@@ -22,279 +25,1274 @@ func runCLI() {
 	var namespace string
 	var deploymentName string
 	var kubeconfig string
+	var kubeContext string
 	var usePorter bool
 	var porterToken string
 	var porterProjectID string
 	var porterBaseURL string
+	var porterProfileName string
+	var porterHeaders headerFlag
+	var porterTimeout time.Duration
+	var porterDeadline time.Duration
+	var porterWithK8s bool
 	var debug bool
+	var progressMode string
 	var showVersion bool
+	var nowValue string
 	var allNamespaces bool
 	var labelSelector string
 	var includeCronJobs bool
+	var includeStaticPods bool
+	var ownerGraph bool
 	var totalOnly bool
 	var format string
+	var showCapabilities bool
+	var kubeletFallback bool
+	var dryRun bool
+	var follow bool
+	var stream bool
+	var perReplica bool
+	var showLabels bool
+	var carbonValue string
+	var carbonCoefficients string
+	var carbonPeriod time.Duration
+	var labelColumnsValue string
+	var imageSizes bool
+	var includeKubernetes bool
+	var groupByLabels string
+	var groupBy string
+	var breakdown string
+	var promBy string
+	var stats bool
+	var namespaceLabels string
+	var reservationsFile string
+	var sortBy string
+	var sortOrderValue string
+	var top int
+	var columnsValue string
+	var colorMode string
+	var outputFile string
+	var unitsValue string
+	var warnThreshold float64
+	var critThreshold float64
+	var failOnThreshold bool
+	var historyFile string
+	var baselineConfigMap string
+	var updateBaseline bool
+	var templateFile string
+	var configFile string
+	var signKey string
+	var signatureFile string
+	var jobHistory int
+	var suspendedAsZero bool
+	var excludeSuspended bool
+	var excludePaused bool
+	var excludeZeroReplicas bool
+	var onlyZeroReplicas bool
+	var cronWindowValue string
+	var batchOverlay bool
+	var checkHPAs bool
+	var hpaCoverageReport bool
+	var peakTolerance int
+	var excludeCanary bool
+	var normalize bool
+	var normalizeCPUStep string
+	var normalizeMemoryStep string
 
-	// Default kubeconfig path: KUBECONFIG env var, then ~/.kube/config
-	defaultKubeconfig := os.Getenv("KUBECONFIG")
-	if defaultKubeconfig == "" {
-		if home := os.Getenv("HOME"); home != "" {
-			defaultKubeconfig = filepath.Join(home, ".kube", "config")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "contexts":
+			runContextsCommand(os.Args[2:])
+			return
+		case "forecast":
+			runForecastCommand(os.Args[2:])
+			return
+		case "verify":
+			runVerifyCommand(os.Args[2:])
+			return
+		case "qos-plan":
+			runQoSPlanCommand(os.Args[2:])
+			return
+		case "autoscaler":
+			runAutoscalerCommand(os.Args[2:])
+			return
+		case "nodes":
+			runNodesCommand(os.Args[2:])
+			return
+		case "namespace":
+			runNamespaceCommand(os.Args[2:])
+			return
+		case "preemption":
+			runPreemptionCommand(os.Args[2:])
+			return
+		case "audit":
+			runAuditCommand(os.Args[2:])
+			return
+		case "support-bundle":
+			runSupportBundleCommand(os.Args[2:])
+			return
+		case "schema":
+			runSchemaCommand(os.Args[2:])
+			return
+		case "grafana":
+			runGrafanaCommand(os.Args[2:])
+			return
+		case "maintenance":
+			runMaintenanceCommand(os.Args[2:])
+			return
 		}
 	}
 
 	flag.BoolVar(&showVersion, "version", false, "Show version and exit")
-	flag.StringVar(&outputType, "output", OutputTypeRequests, "Output type: usage, requests, or max-requests")
+	flag.StringVar(&configFile, "config", "", "Path to config file (defaults to "+defaultConfigPath()+")")
+	flag.StringVar(&outputType, "output", OutputTypeRequests, "Output type: usage, requests, max-requests, idle, or all; or wide, go-template=TEMPLATE, go-template-file=PATH or custom-columns=HEADER:.Field,...")
+	flag.StringVar(&outputType, "o", OutputTypeRequests, "Output type (shorthand for --output)")
 	flag.StringVar(&namespace, "namespace", "", "Namespace (defaults to current context or 'default')")
 	flag.StringVar(&deploymentName, "deployment", "", "Deployment name (defaults to all deployments)")
-	flag.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfig, "Path to kubeconfig file")
+	flag.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	flag.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
 	flag.BoolVar(&usePorter, "porter", false, "Use Porter API instead of direct Kubernetes access")
 	flag.StringVar(&porterToken, "porter-token", os.Getenv("PORTER_TOKEN"), "Porter API token (or set PORTER_TOKEN env var)")
 	flag.StringVar(&porterProjectID, "porter-project-id", os.Getenv("PORTER_PROJECT_ID"), "Porter project ID (or set PORTER_PROJECT_ID env var)")
 	flag.StringVar(&porterBaseURL, "porter-url", getEnvDefault("PORTER_BASE_URL", "https://dashboard.porter.run"), "Porter API base URL")
-	flag.BoolVar(&debug, "debug", false, "Enable debug output")
+	flag.StringVar(&porterProfileName, "porter-profile", os.Getenv("PORTER_PROFILE"), "Porter profile of the config file's porterProfiles to take the token, project ID and URL from (or set PORTER_PROFILE env var)")
+	flag.DurationVar(&porterTimeout, "porter-timeout", 30*time.Second, "Timeout for each Porter API request (0 for none)")
+	flag.DurationVar(&porterDeadline, "porter-deadline", 0, "Give up collecting from Porter after this long in total (0 for no deadline)")
+	flag.Var(&porterHeaders, "porter-header", "Extra 'Name: value' header for Porter API requests, e.g. for an auth proxy (repeatable)")
+	flag.BoolVar(&includeKubernetes, "include-kubernetes", false, "In Porter mode, also collect workloads from the kubeconfig cluster into the same report")
+	flag.BoolVar(&porterWithK8s, "porter-with-k8s", false, "Fetch each Porter cluster's kubeconfig and add live usage from metrics-server")
+	flag.Var(verbosityFlag{&verbosity}, "v", "Debug output level: -v for skipped checks and fallbacks, -v=2 adds phase timings, -v=3 API requests, -v=4 raw Porter responses")
+	flag.BoolVar(&debug, "debug", false, "Enable all debug output (same as -v=4)")
+	flag.BoolVar(&quiet, "quiet", false, "Don't print warnings or progress to stderr; errors are still printed")
+	flag.StringVar(&progressMode, "progress", ProgressSpinner, "Progress shown on stderr while workloads are collected: none, spinner, or bar")
 	flag.BoolVar(&allNamespaces, "A", false, "List resources across all namespaces")
 	flag.BoolVar(&allNamespaces, "all-namespaces", false, "List resources across all namespaces")
 	flag.StringVar(&labelSelector, "l", "", "Label selector to filter deployments (e.g., 'app=myapp,env=prod')")
 	flag.StringVar(&labelSelector, "selector", "", "Label selector to filter deployments (alias for -l)")
 	flag.BoolVar(&includeCronJobs, "include-cronjobs", false, "Include CronJobs in the resource calculation")
+	flag.BoolVar(&ownerGraph, "owner-graph", false, "Include each workload's ReplicaSets/Jobs and Pods with their requests and usage in JSON output")
+	flag.BoolVar(&includeStaticPods, "include-static-pods", false, "Include static (mirror) pods such as kube-apiserver and etcd in the resource calculation")
+	flag.IntVar(&jobHistory, "job-history", 0, "Average CronJob requests over the last N completed jobs instead of the job template")
+	flag.BoolVar(&suspendedAsZero, "suspended-as-zero", false, "Count suspended CronJobs as zero in max-requests")
+	flag.BoolVar(&excludeSuspended, "exclude-suspended", false, "Leave suspended CronJobs out of the report and totals")
+	flag.BoolVar(&excludePaused, "exclude-paused", false, "Leave Deployments with paused rollouts out of the report and totals")
+	flag.BoolVar(&excludeZeroReplicas, "exclude-zero-replicas", false, "Leave Deployments scaled to zero desired replicas out of the report and totals")
+	flag.BoolVar(&onlyZeroReplicas, "only-zero-replicas", false, "Only report Deployments scaled to zero desired replicas, to find dormant workloads still holding HPA max capacity")
+	flag.BoolVar(&excludeCanary, "exclude-canary", false, "Leave canary workloads (track/role=canary labels, Flagger targets with a -primary Deployment) out of the report and totals")
+	flag.BoolVar(&hpaCoverageReport, "hpa-coverage", false, "After the report, show per namespace which deployments an HPA scales, and whether those without one have headroom or are at risk")
+	flag.BoolVar(&checkHPAs, "check-hpa", false, "Warn about HPAs whose CPU target or max replicas can't fit on the largest node or in the namespace quota")
+	flag.IntVar(&peakTolerance, "peak-tolerance", 10, "Percentage usage may exceed a workload's expected-peak annotations before it is flagged")
+	flag.BoolVar(&normalize, "normalize", false, "Suggest per-pod requests rounded up to --normalize-cpu-step and --normalize-memory-step, with the estimated node count before and after")
+	flag.StringVar(&normalizeCPUStep, "normalize-cpu-step", "50m", "CPU step for --normalize")
+	flag.StringVar(&normalizeMemoryStep, "normalize-memory-step", "64Mi", "Memory step for --normalize")
+	flag.BoolVar(&batchOverlay, "batch-overlay", false, "Report serving and CronJob max-requests per hour of day instead of per workload (requires --include-cronjobs)")
+	flag.StringVar(&cronWindowValue, "cron-window", "", "Only include CronJobs scheduled to fire within this daily window in UTC, e.g. '00:00-06:00'")
+	flag.StringVar(&sortBy, "sort-by", "", "Sort rows by cpu, memory, name, namespace, or usage-percent (defaults to namespace, then name)")
+	flag.StringVar(&sortOrderValue, "sort-order", "", "Sort direction for --sort-by: asc or desc (defaults to desc for numbers, asc for names)")
+	flag.StringVar(&columnsValue, "columns", "", "Comma-separated columns to show in table and markdown output (e.g., 'cpu,memory,replicas'); name and namespace columns always show")
+	flag.StringVar(&unitsValue, "units", UnitsDefault, "Units for CPU and memory figures: raw (millicores and bytes), binary (KiB/MiB/GiB) or decimal (kB/MB/GB); by default memory is counted in 1024s and labelled KB/MB/GB")
+	flag.StringVar(&colorMode, "color", ColorAuto, "Color table rows by usage against --warn-threshold and --crit-threshold: auto, always, or never (auto colors a terminal unless NO_COLOR is set)")
+	flag.Float64Var(&warnThreshold, "warn-threshold", 80, "Usage as a percentage of requests at which table rows turn yellow")
+	flag.Float64Var(&critThreshold, "crit-threshold", 90, "Usage as a percentage of requests at which table rows turn red; rows missing a CPU or memory request are always red")
+	flag.BoolVar(&failOnThreshold, "fail-on-threshold", false, "Exit with status 4 when a workload's usage reaches --crit-threshold of its requests, or it lacks a CPU or memory request")
+	flag.IntVar(&top, "top", 0, "Show only the N largest workloads by the selected output type, collapsing the rest into an OTHERS row")
 	flag.BoolVar(&totalOnly, "total-only", false, "Show only the total line, hide individual resources")
-	flag.StringVar(&format, "format", FormatTable, "Output format: table or markdown")
-	flag.Parse()
+	flag.StringVar(&groupBy, "group-by", "", "Group workload rows by 'namespace' or 'priority-class', with a subtotal after each group")
+	flag.StringVar(&breakdown, "breakdown", "", "Break workloads down into a row per 'pod' under each, with its own usage and requests")
+	flag.BoolVar(&stats, "stats", false, "Instead of totals, show min/p50/avg/p95/max usage across each workload's running pods")
+	flag.StringVar(&namespaceLabels, "namespace-labels", "", "With --group-by namespace, add a column per comma-separated namespace label (e.g., 'pod-security,environment')")
+	flag.StringVar(&promBy, "prom-granularity", PromByWorkload, "Labels of --format prom series: 'workload' for namespace, workload and type, or sum them by 'type' (namespace and type), 'namespace' or 'cluster' to keep TSDB cardinality down")
+	flag.StringVar(&groupByLabels, "group-by-labels", "", "Roll workloads up by the first of these comma-separated labels they carry (e.g., 'team,owner,app.kubernetes.io/part-of')")
+	flag.StringVar(&reservationsFile, "reservations", "", "Show the capacity committed to planned workloads in this reservations file against the cluster's free headroom")
+	flag.StringVar(&historyFile, "history-file", "", "Append this run's total requests per namespace to a history file, for the forecast subcommand")
+	flag.StringVar(&baselineConfigMap, "baseline-configmap", "", "Compare the requests per namespace against a baseline kept in this ConfigMap (namespace/name), saving this run as the baseline if there is none")
+	flag.BoolVar(&updateBaseline, "update-baseline", false, "With --baseline-configmap, replace the stored baseline with this run")
+	flag.StringVar(&format, "format", FormatTable, "Output format: table, markdown, json, jsonl, prom, or dot")
+	flag.StringVar(&format, "output-format", FormatTable, "Output format (alias for --format)")
+	flag.StringVar(&outputFile, "output-file", "", "Write the report to this file, replaced atomically once the run succeeds, instead of stdout")
+	flag.StringVar(&signKey, "sign-key", "", "Sign the report with this Ed25519 private key (PEM), writing a detached signature to --signature-file")
+	flag.StringVar(&signatureFile, "signature-file", "", "Where to write the report signature when --sign-key is set")
+	flag.StringVar(&templateFile, "template", "", "Render the results with this Go text/template file instead of --format")
+	flag.BoolVar(&showCapabilities, "capabilities", false, "Print which optional cluster APIs are available and exit")
+	flag.BoolVar(&kubeletFallback, "kubelet-fallback", false, "Read usage from the kubelet Summary API via the API server proxy when metrics-server is unavailable")
+	flag.StringVar(&nowValue, "now", "", "Run as of this RFC 3339 time instead of the current time, for reproducible reports and demos")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print which resources would be queried and roughly how many API calls the run would make, then exit")
+	flag.BoolVar(&imageSizes, "image-sizes", false, "Query container registries for the compressed size of each workload's images and show it beside the resource figures")
+	flag.BoolVar(&perReplica, "per-replica", false, "Add CPU/REPLICA and MEM/REPLICA columns: the figures divided by current replicas, or max replicas for max-requests")
+	flag.StringVar(&carbonValue, "carbon", "", "Estimate each workload's emissions in gCO2e from this grid carbon intensity in gCO2e/kWh, or a gridIntensity region of the config file")
+	flag.StringVar(&carbonCoefficients, "carbon-coefficients", "aws", "Cloud Carbon Footprint energy coefficients for --carbon: aws, gcp or azure")
+	flag.DurationVar(&carbonPeriod, "carbon-period", 30*24*time.Hour, "Period --carbon estimates emissions over, assuming current requests and usage hold for all of it")
+	flag.BoolVar(&showLabels, "show-labels", false, "Add a LABELS column listing each workload's labels as key=value pairs")
+	flag.StringVar(&labelColumnsValue, "label-columns", "", "Add a column per comma-separated workload label key (e.g., 'team,env'), the way kubectl -L does")
+	flag.BoolVar(&stream, "stream", false, "Print each workload's table or markdown row as soon as it is collected instead of once all are, with fixed columns")
+	flag.BoolVar(&follow, "follow", false, "Instead of a report, watch Deployments and print a line whenever one's replicas or pod requests change, until interrupted")
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	parseFlags(flag.CommandLine, os.Args[1:])
+
+	// --debug predates -v and keeps showing everything
+	if debug {
+		verbosity = maxVerbosity
+	}
+	mode, err := parseProgressMode(progressMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	progress.mode = mode
+	if quiet {
+		progress.mode = ProgressNone
+	}
 
 	// Handle version flag
 	if showVersion {
 		fmt.Println(version)
-		os.Exit(0)
+		os.Exit(exitOK)
+	}
+
+	config, err := loadConfig(configFileOrDefault(configFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	headerLabels = config.Headers
+
+	if porterProfileName != "" {
+		profile, err := config.porterProfile(porterProfileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		profile.apply(flag.CommandLine, &porterToken, &porterProjectID, &porterBaseURL)
+	}
+
+	var carbon *carbonModel
+	if carbonValue != "" {
+		if carbon, err = newCarbonModel(carbonValue, carbonCoefficients, carbonPeriod, config.GridIntensity); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	// -o go-template=... renders like --template, over the requests figures
+	reportTemplate, err := outputTemplate(outputType)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing template: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if reportTemplate != nil {
+		if templateFile != "" {
+			fmt.Fprintf(os.Stderr, "Error: --template and -o go-template flags are mutually exclusive\n")
+			os.Exit(exitUsage)
+		}
+		outputType = OutputTypeRequests
+	} else if templateFile != "" {
+		if reportTemplate, err = parseReportTemplate(templateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing template: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	// -o wide shows the requests with extra columns
+	wide := outputType == OutputWide
+	if wide {
+		outputType = OutputTypeRequests
+	}
+
+	// -o custom-columns=... picks the columns, over the requests figures
+	columns, err := outputColumns(outputType)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if columns != nil {
+		if reportTemplate != nil || groupByLabels != "" || batchOverlay || normalize {
+			fmt.Fprintf(os.Stderr, "Error: -o custom-columns can't be combined with --template, --group-by-labels, --batch-overlay or --normalize\n")
+			os.Exit(exitUsage)
+		}
+		outputType = OutputTypeRequests
 	}
 
 	// Validate output type
-	if outputType != OutputTypeUsage && outputType != OutputTypeRequests && outputType != OutputTypeMaxRequests && outputType != OutputTypeCombined {
-		fmt.Fprintf(os.Stderr, "Error: Invalid output type '%s'. Must be 'usage', 'requests', 'max-requests', or 'combined'\n", outputType)
-		os.Exit(1)
+	if outputType != OutputTypeUsage && outputType != OutputTypeRequests && outputType != OutputTypeMaxRequests && outputType != OutputTypeCombined && outputType != OutputTypeIdle && outputType != OutputTypeAll {
+		fmt.Fprintf(os.Stderr, "Error: Invalid output type '%s'. Must be 'usage', 'requests', 'max-requests', 'combined', 'idle', or 'all'\n", outputType)
+		os.Exit(exitUsage)
 	}
 
 	// Validate format
-	if format != FormatTable && format != FormatMarkdown {
-		fmt.Fprintf(os.Stderr, "Error: Invalid format '%s'. Must be 'table' or 'markdown'\n", format)
-		os.Exit(1)
+	if format != FormatTable && format != FormatMarkdown && format != FormatJSON && format != FormatJSONL && format != FormatProm && format != FormatDOT {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format '%s'. Must be 'table', 'markdown', 'json', 'jsonl', 'prom', or 'dot'\n", format)
+		os.Exit(exitUsage)
+	}
+	if outputType == OutputTypeAll && (format == FormatProm || format == FormatDOT || groupByLabels != "" || batchOverlay || normalize) {
+		fmt.Fprintf(os.Stderr, "Error: -o all can't be combined with --format prom, --format dot, --group-by-labels, --batch-overlay or --normalize\n")
+		os.Exit(exitUsage)
+	}
+	order, err := parseSortOrder(sortBy, sortOrderValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if (format == FormatProm || format == FormatJSONL || format == FormatDOT) && (groupByLabels != "" || batchOverlay || normalize) {
+		fmt.Fprintf(os.Stderr, "Error: --format %s can't be combined with --group-by-labels, --batch-overlay or --normalize\n", format)
+		os.Exit(exitUsage)
+	}
+
+	if groupBy != "" {
+		if groupBy != GroupByNamespace && groupBy != GroupByPriorityClass {
+			fmt.Fprintf(os.Stderr, "Error: Invalid --group-by '%s'. Must be 'namespace' or 'priority-class'\n", groupBy)
+			os.Exit(exitUsage)
+		}
+		if reportTemplate != nil || columns != nil || groupByLabels != "" || batchOverlay || normalize || format == FormatProm || format == FormatJSONL || format == FormatDOT {
+			fmt.Fprintf(os.Stderr, "Error: --group-by only applies to table, markdown and json output and can't be combined with --template, -o custom-columns, --group-by-labels, --batch-overlay or --normalize\n")
+			os.Exit(exitUsage)
+		}
+	}
+
+	if promBy != PromByWorkload && promBy != PromByType && promBy != PromByNamespace && promBy != PromByCluster {
+		fmt.Fprintf(os.Stderr, "Error: Invalid --prom-granularity '%s'. Must be 'workload', 'type', 'namespace' or 'cluster'\n", promBy)
+		os.Exit(exitUsage)
+	}
+	if promBy != PromByWorkload && format != FormatProm {
+		warnf("--prom-granularity flag only applies to prom output, ignoring")
+	}
+
+	if breakdown != "" {
+		if breakdown != BreakdownPod {
+			fmt.Fprintf(os.Stderr, "Error: Invalid --breakdown '%s'. Must be 'pod'\n", breakdown)
+			os.Exit(exitUsage)
+		}
+		if (format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || columns != nil || groupByLabels != "" || batchOverlay || normalize {
+			fmt.Fprintf(os.Stderr, "Error: --breakdown only applies to table and markdown output and can't be combined with --template, -o custom-columns, --group-by-labels, --batch-overlay or --normalize; use --owner-graph for pods in JSON\n")
+			os.Exit(exitUsage)
+		}
+	}
+
+	if stats && usePorter && !includeKubernetes {
+		fmt.Fprintf(os.Stderr, "Error: --stats is only supported in Kubernetes mode\n")
+		os.Exit(exitUsage)
+	}
+	if stats && (format != FormatTable || reportTemplate != nil || columns != nil || groupBy != "" || groupByLabels != "" || breakdown != "" || batchOverlay || normalize || totalOnly) {
+		fmt.Fprintf(os.Stderr, "Error: --stats only applies to table output and can't be combined with --template, -o custom-columns, --group-by, --group-by-labels, --breakdown, --batch-overlay, --normalize or --total-only\n")
+		os.Exit(exitUsage)
+	}
+
+	if wide && ((format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || groupByLabels != "" || batchOverlay || normalize) {
+		warnf("-o wide only applies to table and markdown workload reports, ignoring")
+		wide = false
+	}
+	var tableColumns []string
+	if columnsValue != "" {
+		if tableColumns, err = parseColumns(columnsValue); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		if (format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || columns != nil || groupByLabels != "" || batchOverlay || normalize {
+			warnf("--columns only applies to table and markdown workload reports, ignoring")
+			tableColumns = nil
+		}
+	}
+	if perReplica {
+		switch {
+		case (format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || columns != nil || groupByLabels != "" || batchOverlay || normalize || stats:
+			warnf("--per-replica only applies to table and markdown workload reports, ignoring")
+			perReplica = false
+		case outputType == OutputTypeAll:
+			fmt.Fprintf(os.Stderr, "Error: --per-replica can't be combined with -o all\n")
+			os.Exit(exitUsage)
+		}
+	}
+	labelColumns := parseLabelChain(labelColumnsValue)
+	if (showLabels || len(labelColumns) > 0) && ((format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || columns != nil || groupByLabels != "" || batchOverlay || normalize || stats) {
+		warnf("--show-labels and --label-columns only apply to table and markdown workload reports, ignoring; JSON and YAML already carry the labels")
+		showLabels, labelColumns = false, nil
+	}
+	if stream {
+		if format != FormatTable && format != FormatMarkdown {
+			warnf("--stream flag only applies to table and markdown output, ignoring; %s output is already written as workloads are collected", format)
+			stream = false
+		} else if reportTemplate != nil || columns != nil || groupBy != "" || groupByLabels != "" || breakdown != "" || stats || batchOverlay || normalize ||
+			totalOnly || order.key != "" || top > 0 || wide || perReplica || tableColumns != nil || outputFile != "" {
+			fmt.Fprintf(os.Stderr, "Error: --stream prints rows before the rest are collected, so it can't be combined with --template, -o custom-columns, -o wide, --columns, --per-replica, --group-by, --group-by-labels, --breakdown, --stats, --batch-overlay, --normalize, --total-only, --sort-by, --top or --output-file\n")
+			os.Exit(exitUsage)
+		}
+	}
+	if displayUnits, err = parseUnits(unitsValue); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	thresholds, err := parseColorThresholds(warnThreshold, critThreshold)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if outputFile != "" && colorMode == ColorAuto {
+		colorMode = ColorNever
+	}
+	colored, err := useColor(colorMode, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	var tableColors *colorThresholds
+	if colored && format == FormatTable {
+		tableColors = &thresholds
+	}
+	clk, err := newClock(nowValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	now := clk.Now()
+
+	if top < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --top must not be negative\n")
+		os.Exit(exitUsage)
+	}
+	if top > 0 {
+		if groupBy != "" || groupByLabels != "" || batchOverlay || normalize {
+			fmt.Fprintf(os.Stderr, "Error: --top can't be combined with --group-by, --group-by-labels, --batch-overlay or --normalize\n")
+			os.Exit(exitUsage)
+		}
+		// The largest come first unless --sort-by picks another order
+		if order.key == "" {
+			order = sortOrder{key: SortByCPU, descending: true}
+		}
+	}
+	// Rows come out by namespace, then name, unless asked otherwise, so runs
+	// diff cleanly whatever order the APIs listed them in. JSON lines and
+	// --stream are written as workloads arrive, so they keep collection
+	// order, and the aggregate reports order their own rows.
+	if order.key == "" && format != FormatJSONL && !stream && groupByLabels == "" && !batchOverlay && !normalize {
+		order = sortOrder{key: SortByNamespace}
+	}
+	if peakTolerance < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --peak-tolerance must not be negative\n")
+		os.Exit(exitUsage)
+	}
+
+	namespaceLabelKeys := parseNamespaceLabels(namespaceLabels)
+	if len(namespaceLabelKeys) > 0 && groupBy != GroupByNamespace {
+		warnf("--namespace-labels flag only applies with --group-by namespace, ignoring")
+		namespaceLabelKeys = nil
 	}
 
 	validateFlags(usePorter, namespace, allNamespaces, deploymentName, labelSelector)
 
+	if dryRun && usePorter {
+		fmt.Fprintf(os.Stderr, "Error: --dry-run is only supported in Kubernetes mode\n")
+		os.Exit(exitUsage)
+	}
+	if follow && (usePorter || dryRun || outputFile != "" || signKey != "" || historyFile != "") {
+		fmt.Fprintf(os.Stderr, "Error: --follow is only supported in Kubernetes mode and can't be combined with --dry-run, --output-file, --sign-key or --history-file\n")
+		os.Exit(exitUsage)
+	}
+
+	if porterWithK8s && !usePorter {
+		warnf("--porter-with-k8s flag is only supported in Porter mode, ignoring")
+	}
+	if porterHeaders.header != nil && !usePorter {
+		warnf("--porter-header flag is only supported in Porter mode, ignoring")
+	}
+	if jobHistory > 0 && !includeCronJobs {
+		warnf("--job-history flag only applies with --include-cronjobs, ignoring")
+	}
+	if suspendedAsZero && !includeCronJobs {
+		warnf("--suspended-as-zero flag only applies with --include-cronjobs, ignoring")
+	}
+	if excludeZeroReplicas && onlyZeroReplicas {
+		fmt.Fprintf(os.Stderr, "Error: --exclude-zero-replicas and --only-zero-replicas can't be combined\n")
+		os.Exit(exitUsage)
+	}
+	if excludeSuspended && !includeCronJobs {
+		warnf("--exclude-suspended flag only applies with --include-cronjobs, ignoring")
+	}
+	var cronWindow *timeWindow
+	if cronWindowValue != "" {
+		if !includeCronJobs {
+			warnf("--cron-window flag only applies with --include-cronjobs, ignoring")
+		} else if cronWindow, err = parseTimeWindow(cronWindowValue); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	var reservations *reservationTracker
+	if reservationsFile != "" {
+		switch {
+		case (format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || columns != nil || batchOverlay || normalize:
+			warnf("--reservations flag only applies to table and markdown reports, ignoring")
+		case usePorter && !includeKubernetes:
+			warnf("--reservations flag needs Kubernetes access for the cluster's headroom, ignoring")
+		default:
+			planned, err := loadReservations(reservationsFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading reservations: %v\n", err)
+				os.Exit(exitUsage)
+			}
+			reservations = newReservationTracker(planned)
+		}
+	}
+	var baselineNamespace, baselineName string
+	if baselineConfigMap != "" {
+		switch {
+		case (format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || columns != nil || batchOverlay || normalize:
+			warnf("--baseline-configmap flag only applies to table and markdown reports, ignoring")
+			baselineConfigMap = ""
+		case usePorter && !includeKubernetes:
+			warnf("--baseline-configmap flag needs Kubernetes access for the ConfigMap, ignoring")
+			baselineConfigMap = ""
+		default:
+			if baselineNamespace, baselineName, err = parseConfigMapRef(baselineConfigMap); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitUsage)
+			}
+		}
+	} else if updateBaseline {
+		warnf("--update-baseline flag only applies with --baseline-configmap, ignoring")
+	}
+	if hpaCoverageReport {
+		switch {
+		case (format != FormatTable && format != FormatMarkdown) || reportTemplate != nil || columns != nil || batchOverlay || normalize:
+			warnf("--hpa-coverage flag only applies to table and markdown reports, ignoring")
+			hpaCoverageReport = false
+		case usePorter && !includeKubernetes:
+			warnf("--hpa-coverage flag is only supported in Kubernetes mode, ignoring")
+			hpaCoverageReport = false
+		}
+	}
+	if includeKubernetes && !usePorter {
+		warnf("--include-kubernetes flag is only supported in Porter mode, ignoring")
+	}
+
 	ctx := context.Background()
-	var deployments []DeploymentMetrics
+	if reportTemplate != nil && groupByLabels != "" {
+		fmt.Fprintf(os.Stderr, "Error: --template and --group-by-labels flags are mutually exclusive\n")
+		os.Exit(exitUsage)
+	}
+	if batchOverlay {
+		if !includeCronJobs {
+			fmt.Fprintf(os.Stderr, "Error: --batch-overlay requires --include-cronjobs\n")
+			os.Exit(exitUsage)
+		}
+		if reportTemplate != nil || groupByLabels != "" {
+			fmt.Fprintf(os.Stderr, "Error: --batch-overlay can't be combined with --template or --group-by-labels\n")
+			os.Exit(exitUsage)
+		}
+	}
+	var normalizeStep ResourceMetrics
+	if normalize {
+		if batchOverlay || reportTemplate != nil || groupByLabels != "" {
+			fmt.Fprintf(os.Stderr, "Error: --normalize can't be combined with --batch-overlay, --template or --group-by-labels\n")
+			os.Exit(exitUsage)
+		}
+		cpuStep, cpuErr := parseResourceValue(normalizeCPUStep, true)
+		memoryStep, memoryErr := parseResourceValue(normalizeMemoryStep, false)
+		if cpuErr != nil || memoryErr != nil || cpuStep <= 0 || memoryStep <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: Invalid --normalize-cpu-step '%s' or --normalize-memory-step '%s'\n", normalizeCPUStep, normalizeMemoryStep)
+			os.Exit(exitUsage)
+		}
+		normalizeStep = ResourceMetrics{CPU: cpuStep, Memory: memoryStep}
+	}
+
+	// With --output-file the report is collected and written once the run
+	// has succeeded
+	var out io.Writer = os.Stdout
+	var report bytes.Buffer
+	if outputFile != "" {
+		out = &report
+	}
+	var signer *reportSigner
+	if signKey != "" {
+		if signatureFile == "" {
+			fmt.Fprintf(os.Stderr, "Error: --sign-key requires --signature-file\n")
+			os.Exit(exitUsage)
+		}
+		key, err := loadSigningKey(signKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading signing key: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		signer = newReportSigner(key)
+		out = io.MultiWriter(out, signer)
+	}
+
+	meta := &runMetadata{
+		GeneratedAt: now.UTC(),
+		Version:     version,
+		Flags:       explicitFlags(flag.CommandLine),
+	}
+	if usePorter {
+		meta.PorterProjectID = porterProjectID
+	}
+	if !usePorter || includeKubernetes {
+		meta.Context, meta.Cluster = kubeconfigContextCluster(kubeconfig, kubeContext)
+	}
+
+	var printer resultPrinter
+	var overlay *overlayPrinter
+	var normalizer *normalizePrinter
+	if batchOverlay {
+		overlay = newOverlayPrinter(out, format, meta)
+		printer = overlay
+	} else if normalize {
+		normalizer = newNormalizePrinter(out, format, normalizeStep, meta)
+		printer = normalizer
+	} else if stats {
+		printer = &statsPrinter{out: out}
+	} else if reportTemplate != nil {
+		printer = newTemplatePrinter(out, reportTemplate, outputType, meta)
+	} else if columns != nil {
+		printer = &customColumnsPrinter{out: out, columns: columns}
+	} else if chain := parseLabelChain(groupByLabels); len(chain) > 0 {
+		printer = newGroupPrinter(out, outputType, totalOnly, format, chain, meta)
+	} else if stream {
+		otherTypes := (!usePorter || includeKubernetes) && (includeCronJobs || includeStaticPods)
+		printer = newStreamPrinter(out, outputType, format, otherTypes, usePorter, usePorter && includeKubernetes, tableColors, labelColumns, showLabels)
+	} else {
+		printer = newResultPrinter(out, outputType, usePorter, totalOnly, format, tableOptions{groupBy: groupBy, breakdown: breakdown, wide: wide, perReplica: perReplica, labels: labelColumns, showLabels: showLabels, columns: tableColumns, colors: tableColors, promBy: promBy}, meta)
+	}
+
+	if top > 0 {
+		printer = &topPrinter{next: printer, n: top, key: order.key, outputType: outputType}
+	}
+	if order.key != "" {
+		printer = &sortingPrinter{next: printer, order: order, outputType: outputType}
+	}
+
+	add := printer.Add
+	// The baseline comparison sums requests per namespace the same way the
+	// history file does
+	var history *historyRecorder
+	if historyFile != "" || baselineConfigMap != "" {
+		history = newHistoryRecorder()
+		add = func(dm DeploymentMetrics) {
+			history.Add(dm)
+			printer.Add(dm)
+		}
+	}
+	breached := false
+	if failOnThreshold {
+		next := add
+		add = func(dm DeploymentMetrics) {
+			if thresholds.rowColor(dm) == colorRed {
+				breached = true
+			}
+			next(dm)
+		}
+	}
+	if reservations != nil {
+		next := add
+		add = func(dm DeploymentMetrics) {
+			reservations.Add(dm)
+			next(dm)
+		}
+	}
+	if carbon != nil {
+		next := add
+		add = func(dm DeploymentMetrics) {
+			dm.CarbonGrams = carbon.Grams(dm)
+			next(dm)
+		}
+	}
 
 	if usePorter {
 		if porterToken == "" {
-			fmt.Fprintf(os.Stderr, "Error: Porter token required. Set PORTER_TOKEN env var or use --porter-token flag\n")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "Error: Porter token required. Set PORTER_TOKEN env var, use --porter-token flag or pick a --porter-profile with a token\n")
+			os.Exit(exitUsage)
 		}
 		if porterProjectID == "" {
-			fmt.Fprintf(os.Stderr, "Error: Porter project ID required. Set PORTER_PROJECT_ID env var or use --porter-project-id flag\n")
-			os.Exit(1)
-		}
-		if labelSelector != "" {
-			fmt.Fprintf(os.Stderr, "Warning: -l/--selector flag is only supported in Kubernetes mode, ignoring\n")
+			fmt.Fprintf(os.Stderr, "Error: Porter project ID required. Set PORTER_PROJECT_ID env var, use --porter-project-id flag or pick a --porter-profile with a projectId\n")
+			os.Exit(exitUsage)
 		}
-		if includeCronJobs {
-			fmt.Fprintf(os.Stderr, "Warning: --include-cronjobs flag is only supported in Kubernetes mode, ignoring\n")
+		if !includeKubernetes {
+			if labelSelector != "" {
+				warnf("-l/--selector flag is only supported in Kubernetes mode, ignoring")
+			}
+			if includeCronJobs {
+				warnf("--include-cronjobs flag is only supported in Kubernetes mode, ignoring")
+			}
+			if includeStaticPods {
+				warnf("--include-static-pods flag is only supported in Kubernetes mode, ignoring")
+			}
+			if showCapabilities {
+				warnf("--capabilities flag is only supported in Kubernetes mode, ignoring")
+			}
+			if kubeletFallback {
+				warnf("--kubelet-fallback flag is only supported in Kubernetes mode, ignoring")
+			}
+			if len(namespaceLabelKeys) > 0 {
+				warnf("--namespace-labels flag is only supported in Kubernetes mode, ignoring")
+			}
+			if imageSizes {
+				warnf("--image-sizes flag is only supported in Kubernetes mode, ignoring")
+			}
+			if breakdown != "" {
+				warnf("--breakdown flag is only supported in Kubernetes mode, ignoring")
+			}
+			if showLabels || len(labelColumns) > 0 {
+				warnf("--show-labels and --label-columns flags are only supported in Kubernetes mode, ignoring")
+			}
+			if excludeZeroReplicas || onlyZeroReplicas {
+				warnf("--exclude-zero-replicas and --only-zero-replicas flags are only supported in Kubernetes mode, ignoring")
+			}
 		}
 
 		client := &PorterClient{
 			BaseURL:               porterBaseURL,
 			Token:                 porterToken,
 			ProjectID:             porterProjectID,
-			HTTPClient:            &http.Client{},
-			Debug:                 debug,
+			HTTPClient:            &http.Client{Timeout: porterTimeout, Transport: traceRequests(nil)},
+			UserAgent:             porterUserAgent(),
+			Headers:               porterHeaders.header,
 			deploymentTargetCache: make(map[string]*PorterDeploymentTarget),
 			clusterCache:          make(map[int]*PorterCluster),
+			metricsClientCache:    make(map[int]*versioned.Clientset),
+			metricsClientErrors:   make(map[int]error),
 		}
 
-		var err error
-		deployments, err = getPorterApplicationMetrics(ctx, client, deploymentName)
+		porterCtx := ctx
+		if porterDeadline > 0 {
+			var cancel context.CancelFunc
+			porterCtx, cancel = context.WithTimeout(ctx, porterDeadline)
+			defer cancel()
+		}
+		done := timed("collecting Porter applications")
+		err := getPorterApplicationMetrics(porterCtx, client, deploymentName, porterWithK8s, add)
+		done()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error getting Porter application metrics: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitConnection)
+		}
+	}
+
+	var checker *hpaChecker
+	var coverage *hpaCoverage
+	peaks := &peakChecker{tolerance: peakTolerance}
+	var nodeFit *nodeFitChecker
+	var sizer *imageSizer
+	var headroom ResourceMetrics
+	var baselines *baselineStore
+	var baseline *historySnapshot
+
+	// Kubernetes rows are collected on their own or, with --include-kubernetes,
+	// after the Porter ones; both land in the same printer and totals
+	if !usePorter || includeKubernetes {
+		clientset, metricsClientset := setupKubernetesClients(kubeconfig, kubeContext)
+
+		caps, err := detectCapabilities(clientset)
+		if err != nil {
+			debugf(verbosityDecisions, "%v, assuming all APIs are available", err)
+		}
+		if showCapabilities {
+			printCapabilities(os.Stdout, caps)
+			return
+		}
+		if !caps.Metrics && !kubeletFallback && (stats || outputType == OutputTypeUsage || outputType == OutputTypeCombined || outputType == OutputTypeIdle || outputType == OutputTypeAll) {
+			partialf("metrics.k8s.io API not available (is metrics-server installed?), usage will show as zero; use --kubelet-fallback to read kubelet stats instead")
+		}
+		if includeCronJobs && !caps.BatchV1 {
+			partialf("batch/v1 API not available, ignoring --include-cronjobs")
+			includeCronJobs = false
 		}
-	} else {
-		clientset, metricsClientset := setupKubernetesClients(kubeconfig)
 
 		if allNamespaces {
 			namespace = ""
 		} else if namespace == "" {
-			var err error
-			namespace, err = getNamespaceFromKubeconfig(kubeconfig)
+			namespace, err = getNamespaceFromKubeconfig(kubeconfig, kubeContext)
+			if err != nil {
+				// In-cluster runs have no kubeconfig; use the pod's own namespace
+				namespace, err = readServiceAccountNamespace(serviceAccountNamespaceFile)
+				if err != nil {
+					namespace = "default"
+				}
+			}
+		}
+
+		if dryRun {
+			nodeLookups := 1 // largest node for the unschedulable pod check
+			if checkHPAs {
+				nodeLookups += 2
+			}
+			if overlay != nil || normalizer != nil {
+				nodeLookups++
+			}
+			if reservations != nil {
+				nodeLookups += 2
+			}
+			plan, spent, err := planQueries(ctx, clientset, caps, dryRunOptions{
+				Namespace:         namespace,
+				DeploymentName:    deploymentName,
+				LabelSelector:     labelSelector,
+				IncludeCronJobs:   includeCronJobs,
+				IncludeStaticPods: includeStaticPods,
+				JobHistory:        jobHistory,
+				KubeletFallback:   kubeletFallback,
+				NodeLookups:       nodeLookups,
+			})
 			if err != nil {
-				namespace = "default"
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(apiExitCode(err))
+			}
+			printQueryPlan(os.Stdout, plan, spent)
+			return
+		}
+
+		if follow {
+			if err := followDeployments(ctx, clientset, namespace, deploymentName, labelSelector, os.Stdout, clk); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(apiExitCode(err))
 			}
+			return
 		}
 
-		deployments = getAllDeployments(ctx, clientset, metricsClientset, namespace, deploymentName, labelSelector, allNamespaces)
+		if overlay != nil {
+			capacity, err := loadNodeAllocatable(ctx, clientset)
+			if err != nil {
+				warnf("Error getting node allocatable, overlay won't flag capacity: %v", err)
+			}
+			overlay.SetCapacity(capacity)
+		}
+		if normalizer != nil {
+			_, largest, err := loadNodeSizes(ctx, clientset)
+			if err != nil {
+				warnf("Error getting node sizes, skipping the packing estimate: %v", err)
+			}
+			normalizer.SetNodeSize(largest)
+		}
+
+		if reservations != nil {
+			allocatable, err := loadNodeAllocatable(ctx, clientset)
+			if err == nil {
+				var requests ResourceMetrics
+				requests, err = clusterRequests(ctx, clientset)
+				headroom = ResourceMetrics{CPU: allocatable.CPU - requests.CPU, Memory: allocatable.Memory - requests.Memory}
+			}
+			if err != nil {
+				warnf("Error getting the cluster's free headroom for --reservations: %v", err)
+			}
+		}
+
+		if baselineConfigMap != "" {
+			baselines = &baselineStore{clientset: clientset, namespace: baselineNamespace, name: baselineName}
+			if baseline, err = baselines.Load(ctx); err != nil {
+				warnf("Error loading the baseline, skipping the comparison: %v", err)
+				baselines = nil
+			}
+		}
+
+		var usage *podUsageIndex
+		done := timed("loading pod usage")
+		if caps.Metrics {
+			usage = loadPodUsage(ctx, clientset, metricsClientset, namespace)
+		} else if kubeletFallback {
+			usage = loadKubeletPodUsage(ctx, clientset, namespace)
+		}
+		done()
+
+		emit := dedupeEmitter(func(dm DeploymentMetrics) {
+			peaks.Add(dm)
+			add(dm)
+		})
+		if !ownerGraph && breakdown == "" && !stats {
+			next := emit
+			emit = func(dm DeploymentMetrics) {
+				dm.Owners = nil
+				next(dm)
+			}
+		}
+		if checkHPAs {
+			if caps.AutoscalingV1 || caps.AutoscalingV2 {
+				checker = newHPAChecker(ctx, clientset, namespace)
+				next := emit
+				emit = func(dm DeploymentMetrics) {
+					checker.Add(dm)
+					next(dm)
+				}
+			} else {
+				warnf("autoscaling API not available, ignoring --check-hpa")
+			}
+		}
+		if hpaCoverageReport {
+			if caps.AutoscalingV1 || caps.AutoscalingV2 {
+				coverage = newHPACoverage()
+				next := emit
+				emit = func(dm DeploymentMetrics) {
+					coverage.Add(dm)
+					next(dm)
+				}
+			} else {
+				warnf("autoscaling API not available, ignoring --hpa-coverage")
+			}
+		}
+		// Pods bigger than every node stay pending forever, so they're
+		// flagged on every run; without access to nodes the check is skipped
+		if _, largest, err := loadNodeSizes(ctx, clientset); err != nil {
+			debugf(verbosityDecisions, "%v, skipping the unschedulable pod check", err)
+		} else {
+			nodeFit = &nodeFitChecker{largest: largest}
+			next := emit
+			emit = func(dm DeploymentMetrics) {
+				nodeFit.Mark(&dm)
+				next(dm)
+			}
+		}
+		if imageSizes {
+			sizer = newImageSizer(ctx, newRegistryClient(&http.Client{Timeout: 30 * time.Second, Transport: traceRequests(nil)}))
+			next := emit
+			emit = func(dm DeploymentMetrics) {
+				sizer.Mark(&dm)
+				next(dm)
+			}
+		}
+		if len(namespaceLabelKeys) > 0 {
+			labels, err := loadNamespaceLabels(ctx, clientset, namespace, namespaceLabelKeys)
+			if err != nil {
+				warnf("Error getting namespace labels: %v", err)
+			}
+			next := emit
+			emit = func(dm DeploymentMetrics) {
+				dm.NamespaceLabels = labels[dm.Namespace]
+				next(dm)
+			}
+		}
+		// Canaries are marked before anything else sees them, so with
+		// --exclude-canary the checks and totals skip them alike, and
+		// suspended CronJobs, paused Deployments and Deployments scaled to
+		// zero with their --exclude flags, or all but those scaled to zero
+		// with --only-zero-replicas
+		marker := &deploymentMarker{ctx: ctx, clientset: clientset, namespace: namespace}
+		report := emit
+		emit = func(dm DeploymentMetrics) {
+			marker.Mark(&dm)
+			if (dm.Canary && excludeCanary) || (dm.Suspended && excludeSuspended) || (dm.Paused && excludePaused) {
+				return
+			}
+			if (excludeZeroReplicas && dm.ScaledToZero()) || (onlyZeroReplicas && !dm.ScaledToZero()) {
+				return
+			}
+			report(dm)
+		}
+		done = timed("collecting deployments")
+		getAllDeployments(ctx, clientset, usage, caps, namespace, deploymentName, labelSelector, allNamespaces, emit)
+		done()
+
+		if includeStaticPods {
+			done := timed("collecting static pods")
+			getAllStaticPods(ctx, clientset, usage, namespace, deploymentName, labelSelector, emit)
+			done()
+		}
 
 		if includeCronJobs {
-			cronJobDeployments := getAllCronJobs(ctx, clientset, metricsClientset, namespace, deploymentName, labelSelector, allNamespaces)
-			deployments = append(deployments, cronJobDeployments...)
+			done := timed("collecting cronjobs")
+			getAllCronJobs(ctx, clientset, usage, caps, namespace, deploymentName, labelSelector, allNamespaces, jobHistory, suspendedAsZero, cronWindow, now, emit)
+			done()
+		}
+	}
+
+	done := timed("printing the report")
+	printer.Flush()
+	done()
+
+	if reservations != nil {
+		reservations.Print(out, format, headroom, now)
+	}
+
+	if coverage != nil {
+		coverage.Print(out, format)
+	}
+
+	if baselines != nil {
+		current := history.Snapshot(now)
+		if baseline != nil {
+			printBaselineComparison(out, format, baselineConfigMap, *baseline, current)
+		}
+		if baseline == nil || updateBaseline {
+			if err := baselines.Save(ctx, current); err != nil {
+				warnf("Error saving the baseline: %v", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "Saved this run as the baseline in ConfigMap %s\n", baselineConfigMap)
+			}
+		}
+	}
+
+	var findings io.Writer = os.Stderr
+	if quiet {
+		findings = io.Discard
+	}
+	if checker != nil {
+		checker.Print(findings)
+	}
+	peaks.Print(findings)
+	if nodeFit != nil {
+		nodeFit.Print(findings)
+	}
+	if sizer != nil {
+		sizer.Print(findings)
+	}
+
+	if outputFile != "" {
+		if err := writeFileAtomic(outputFile, report.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	if signer != nil {
+		if err := signer.WriteSignature(signatureFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing signature: %v\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	if historyFile != "" {
+		if err := history.Save(historyFile, now); err != nil {
+			warnf("Error writing history file: %v", err)
 		}
 	}
 
-	printResults(deployments, outputType, usePorter, totalOnly, format)
+	os.Exit(runExitStatus(breached, partialResults))
+}
+
+// dedupeEmitter drops repeated workloads, passing on only the first occurrence
+// of each kind/namespace/name so overlapping selection paths can't double count
+func dedupeEmitter(emit func(DeploymentMetrics)) func(DeploymentMetrics) {
+	seen := make(map[string]bool)
+	return func(dm DeploymentMetrics) {
+		key := dm.Key()
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		emit(dm)
+	}
 }
 
 func validateFlags(usePorter bool, namespace string, allNamespaces bool, deploymentName string, labelSelector string) {
 	if namespace != "" && allNamespaces {
 		fmt.Fprintf(os.Stderr, "Error: --namespace and -A/--all-namespaces flags are mutually exclusive\n")
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	if deploymentName != "" && labelSelector != "" {
 		fmt.Fprintf(os.Stderr, "Error: --deployment and -l/--selector flags are mutually exclusive\n")
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+}
+
+// configFileOrDefault returns the --config path, or the default path when unset,
+// along with whether it was given explicitly
+func configFileOrDefault(configFile string) (string, bool) {
+	if configFile != "" {
+		return configFile, true
+	}
+	return defaultConfigPath(), false
+}
+
+// defaultKubeconfigPath returns the KUBECONFIG env var, falling back to ~/.kube/config
+func defaultKubeconfigPath() string {
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		return kubeconfig
 	}
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, ".kube", "config")
+	}
+	return ""
 }
 
-func setupKubernetesClients(kubeconfig string) (*kubernetes.Clientset, *versioned.Clientset) {
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+func setupKubernetesClients(kubeconfig, kubeContext string) (*kubernetes.Clientset, *versioned.Clientset) {
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
+	config.WrapTransport = traceRequests
+
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	metricsClientset, err := versioned.NewForConfig(config)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating metrics client: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 
 	return clientset, metricsClientset
 }
 
-func getAllDeployments(ctx context.Context, clientset *kubernetes.Clientset, metricsClientset *versioned.Clientset, namespace, deploymentName, labelSelector string, allNamespaces bool) []DeploymentMetrics {
-	var deployments []DeploymentMetrics
+// listPageSize bounds how many objects a single list call returns, so huge
+// clusters are walked in pages instead of one giant response
+const listPageSize = 500
 
-	if deploymentName != "" {
-		if allNamespaces {
-			deploymentList, err := clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error listing deployments: %v\n", err)
-				os.Exit(1)
-			}
-			found := false
-			for _, deployment := range deploymentList.Items {
-				if deployment.Name == deploymentName {
-					found = true
-					metrics, err := getDeploymentMetrics(ctx, clientset, metricsClientset, deployment.Namespace, deployment.Name)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Warning: Error getting metrics for deployment %s in namespace %s: %v\n",
-							deploymentName, deployment.Namespace, err)
-						continue
-					}
-					deployments = append(deployments, metrics)
-				}
-			}
-			if !found {
-				fmt.Fprintf(os.Stderr, "Error: No deployment named %s found in any namespace\n", deploymentName)
-				os.Exit(1)
-			}
-		} else {
-			deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error getting deployment %s: %v\n", deploymentName, err)
-				os.Exit(1)
-			}
-			metrics, err := getDeploymentMetrics(ctx, clientset, metricsClientset, deployment.Namespace, deployment.Name)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error getting metrics for deployment %s: %v\n", deploymentName, err)
-				os.Exit(1)
-			}
-			deployments = append(deployments, metrics)
+func getAllDeployments(ctx context.Context, clientset *kubernetes.Clientset, usage *podUsageIndex, caps clusterCapabilities, namespace, deploymentName, labelSelector string, allNamespaces bool, emit func(DeploymentMetrics)) {
+	if deploymentName != "" && !allNamespaces {
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting deployment %s: %v\n", deploymentName, err)
+			os.Exit(apiExitCode(err))
 		}
-	} else {
-		listOptions := metav1.ListOptions{}
-		if labelSelector != "" {
-			listOptions.LabelSelector = labelSelector
+		metrics, err := getDeploymentMetrics(ctx, clientset, usage, caps, deployment.Namespace, deployment.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting metrics for deployment %s: %v\n", deploymentName, err)
+			os.Exit(apiExitCode(err))
 		}
+		emit(metrics)
+		return
+	}
+
+	listOptions := metav1.ListOptions{LabelSelector: labelSelector, Limit: listPageSize}
+	if deploymentName != "" {
+		// Name across all namespaces
+		listOptions = metav1.ListOptions{FieldSelector: "metadata.name=" + deploymentName, Limit: listPageSize}
+	}
+
+	found := false
+	loaded := 0
+	for {
 		deploymentList, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
 		if err != nil {
+			progress.Clear()
 			fmt.Fprintf(os.Stderr, "Error listing deployments: %v\n", err)
-			os.Exit(1)
+			os.Exit(apiExitCode(err))
 		}
+		total := listTotal(loaded, len(deploymentList.Items), deploymentList.RemainingItemCount, deploymentList.Continue != "")
 		for _, deployment := range deploymentList.Items {
-			metrics, err := getDeploymentMetrics(ctx, clientset, metricsClientset, deployment.Namespace, deployment.Name)
+			found = true
+			loaded++
+			progress.Show("deployment", loaded, total, deployment.Namespace+"/"+deployment.Name)
+			metrics, err := getDeploymentMetrics(ctx, clientset, usage, caps, deployment.Namespace, deployment.Name)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Error getting metrics for deployment %s: %v\n", deployment.Name, err)
+				partialf("Error getting metrics for deployment %s in namespace %s: %v",
+					deployment.Name, deployment.Namespace, err)
 				continue
 			}
-			deployments = append(deployments, metrics)
+			emit(metrics)
+		}
+		if deploymentList.Continue == "" {
+			break
 		}
+		listOptions.Continue = deploymentList.Continue
 	}
+	progress.Clear()
 
-	return deployments
+	if deploymentName != "" && !found {
+		fmt.Fprintf(os.Stderr, "Error: No deployment named %s found in any namespace\n", deploymentName)
+		os.Exit(exitUsage)
+	}
 }
 
-func getAllCronJobs(ctx context.Context, clientset *kubernetes.Clientset, metricsClientset *versioned.Clientset, namespace, deploymentName, labelSelector string, allNamespaces bool) []DeploymentMetrics {
-	var deployments []DeploymentMetrics
+func getAllCronJobs(ctx context.Context, clientset *kubernetes.Clientset, usage *podUsageIndex, caps clusterCapabilities, namespace, deploymentName, labelSelector string, allNamespaces bool, jobHistory int, suspendedAsZero bool, window *timeWindow, now time.Time, emit func(DeploymentMetrics)) {
+	var jobRuns *jobRunIndex
+	if jobHistory > 0 {
+		jobRuns = newJobRunIndex(clientset, jobHistory)
+	}
 
-	if deploymentName != "" {
-		if allNamespaces {
-			cronJobList, err := clientset.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error listing cronjobs: %v\n", err)
-				os.Exit(1)
-			}
-			found := false
-			for _, cronJob := range cronJobList.Items {
-				if cronJob.Name == deploymentName {
-					found = true
-					metrics, err := getCronJobMetrics(ctx, clientset, metricsClientset, cronJob.Namespace, cronJob.Name)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Warning: Error getting metrics for cronjob %s in namespace %s: %v\n",
-							deploymentName, cronJob.Namespace, err)
-						continue
-					}
-					deployments = append(deployments, metrics)
-				}
-			}
-			if !found {
-				fmt.Fprintf(os.Stderr, "Warning: No cronjob named %s found in any namespace\n", deploymentName)
-			}
-		} else {
-			cronJob, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Error getting cronjob %s: %v\n", deploymentName, err)
-			} else {
-				metrics, err := getCronJobMetrics(ctx, clientset, metricsClientset, cronJob.Namespace, cronJob.Name)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: Error getting metrics for cronjob %s: %v\n", deploymentName, err)
-				} else {
-					deployments = append(deployments, metrics)
-				}
-			}
+	if deploymentName != "" && !allNamespaces {
+		cronJob, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			partialf("Error getting cronjob %s: %v", deploymentName, err)
+			return
 		}
-	} else {
-		listOptions := metav1.ListOptions{}
-		if labelSelector != "" {
-			listOptions.LabelSelector = labelSelector
+		if !cronJobInWindow(cronJob.Namespace, cronJob.Name, cronJob.Spec.Schedule, cronJob.Spec.TimeZone, window, now) {
+			return
 		}
+		warnings := &workerLog{}
+		metrics, err := getCronJobMetrics(ctx, clientset, usage, caps, cronJob.Namespace, cronJob.Name, jobRuns, suspendedAsZero, now, warnings)
+		warnings.flush()
+		if err != nil {
+			partialf("Error getting metrics for cronjob %s: %v", deploymentName, err)
+			return
+		}
+		emit(metrics)
+		return
+	}
+
+	listOptions := metav1.ListOptions{LabelSelector: labelSelector, Limit: listPageSize}
+	if deploymentName != "" {
+		// Name across all namespaces
+		listOptions = metav1.ListOptions{FieldSelector: "metadata.name=" + deploymentName, Limit: listPageSize}
+	}
+
+	found := false
+	loaded := 0
+	for {
 		cronJobList, err := clientset.BatchV1().CronJobs(namespace).List(ctx, listOptions)
 		if err != nil {
+			progress.Clear()
 			fmt.Fprintf(os.Stderr, "Error listing cronjobs: %v\n", err)
-			os.Exit(1)
+			os.Exit(apiExitCode(err))
 		}
+		total := listTotal(loaded, len(cronJobList.Items), cronJobList.RemainingItemCount, cronJobList.Continue != "")
+		var inWindow []batchv1.CronJob
 		for _, cronJob := range cronJobList.Items {
-			metrics, err := getCronJobMetrics(ctx, clientset, metricsClientset, cronJob.Namespace, cronJob.Name)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Error getting metrics for cronjob %s: %v\n", cronJob.Name, err)
-				continue
+			found = true
+			if cronJobInWindow(cronJob.Namespace, cronJob.Name, cronJob.Spec.Schedule, cronJob.Spec.TimeZone, window, now) {
+				inWindow = append(inWindow, cronJob)
+			} else {
+				loaded++
 			}
-			deployments = append(deployments, metrics)
 		}
+		fetch := func(cronJob batchv1.CronJob, warnings *workerLog) (DeploymentMetrics, error) {
+			return getCronJobMetrics(ctx, clientset, usage, caps, cronJob.Namespace, cronJob.Name, jobRuns, suspendedAsZero, now, warnings)
+		}
+		collectCronJobs(inWindow, fetch, func(result cronJobResult) {
+			loaded++
+			progress.Show("cronjob", loaded, total, result.cronJob.Namespace+"/"+result.cronJob.Name)
+			result.warnings.flush()
+			if result.err != nil {
+				partialf("Error getting metrics for cronjob %s in namespace %s: %v",
+					result.cronJob.Name, result.cronJob.Namespace, result.err)
+				return
+			}
+			emit(result.metrics)
+		})
+		if cronJobList.Continue == "" {
+			break
+		}
+		listOptions.Continue = cronJobList.Continue
+	}
+	progress.Clear()
+
+	if deploymentName != "" && !found {
+		warnf("No cronjob named %s found in any namespace", deploymentName)
 	}
+}
 
-	return deployments
+// getAllStaticPods emits every mirror pod in the namespace, or in all
+// namespaces if it is empty. Static pods have no owning workload, so each is
+// reported on its own.
+func getAllStaticPods(ctx context.Context, clientset *kubernetes.Clientset, usage *podUsageIndex, namespace, deploymentName, labelSelector string, emit func(DeploymentMetrics)) {
+	listOptions := metav1.ListOptions{LabelSelector: labelSelector, Limit: listPageSize}
+	if deploymentName != "" {
+		listOptions.FieldSelector = "metadata.name=" + deploymentName
+	}
+
+	for {
+		podList, err := clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+		if err != nil {
+			partialf("Error listing static pods: %v", err)
+			return
+		}
+		for _, pod := range podList.Items {
+			if isMirrorPod(pod) {
+				emit(staticPodMetrics(pod, usage))
+			}
+		}
+		if podList.Continue == "" {
+			break
+		}
+		listOptions.Continue = podList.Continue
+	}
+}
+
+// cronJobInWindow applies --cron-window, skipping CronJobs with schedules
+// that can't be parsed
+func cronJobInWindow(namespace, name, schedule string, timeZone *string, window *timeWindow, now time.Time) bool {
+	ok, err := scheduleFiresIn(schedule, timeZone, window, now)
+	if err != nil {
+		partialf("Skipping cronjob %s in namespace %s: %v", name, namespace, err)
+	}
+	return ok
 }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupeEmitter(t *testing.T) {
+	deployments := []DeploymentMetrics{
+		{Name: "web", Namespace: "prod", Type: "Deployment", CurrentReplicas: 2},
+		{Name: "web", Namespace: "prod", Type: "CronJob"},
+		{Name: "web", Namespace: "staging", Type: "Deployment"},
+		{Name: "web", Namespace: "prod", Type: "Deployment", CurrentReplicas: 5},
+	}
+
+	var got []DeploymentMetrics
+	emit := dedupeEmitter(func(dm DeploymentMetrics) {
+		got = append(got, dm)
+	})
+	for _, dm := range deployments {
+		emit(dm)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("len(got) = %d, want 3", len(got))
+	}
+	if got[0].CurrentReplicas != 2 {
+		t.Errorf("first occurrence should be kept, got CurrentReplicas = %d", got[0].CurrentReplicas)
+	}
+	if got[1].Key() != "CronJob/prod/web" {
+		t.Errorf("got[1].Key() = %v, want CronJob/prod/web", got[1].Key())
+	}
+	if got[2].Key() != "Deployment/staging/web" {
+		t.Errorf("got[2].Key() = %v, want Deployment/staging/web", got[2].Key())
+	}
+}
+
+// TestStreamWritesRowsAsCollected runs the CLI against a fake Porter API
+// whose second application only answers once the first row has reached
+// stdout, so a --stream run that buffers its rows would hang until the
+// fallback below
+func TestStreamWritesRowsAsCollected(t *testing.T) {
+	if args := os.Getenv("K8S_RESOURCE_CLI_TEST_ARGS"); args != "" {
+		os.Args = append([]string{"k8s-resource-cli"}, strings.Split(args, " ")...)
+		runCLI()
+		os.Exit(exitOK)
+	}
+
+	firstRow := make(chan struct{})
+	released := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/applications"):
+			w.Write([]byte(`{"applications":[{"id":"1","name":"web"},{"id":"2","name":"worker"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/applications/1"):
+			w.Write([]byte(`{"id":"1","name":"web","services":[{"name":"web","cpu_cores":0.5,"ram_megabytes":512,"instances":1}]}`))
+		case strings.HasSuffix(r.URL.Path, "/applications/2"):
+			select {
+			case <-firstRow:
+				released <- true
+			case <-time.After(5 * time.Second):
+				released <- false
+			}
+			w.Write([]byte(`{"id":"2","name":"worker","services":[{"name":"worker","cpu_cores":0.25,"ram_megabytes":256,"instances":1}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStreamWritesRowsAsCollected$")
+	cmd.Env = append(os.Environ(),
+		"K8S_RESOURCE_CLI_TEST_ARGS=--porter --porter-url "+server.URL+" --porter-token token --porter-project-id 1 --stream --quiet",
+		"HOME="+t.TempDir(), "XDG_CONFIG_HOME="+t.TempDir())
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(stdout)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if strings.HasPrefix(scanner.Text(), "web-web ") {
+			close(firstRow)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("CLI run failed: %v\n%s", err, strings.Join(lines, "\n"))
+	}
+	if !<-released {
+		t.Errorf("--stream didn't write the first row before collecting the next workload:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+// TestJSONSortedByDefault runs the CLI against a fake Porter API listing its
+// applications out of order and checks that JSON items come out by
+// namespace, then name, while JSON lines keep collection order
+func TestJSONSortedByDefault(t *testing.T) {
+	if args := os.Getenv("K8S_RESOURCE_CLI_TEST_ARGS"); args != "" {
+		os.Args = append([]string{"k8s-resource-cli"}, strings.Split(args, " ")...)
+		runCLI()
+		os.Exit(exitOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/applications"):
+			w.Write([]byte(`{"applications":[{"id":"1","name":"worker"},{"id":"2","name":"api"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/applications/1"):
+			w.Write([]byte(`{"id":"1","name":"worker","services":[{"name":"worker","cpu_cores":0.25,"ram_megabytes":256,"instances":1}]}`))
+		case strings.HasSuffix(r.URL.Path, "/applications/2"):
+			w.Write([]byte(`{"id":"2","name":"api","services":[{"name":"api","cpu_cores":0.5,"ram_megabytes":512,"instances":1}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		format string
+		want   []string
+	}{
+		{format: "json", want: []string{"api-api", "worker-worker"}},
+		{format: "jsonl", want: []string{"worker-worker", "api-api"}},
+	} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestJSONSortedByDefault$")
+		cmd.Env = append(os.Environ(),
+			"K8S_RESOURCE_CLI_TEST_ARGS=--porter --porter-url "+server.URL+" --porter-token token --porter-project-id 1 --format "+tt.format+" --quiet",
+			"HOME="+t.TempDir(), "XDG_CONFIG_HOME="+t.TempDir())
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("--format %s run failed: %v\n%s", tt.format, err, out)
+		}
+
+		var names []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if _, name, ok := strings.Cut(line, `"name":"`); ok {
+				names = append(names, name[:strings.Index(name, `"`)])
+			}
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("--format %s names = %v, want %v\n%s", tt.format, names, tt.want, out)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// clock tells the time for a run: report timestamps, history records,
+// reservation status and forecast ages. Pinning it with --now makes output
+// reproducible for golden tests and demos.
+type clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// fixedClock always returns the same time
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+// newClock returns the system clock for an empty --now, or a clock fixed
+// at the given RFC 3339 time
+func newClock(value string) (clock, error) {
+	if value == "" {
+		return systemClock{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --now '%s', expected an RFC 3339 time like 2026-01-02T15:04:05Z", value)
+	}
+	return fixedClock(t), nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewClock(t *testing.T) {
+	clk, err := newClock("")
+	if err != nil {
+		t.Fatalf("newClock(\"\") error = %v", err)
+	}
+	if _, ok := clk.(systemClock); !ok {
+		t.Errorf("newClock(\"\") = %T, want systemClock", clk)
+	}
+
+	clk, err = newClock("2026-01-02T15:04:05Z")
+	if err != nil {
+		t.Fatalf("newClock() error = %v", err)
+	}
+	want := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	if got := clk.Now(); !got.Equal(want) || !clk.Now().Equal(got) {
+		t.Errorf("newClock().Now() = %v, want %v every time", got, want)
+	}
+
+	if _, err := newClock("2026-01-02"); err == nil {
+		t.Errorf("newClock() with a date only should fail")
+	}
+}
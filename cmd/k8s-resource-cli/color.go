@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// ANSI colors for table rows. They are all the same length, and every row
+// gets one, so tabwriter keeps colored and plain rows aligned.
+const (
+	colorDefault = "\x1b[39m"
+	colorYellow  = "\x1b[33m"
+	colorRed     = "\x1b[31m"
+	colorReset   = "\x1b[0m"
+)
+
+// Values for --color
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// colorThresholds are the usage percentages of requests at which a row
+// turns yellow (warn) and red (crit)
+type colorThresholds struct {
+	warn float64
+	crit float64
+}
+
+// rowColor colors a workload red when it lacks a CPU or memory request or
+// its usage reaches crit percent of its requests, yellow from warn percent
+func (c colorThresholds) rowColor(dm DeploymentMetrics) string {
+	if dm.Requests.CPU == 0 || dm.Requests.Memory == 0 {
+		return colorRed
+	}
+	percent := sortValue(dm, SortByUsagePercent, OutputTypeUsage)
+	switch {
+	case percent >= c.crit:
+		return colorRed
+	case percent >= c.warn:
+		return colorYellow
+	}
+	return colorDefault
+}
+
+// parseColorThresholds validates --warn-threshold and --crit-threshold
+func parseColorThresholds(warn, crit float64) (colorThresholds, error) {
+	if warn <= 0 || crit <= 0 {
+		return colorThresholds{}, fmt.Errorf("--warn-threshold and --crit-threshold must be positive")
+	}
+	if warn > crit {
+		return colorThresholds{}, fmt.Errorf("--warn-threshold %g is above --crit-threshold %g", warn, crit)
+	}
+	return colorThresholds{warn: warn, crit: crit}, nil
+}
+
+// useColor decides whether table output is colored. auto colors only a
+// terminal, and not when NO_COLOR is set (https://no-color.org).
+func useColor(mode string, out *os.File) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto:
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := out.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid --color '%s'. Must be 'auto', 'always', or 'never'", mode)
+}
+
+// colorLine wraps a table line in color, resetting it at the end of the line
+func colorLine(cells []string, color string) []string {
+	colored := append([]string(nil), cells...)
+	colored[0] = color + colored[0]
+	colored[len(colored)-1] += colorReset
+	return colored
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRowColor(t *testing.T) {
+	thresholds := colorThresholds{warn: 80, crit: 90}
+	requests := ResourceMetrics{CPU: 1000, Memory: 1000}
+
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want string
+	}{
+		{"low usage", DeploymentMetrics{Usage: ResourceMetrics{CPU: 500, Memory: 500}, Requests: requests}, colorDefault},
+		{"warn on memory", DeploymentMetrics{Usage: ResourceMetrics{CPU: 100, Memory: 850}, Requests: requests}, colorYellow},
+		{"crit on cpu", DeploymentMetrics{Usage: ResourceMetrics{CPU: 950, Memory: 100}, Requests: requests}, colorRed},
+		{"missing memory request", DeploymentMetrics{Requests: ResourceMetrics{CPU: 1000}}, colorRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := thresholds.rowColor(tt.dm); got != tt.want {
+				t.Errorf("rowColor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseColorThresholds(t *testing.T) {
+	if _, err := parseColorThresholds(80, 90); err != nil {
+		t.Errorf("parseColorThresholds(80, 90) error = %v", err)
+	}
+	if _, err := parseColorThresholds(95, 90); err == nil {
+		t.Error("parseColorThresholds() should reject a warn threshold above crit")
+	}
+	if _, err := parseColorThresholds(0, 90); err == nil {
+		t.Error("parseColorThresholds() should reject a zero threshold")
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if got, _ := useColor(ColorAlways, file); !got {
+		t.Error("useColor(always) = false")
+	}
+	if got, _ := useColor(ColorAuto, file); got {
+		t.Error("useColor(auto) should not color a regular file")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if got, _ := useColor(ColorAuto, os.Stdout); got {
+		t.Error("useColor(auto) should honor NO_COLOR")
+	}
+	if _, err := useColor("sometimes", file); err == nil {
+		t.Error("useColor() should reject an unknown mode")
+	}
+}
+
+func TestTablePrinterColors(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{colors: &colorThresholds{warn: 80, crit: 90}}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Usage: ResourceMetrics{CPU: 425, Memory: 524288}, Requests: ResourceMetrics{CPU: 500, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "worker", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 250}})
+	p.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[1], colorYellow+"web") || !strings.HasPrefix(lines[2], colorRed+"worker") {
+		t.Errorf("rows should be yellow and red:\n%q", lines)
+	}
+	for _, line := range lines {
+		if !strings.HasSuffix(line, colorReset) {
+			t.Errorf("line %q should end with a color reset", line)
+		}
+	}
+	if column := strings.Index(lines[0], "NAMESPACE"); strings.Index(lines[1], "shop") != column || strings.Index(lines[2], "shop") != column {
+		t.Errorf("colored columns are misaligned:\n%s", buf.String())
+	}
+}
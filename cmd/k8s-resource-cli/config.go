@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// fileConfig is the optional config file, YAML or JSON
+type fileConfig struct {
+	// Headers renames report column headers, keyed by the built-in name
+	// (e.g. DEPLOYMENT: WORKLOAD). TOTAL renames the totals row label.
+	Headers map[string]string `json:"headers"`
+	// GridIntensity names grid carbon intensities in gCO2e per kWh, e.g.
+	// eu-west-1: 300, for --carbon to pick by region
+	GridIntensity map[string]float64 `json:"gridIntensity"`
+	// PorterProfiles names Porter accounts, e.g. staging and production,
+	// for --porter-profile to pick from
+	PorterProfiles map[string]porterProfile `json:"porterProfiles"`
+}
+
+// porterProfile is a named set of Porter credentials. Fields left empty
+// fall back to the flags and environment variables.
+type porterProfile struct {
+	Token     string `json:"token"`
+	ProjectID string `json:"projectId"`
+	URL       string `json:"url"`
+}
+
+// porterProfile looks up a profile by name
+func (c fileConfig) porterProfile(name string) (porterProfile, error) {
+	profile, ok := c.PorterProfiles[name]
+	if !ok {
+		if len(c.PorterProfiles) == 0 {
+			return profile, fmt.Errorf("unknown --porter-profile '%s': the config file has no porterProfiles", name)
+		}
+		return profile, fmt.Errorf("unknown --porter-profile '%s'. Must be one of %s", name, strings.Join(sortedKeys(c.PorterProfiles), ", "))
+	}
+	return profile, nil
+}
+
+// apply overrides the Porter settings with the profile's, except those set
+// explicitly on the command line: flags beat the profile, and the profile
+// beats the PORTER_* environment variables.
+func (p porterProfile) apply(fs *flag.FlagSet, token, projectID, baseURL *string) {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if p.Token != "" && !set["porter-token"] {
+		*token = p.Token
+	}
+	if p.ProjectID != "" && !set["porter-project-id"] {
+		*projectID = p.ProjectID
+	}
+	if p.URL != "" && !set["porter-url"] {
+		*baseURL = p.URL
+	}
+}
+
+// headerLabels holds the header overrides loaded from the config file
+var headerLabels map[string]string
+
+// header returns the configured label for a built-in header name
+func header(name string) string {
+	if label, ok := headerLabels[name]; ok && label != "" {
+		return label
+	}
+	return name
+}
+
+// defaultConfigPath returns <user config dir>/k8s-resource-cli/config.yaml
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "k8s-resource-cli", "config.yaml")
+}
+
+// loadConfig reads the config file. A missing file is only an error when
+// the path was given explicitly rather than defaulted.
+func loadConfig(path string, explicit bool) (fileConfig, error) {
+	var config fileConfig
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return config, nil
+		}
+		return config, err
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return config, nil
+}
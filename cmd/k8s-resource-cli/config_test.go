@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("headers:\n  DEPLOYMENT: WORKLOAD\n  TOTAL: SUM\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.Headers["DEPLOYMENT"] != "WORKLOAD" {
+		t.Errorf("Headers[DEPLOYMENT] = %v, want WORKLOAD", config.Headers["DEPLOYMENT"])
+	}
+
+	missing := filepath.Join(dir, "missing.yaml")
+	if _, err := loadConfig(missing, false); err != nil {
+		t.Errorf("loadConfig() on missing default path error = %v, want nil", err)
+	}
+	if _, err := loadConfig(missing, true); err == nil {
+		t.Error("loadConfig() on missing explicit path should return an error")
+	}
+}
+
+func TestHeaderOverrides(t *testing.T) {
+	headerLabels = map[string]string{"DEPLOYMENT": "WORKLOAD", "CPU": ""}
+	defer func() { headerLabels = nil }()
+
+	if got := header("DEPLOYMENT"); got != "WORKLOAD" {
+		t.Errorf("header(DEPLOYMENT) = %v, want WORKLOAD", got)
+	}
+	if got := header("CPU"); got != "CPU" {
+		t.Errorf("header(CPU) with empty override = %v, want CPU", got)
+	}
+	if got := header("MEMORY"); got != "MEMORY" {
+		t.Errorf("header(MEMORY) = %v, want MEMORY", got)
+	}
+}
+
+func TestPorterProfile(t *testing.T) {
+	config := fileConfig{PorterProfiles: map[string]porterProfile{
+		"staging":    {Token: "staging-token", ProjectID: "12", URL: "https://porter.staging.example.com"},
+		"production": {Token: "production-token", ProjectID: "34"},
+	}}
+	if _, err := config.porterProfile("dev"); err == nil {
+		t.Error("porterProfile(dev) should return an error for an unknown profile")
+	}
+	if _, err := (fileConfig{}).porterProfile("staging"); err == nil {
+		t.Error("porterProfile(staging) without porterProfiles should return an error")
+	}
+
+	tests := []struct {
+		name      string
+		profile   string
+		args      []string
+		token     string
+		projectID string
+		baseURL   string
+	}{
+		{"profile over environment", "staging", nil, "staging-token", "12", "https://porter.staging.example.com"},
+		{"flags over profile", "staging", []string{"--porter-project-id", "99"}, "staging-token", "99", "https://porter.staging.example.com"},
+		{"empty fields keep defaults", "production", nil, "production-token", "34", "https://dashboard.porter.run"},
+	}
+	for _, tt := range tests {
+		var token, projectID, baseURL string
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.StringVar(&token, "porter-token", "env-token", "")
+		fs.StringVar(&projectID, "porter-project-id", "env-project", "")
+		fs.StringVar(&baseURL, "porter-url", "https://dashboard.porter.run", "")
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		profile, err := config.porterProfile(tt.profile)
+		if err != nil {
+			t.Fatalf("%s: porterProfile() error = %v", tt.name, err)
+		}
+		profile.apply(fs, &token, &projectID, &baseURL)
+		if token != tt.token || projectID != tt.projectID || baseURL != tt.baseURL {
+			t.Errorf("%s: got %q, %q, %q, want %q, %q, %q", tt.name, token, projectID, baseURL, tt.token, tt.projectID, tt.baseURL)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+type kubeContextInfo struct {
+	Name      string
+	Cluster   string
+	Namespace string
+	Current   bool
+	Reachable string
+}
+
+// runContextsCommand implements the "contexts" subcommand, listing the
+// kubeconfig contexts and whether their clusters can be reached
+func runContextsCommand(args []string) {
+	var kubeconfig string
+	var noProbe bool
+	var timeout time.Duration
+
+	fs := flag.NewFlagSet("contexts", flag.ContinueOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.BoolVar(&noProbe, "no-probe", false, "Skip the cluster reachability check")
+	fs.DurationVar(&timeout, "timeout", 3*time.Second, "Timeout for each cluster reachability check")
+	parseFlags(fs, args)
+
+	config, err := loadKubeconfig(kubeconfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	contexts := listKubeContexts(config)
+	if len(contexts) == 0 {
+		fmt.Println("No contexts found")
+		return
+	}
+
+	if !noProbe {
+		probeKubeContexts(config, contexts, timeout)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "CURRENT\tNAME\tCLUSTER\tNAMESPACE\tREACHABLE\n")
+	for _, c := range contexts {
+		current := ""
+		if c.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, c.Name, c.Cluster, c.Namespace, c.Reachable)
+	}
+	w.Flush()
+}
+
+// listKubeContexts returns the contexts in the kubeconfig sorted by name
+func listKubeContexts(config *clientcmdapi.Config) []kubeContextInfo {
+	var contexts []kubeContextInfo
+	for name, ctx := range config.Contexts {
+		namespace := ctx.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		contexts = append(contexts, kubeContextInfo{
+			Name:      name,
+			Cluster:   ctx.Cluster,
+			Namespace: namespace,
+			Current:   name == config.CurrentContext,
+			Reachable: "-",
+		})
+	}
+
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+
+	return contexts
+}
+
+// probeKubeContexts checks each cluster once (contexts sharing a cluster reuse
+// the result) and records whether its API server answered within the timeout
+func probeKubeContexts(config *clientcmdapi.Config, contexts []kubeContextInfo, timeout time.Duration) {
+	// Pick one context per cluster to probe with
+	probeContext := make(map[string]string)
+	for _, c := range contexts {
+		if _, ok := probeContext[c.Cluster]; !ok {
+			probeContext[c.Cluster] = c.Name
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]string)
+
+	for cluster, contextName := range probeContext {
+		wg.Add(1)
+		go func(cluster, contextName string) {
+			defer wg.Done()
+			result := "yes"
+			if err := probeKubeContext(config, contextName, timeout); err != nil {
+				result = "no"
+			}
+			mu.Lock()
+			results[cluster] = result
+			mu.Unlock()
+		}(cluster, contextName)
+	}
+	wg.Wait()
+
+	for i := range contexts {
+		contexts[i].Reachable = results[contexts[i].Cluster]
+	}
+}
+
+func probeKubeContext(config *clientcmdapi.Config, contextName string, timeout time.Duration) error {
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return err
+	}
+	restConfig.Timeout = timeout
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	_, err = discoveryClient.ServerVersion()
+	return err
+}
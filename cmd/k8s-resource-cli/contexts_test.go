@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestListKubeContexts(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.CurrentContext = "prod"
+	config.Contexts["staging"] = &clientcmdapi.Context{Cluster: "staging-cluster", Namespace: "apps"}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod-cluster"}
+
+	contexts := listKubeContexts(config)
+	if len(contexts) != 2 {
+		t.Fatalf("len(contexts) = %d, want 2", len(contexts))
+	}
+
+	if contexts[0].Name != "prod" || contexts[1].Name != "staging" {
+		t.Errorf("contexts not sorted by name: %v, %v", contexts[0].Name, contexts[1].Name)
+	}
+	if !contexts[0].Current {
+		t.Error("prod should be marked as current")
+	}
+	if contexts[1].Current {
+		t.Error("staging should not be marked as current")
+	}
+	if contexts[0].Namespace != "default" {
+		t.Errorf("prod Namespace = %v, want default", contexts[0].Namespace)
+	}
+	if contexts[1].Namespace != "apps" {
+		t.Errorf("staging Namespace = %v, want apps", contexts[1].Namespace)
+	}
+}
+
+func TestLoadKubeconfigMergesList(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "prod")
+	second := filepath.Join(dir, "staging")
+	files := map[string]string{
+		first: `apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod-cluster
+  cluster: {server: "https://prod.example.com"}
+contexts:
+- name: prod
+  context: {cluster: prod-cluster}
+`,
+		second: `apiVersion: v1
+kind: Config
+current-context: staging
+clusters:
+- name: staging-cluster
+  cluster: {server: "https://staging.example.com"}
+contexts:
+- name: staging
+  context: {cluster: staging-cluster, namespace: apps}
+`,
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kubeconfig := first + string(filepath.ListSeparator) + second
+	config, err := loadKubeconfig(kubeconfig)
+	if err != nil {
+		t.Fatalf("loadKubeconfig() error = %v", err)
+	}
+	contexts := listKubeContexts(config)
+	if len(contexts) != 2 || contexts[0].Name != "prod" || contexts[1].Name != "staging" {
+		t.Fatalf("contexts = %+v, want prod and staging", contexts)
+	}
+	if !contexts[0].Current {
+		t.Error("prod should be current, the first file wins")
+	}
+
+	// A context from the second file works with --context everywhere else too
+	restConfig, err := buildRESTConfig(kubeconfig, "staging")
+	if err != nil {
+		t.Fatalf("buildRESTConfig() error = %v", err)
+	}
+	if restConfig.Host != "https://staging.example.com" {
+		t.Errorf("Host = %q, want the staging cluster", restConfig.Host)
+	}
+	if namespace, err := getNamespaceFromKubeconfig(kubeconfig, "staging"); err != nil || namespace != "apps" {
+		t.Errorf("getNamespaceFromKubeconfig() = %q, %v, want apps", namespace, err)
+	}
+	if context, cluster := kubeconfigContextCluster(kubeconfig, ""); context != "prod" || cluster != "prod-cluster" {
+		t.Errorf("kubeconfigContextCluster() = %q, %q, want prod, prod-cluster", context, cluster)
+	}
+}
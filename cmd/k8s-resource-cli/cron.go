@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed standard 5-field cron expression, as accepted by
+// CronJob spec.schedule. Each field is a bitset of the values it matches.
+type cronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// Kubernetes, like cron, fires when either day field matches if both
+	// are restricted, and uses only the restricted one otherwise
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var weekdayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// parseCronSchedule parses a CronJob schedule. A leading TZ= or CRON_TZ=
+// assignment is ignored; times are in the schedule's own time zone.
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		fields = fields[1:]
+	}
+	if len(fields) == 1 {
+		expanded, ok := cronMacros[strings.ToLower(fields[0])]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cron schedule %q", spec)
+		}
+		fields = strings.Fields(expanded)
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron schedule %q must have 5 fields", spec)
+	}
+
+	var s cronSchedule
+	var err error
+	if s.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.months, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if s.weekdays, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, err
+	}
+	// 7 is an alias for Sunday
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	s.daysRestricted = !isCronWildcard(fields[2])
+	s.weekdaysRestricted = !isCronWildcard(fields[4])
+
+	return &s, nil
+}
+
+func isCronWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseCronField parses a comma-separated list of values, a-b ranges and
+// */n or a-b/n steps into a bitset
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in cron field %q", field)
+			}
+		}
+
+		var lo, hi int
+		if isCronWildcard(rangePart) {
+			lo, hi = min, max
+		} else {
+			loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseCronValue(loPart, names); err != nil {
+				return 0, fmt.Errorf("invalid cron field %q: %w", field, err)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(hiPart, names); err != nil {
+					return 0, fmt.Errorf("invalid cron field %q: %w", field, err)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("cron field %q out of range %d-%d", field, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToUpper(value)]; ok {
+		return n, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// timeWindow is a daily time range in minutes since midnight. The start is
+// inclusive and the end exclusive; a window ending before it starts wraps
+// past midnight.
+type timeWindow struct {
+	start int
+	end   int
+}
+
+// parseTimeWindow parses "HH:MM-HH:MM"
+func parseTimeWindow(value string) (*timeWindow, error) {
+	startPart, endPart, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid time window %q, expected HH:MM-HH:MM", value)
+	}
+	start, err := parseClock(startPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time window %q: %w", value, err)
+	}
+	end, err := parseClock(endPart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time window %q: %w", value, err)
+	}
+	return &timeWindow{start: start, end: end}, nil
+}
+
+func parseClock(value string) (int, error) {
+	hourPart, minutePart, ok := strings.Cut(strings.TrimSpace(value), ":")
+	if !ok {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	hour, err := strconv.Atoi(hourPart)
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	minute, err := strconv.Atoi(minutePart)
+	if err != nil || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return hour*60 + minute, nil
+}
+
+func (w *timeWindow) contains(minuteOfDay int) bool {
+	if w.start <= w.end {
+		return minuteOfDay >= w.start && minuteOfDay < w.end
+	}
+	return minuteOfDay >= w.start || minuteOfDay < w.end
+}
+
+// firesIn reports whether the schedule, running in loc, has any hour:minute
+// inside the window, which is in UTC. A zone with daylight saving time is
+// checked at both its winter and its summer offset of the year.
+func (s *cronSchedule) firesIn(w *timeWindow, loc *time.Location, year int) bool {
+	offsets := make(map[int]bool)
+	for _, month := range []time.Month{time.January, time.July} {
+		_, offset := time.Date(year, month, 1, 0, 0, 0, 0, loc).Zone()
+		offsets[offset/60] = true
+	}
+
+	for hour := 0; hour < 24; hour++ {
+		if s.hours&(1<<uint(hour)) == 0 {
+			continue
+		}
+		for minute := 0; minute < 60; minute++ {
+			if s.minutes&(1<<uint(minute)) == 0 {
+				continue
+			}
+			for offset := range offsets {
+				if w.contains(((hour*60+minute-offset)%1440 + 1440) % 1440) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// scheduleFiresIn reports whether a CronJob schedule fires inside the window,
+// in UTC. A nil window matches every schedule.
+func scheduleFiresIn(schedule string, timeZone *string, window *timeWindow, now time.Time) (bool, error) {
+	if window == nil {
+		return true, nil
+	}
+	loc, err := cronLocation(schedule, timeZone)
+	if err != nil {
+		return false, err
+	}
+	s, err := parseCronSchedule(schedule)
+	if err != nil {
+		return false, err
+	}
+	return s.firesIn(window, loc, now.UTC().Year()), nil
+}
+
+// matchesDay reports whether the schedule fires on t's day
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.daysRestricted && s.weekdaysRestricted:
+		return day || weekday
+	case s.daysRestricted:
+		return day
+	case s.weekdaysRestricted:
+		return weekday
+	}
+	return true
+}
+
+// next returns the first time after t the schedule fires, in t's location,
+// or false when it never does within five years (e.g. "0 0 30 2 *")
+func (s *cronSchedule) next(t time.Time) (time.Time, bool) {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// cronLocation returns the time zone a CronJob's schedule runs in: its
+// spec.timeZone, else a TZ= or CRON_TZ= prefix of the schedule, else UTC,
+// which kube-controller-manager almost always runs in
+func cronLocation(schedule string, timeZone *string) (*time.Location, error) {
+	name := ""
+	if timeZone != nil {
+		name = *timeZone
+	} else if fields := strings.Fields(schedule); len(fields) > 0 {
+		if zone, ok := strings.CutPrefix(fields[0], "CRON_TZ="); ok {
+			name = zone
+		} else if zone, ok := strings.CutPrefix(fields[0], "TZ="); ok {
+			name = zone
+		}
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// nextCronRun returns when a CronJob schedule next fires after now, or nil
+// when it never does
+func nextCronRun(schedule string, timeZone *string, now time.Time) (*time.Time, error) {
+	loc, err := cronLocation(schedule, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseCronSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+	next, ok := s.next(now.In(loc))
+	if !ok {
+		return nil, nil
+	}
+	return &next, nil
+}
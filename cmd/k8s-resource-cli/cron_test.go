@@ -0,0 +1,160 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"0 2 * * *", false},
+		{"*/15 0-5 * * MON-FRI", false},
+		{"30 1,3 1 JAN,JUL 7", false},
+		{"TZ=Europe/Amsterdam 0 3 * * *", false},
+		{"@daily", false},
+		{"@every 5m", true},
+		{"0 2 * *", true},
+		{"60 * * * *", true},
+		{"0 5-2 * * *", true},
+		{"*/0 * * * *", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseCronSchedule(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseCronSchedule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleSundayAlias(t *testing.T) {
+	s, err := parseCronSchedule("0 0 * * 7")
+	if err != nil {
+		t.Fatalf("parseCronSchedule() error = %v", err)
+	}
+	if s.weekdays&1 == 0 {
+		t.Errorf("weekday 7 should also match Sunday (0)")
+	}
+}
+
+func TestParseTimeWindow(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantStart int
+		wantEnd   int
+		wantErr   bool
+	}{
+		{"00:00-06:00", 0, 360, false},
+		{"22:30-02:00", 1350, 120, false},
+		{"18:00-24:00", 1080, 1440, false},
+		{"06:00", 0, 0, true},
+		{"25:00-06:00", 0, 0, true},
+		{"00:60-06:00", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			w, err := parseTimeWindow(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimeWindow(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && (w.start != tt.wantStart || w.end != tt.wantEnd) {
+				t.Errorf("parseTimeWindow(%q) = %d-%d, want %d-%d", tt.value, w.start, w.end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestScheduleFiresIn(t *testing.T) {
+	nightly := &timeWindow{start: 0, end: 6 * 60}
+	overnight := &timeWindow{start: 22 * 60, end: 2 * 60}
+
+	tests := []struct {
+		name     string
+		schedule string
+		window   *timeWindow
+		want     bool
+	}{
+		{"no window", "0 12 * * *", nil, true},
+		{"inside", "30 2 * * *", nightly, true},
+		{"end is exclusive", "0 6 * * *", nightly, false},
+		{"daytime", "0 12 * * *", nightly, false},
+		{"every 15 minutes", "*/15 * * * *", nightly, true},
+		{"one of several hours", "0 4,14 * * *", nightly, true},
+		{"wrapping window late", "0 23 * * *", overnight, true},
+		{"wrapping window early", "45 1 * * *", overnight, true},
+		{"wrapping window outside", "0 3 * * *", overnight, false},
+		{"macro", "@midnight", nightly, true},
+		// 02:00 in Tokyo is 17:00 UTC
+		{"other zone outside", "CRON_TZ=Asia/Tokyo 0 2 * * *", nightly, false},
+		{"other zone inside", "CRON_TZ=Asia/Tokyo 0 12 * * *", nightly, true},
+		// 06:30 in New York is 11:30 UTC in winter and 10:30 UTC in summer
+		{"daylight saving time", "TZ=America/New_York 30 6 * * *", &timeWindow{start: 10 * 60, end: 11 * 60}, true},
+	}
+
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scheduleFiresIn(tt.schedule, nil, tt.window, now)
+			if err != nil {
+				t.Fatalf("scheduleFiresIn() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("scheduleFiresIn(%q) = %v, want %v", tt.schedule, got, tt.want)
+			}
+		})
+	}
+
+	tokyo := "Asia/Tokyo"
+	if got, err := scheduleFiresIn("0 2 * * *", &tokyo, nightly, now); err != nil || got {
+		t.Errorf("scheduleFiresIn() with spec.timeZone Asia/Tokyo = %v, %v, want false", got, err)
+	}
+}
+
+func TestNextCronRun(t *testing.T) {
+	// A Friday
+	now := time.Date(2026, 10, 16, 10, 17, 30, 0, time.UTC)
+	amsterdam := "Europe/Amsterdam"
+
+	tests := []struct {
+		name     string
+		schedule string
+		timeZone *string
+		want     time.Time
+	}{
+		{"later today", "0 12 * * *", nil, time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)},
+		{"tomorrow", "0 2 * * *", nil, time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)},
+		{"every 15 minutes", "*/15 * * * *", nil, time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)},
+		{"weekday", "0 0 * * MON", nil, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"first of the month", "0 0 1 * *", nil, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"either day field", "0 0 1 * MON", nil, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{"next year", "@yearly", nil, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"spec time zone", "0 9 * * *", &amsterdam, time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC)},
+		{"schedule time zone", "CRON_TZ=America/New_York 0 9 * * *", nil, time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextCronRun(tt.schedule, tt.timeZone, now)
+			if err != nil {
+				t.Fatalf("nextCronRun() error = %v", err)
+			}
+			if got == nil || !got.Equal(tt.want) {
+				t.Errorf("nextCronRun(%q) = %v, want %v", tt.schedule, got, tt.want)
+			}
+		})
+	}
+
+	if got, err := nextCronRun("0 0 30 2 *", nil, now); err != nil || got != nil {
+		t.Errorf("nextCronRun() for February 30th = %v, %v, want nil", got, err)
+	}
+	unknown := "Mars/Olympus_Mons"
+	if _, err := nextCronRun("0 0 * * *", &unknown, now); err == nil {
+		t.Error("nextCronRun() should return an error for an unknown time zone")
+	}
+}
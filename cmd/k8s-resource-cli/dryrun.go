@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// dryRunOptions are the flags that decide which API calls a run makes
+type dryRunOptions struct {
+	Namespace         string // empty for all namespaces
+	DeploymentName    string
+	LabelSelector     string
+	IncludeCronJobs   bool
+	IncludeStaticPods bool
+	JobHistory        int
+	KubeletFallback   bool
+	NodeLookups       int // node or quota lists made by the unschedulable pod check, --check-hpa, --batch-overlay, --normalize and --reservations
+}
+
+// plannedQuery is one line of the --dry-run plan
+type plannedQuery struct {
+	Resource string
+	Scope    string
+	Objects  int // -1 when not counted
+	Calls    int
+}
+
+// objectLister lists one page, returning the item count and list metadata
+type objectLister func(ctx context.Context, opts metav1.ListOptions) (int, metav1.ListMeta, error)
+
+// countObjects counts what a list would return along with the API calls
+// counting took. One call with limit 1 is enough when the API server
+// reports remainingItemCount, which it doesn't for selector queries; then
+// the list is paged through.
+func countObjects(ctx context.Context, list objectLister, opts metav1.ListOptions) (int, int, error) {
+	opts.Limit = 1
+	items, meta, err := list(ctx, opts)
+	if err != nil {
+		return 0, 1, err
+	}
+	count, calls := items, 1
+	if meta.RemainingItemCount != nil {
+		return count + int(*meta.RemainingItemCount), calls, nil
+	}
+	opts.Limit = listPageSize
+	for meta.Continue != "" {
+		opts.Continue = meta.Continue
+		items, meta, err = list(ctx, opts)
+		calls++
+		if err != nil {
+			return count, calls, err
+		}
+		count += items
+	}
+	return count, calls, nil
+}
+
+// pages is how many list calls it takes to read n objects
+func pages(n int) int {
+	return max(1, (n+listPageSize-1)/listPageSize)
+}
+
+// planQueries works out the API calls a run with these options would make,
+// counting objects with cheap list calls. It returns the plan and how many
+// calls the counting itself made.
+func planQueries(ctx context.Context, clientset *kubernetes.Clientset, caps clusterCapabilities, opts dryRunOptions) ([]plannedQuery, int, error) {
+	scope := opts.Namespace
+	if scope == "" {
+		scope = "all namespaces"
+	}
+	selector := metav1.ListOptions{LabelSelector: opts.LabelSelector}
+	if opts.DeploymentName != "" {
+		selector = metav1.ListOptions{FieldSelector: "metadata.name=" + opts.DeploymentName}
+	}
+
+	plan := []plannedQuery{{Resource: "discovery", Scope: "cluster", Objects: -1, Calls: 1}}
+	spent := 1 // the discovery call already made
+
+	count := func(list objectLister, opts metav1.ListOptions) (int, error) {
+		n, calls, err := countObjects(ctx, list, opts)
+		spent += calls
+		return n, err
+	}
+
+	// Usage comes from metrics-server per namespace, or the kubelet per node
+	if caps.Metrics {
+		namespaces := 1
+		if opts.Namespace == "" {
+			n, err := count(func(ctx context.Context, o metav1.ListOptions) (int, metav1.ListMeta, error) {
+				l, err := clientset.CoreV1().Namespaces().List(ctx, o)
+				if err != nil {
+					return 0, metav1.ListMeta{}, err
+				}
+				return len(l.Items), l.ListMeta, nil
+			}, metav1.ListOptions{})
+			if err != nil {
+				return nil, spent, fmt.Errorf("error counting namespaces: %w", err)
+			}
+			namespaces = n
+			plan = append(plan, plannedQuery{Resource: "namespaces", Scope: "cluster", Objects: n, Calls: pages(n)})
+		}
+		plan = append(plan, plannedQuery{Resource: "pod metrics", Scope: scope, Objects: -1, Calls: namespaces})
+	} else if opts.KubeletFallback {
+		n, err := count(func(ctx context.Context, o metav1.ListOptions) (int, metav1.ListMeta, error) {
+			l, err := clientset.CoreV1().Nodes().List(ctx, o)
+			if err != nil {
+				return 0, metav1.ListMeta{}, err
+			}
+			return len(l.Items), l.ListMeta, nil
+		}, metav1.ListOptions{})
+		if err != nil {
+			return nil, spent, fmt.Errorf("error counting nodes: %w", err)
+		}
+		plan = append(plan, plannedQuery{Resource: "nodes", Scope: "cluster", Objects: n, Calls: pages(n)})
+		plan = append(plan, plannedQuery{Resource: "kubelet stats", Scope: "every node", Objects: -1, Calls: n})
+	}
+
+	// Each Deployment takes a get, a pod list and an HPA list; the names are
+	// listed twice, once more to find Flagger canaries
+	deployments, err := count(func(ctx context.Context, o metav1.ListOptions) (int, metav1.ListMeta, error) {
+		l, err := clientset.AppsV1().Deployments(opts.Namespace).List(ctx, o)
+		if err != nil {
+			return 0, metav1.ListMeta{}, err
+		}
+		return len(l.Items), l.ListMeta, nil
+	}, selector)
+	if err != nil {
+		return nil, spent, fmt.Errorf("error counting deployments: %w", err)
+	}
+	plan = append(plan, plannedQuery{Resource: "deployments", Scope: scope, Objects: deployments, Calls: 2*pages(deployments) + 3*deployments})
+
+	// Each CronJob takes a get, plus a Job list with --job-history and a pod
+	// list per active Job, which isn't counted here
+	if opts.IncludeCronJobs && caps.BatchV1 {
+		cronJobs, err := count(func(ctx context.Context, o metav1.ListOptions) (int, metav1.ListMeta, error) {
+			l, err := clientset.BatchV1().CronJobs(opts.Namespace).List(ctx, o)
+			if err != nil {
+				return 0, metav1.ListMeta{}, err
+			}
+			return len(l.Items), l.ListMeta, nil
+		}, selector)
+		if err != nil {
+			return nil, spent, fmt.Errorf("error counting cronjobs: %w", err)
+		}
+		perCronJob := 1
+		if opts.JobHistory > 0 {
+			perCronJob++
+		}
+		plan = append(plan, plannedQuery{Resource: "cronjobs", Scope: scope, Objects: cronJobs, Calls: pages(cronJobs) + perCronJob*cronJobs})
+	}
+
+	if opts.IncludeStaticPods {
+		pods, err := count(func(ctx context.Context, o metav1.ListOptions) (int, metav1.ListMeta, error) {
+			l, err := clientset.CoreV1().Pods(opts.Namespace).List(ctx, o)
+			if err != nil {
+				return 0, metav1.ListMeta{}, err
+			}
+			return len(l.Items), l.ListMeta, nil
+		}, selector)
+		if err != nil {
+			return nil, spent, fmt.Errorf("error counting pods: %w", err)
+		}
+		plan = append(plan, plannedQuery{Resource: "pods (static pods)", Scope: scope, Objects: pods, Calls: pages(pods)})
+	}
+
+	if opts.NodeLookups > 0 {
+		plan = append(plan, plannedQuery{Resource: "nodes and quotas", Scope: "cluster", Objects: -1, Calls: opts.NodeLookups})
+	}
+
+	return plan, spent, nil
+}
+
+func printQueryPlan(out io.Writer, plan []plannedQuery, spent int) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "RESOURCE\tSCOPE\tOBJECTS\tAPI CALLS\n")
+	total := 0
+	for _, q := range plan {
+		objects := "-"
+		if q.Objects >= 0 {
+			objects = strconv.Itoa(q.Objects)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", q.Resource, q.Scope, objects, q.Calls)
+		total += q.Calls
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t~%d\n", total)
+	w.Flush()
+	fmt.Fprintf(out, "\nCounting took %d API calls; nothing else was queried.\n", spent)
+}
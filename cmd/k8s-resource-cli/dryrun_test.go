@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeLister serves total objects in pages, reporting remainingItemCount
+// only when withRemaining is set
+func fakeLister(total int, withRemaining bool) objectLister {
+	return func(ctx context.Context, opts metav1.ListOptions) (int, metav1.ListMeta, error) {
+		offset := 0
+		if opts.Continue != "" {
+			offset = len(opts.Continue)
+		}
+		n := min(int(opts.Limit), total-offset)
+		var meta metav1.ListMeta
+		if rest := total - offset - n; rest > 0 {
+			meta.Continue = strings.Repeat("x", offset+n)
+			if withRemaining {
+				remaining := int64(rest)
+				meta.RemainingItemCount = &remaining
+			}
+		}
+		return n, meta, nil
+	}
+}
+
+func TestCountObjects(t *testing.T) {
+	tests := []struct {
+		name          string
+		total         int
+		withRemaining bool
+		wantCalls     int
+	}{
+		{"remaining count", 1200, true, 1},
+		{"empty", 0, false, 1},
+		{"paged", 1200, false, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, calls, err := countObjects(context.Background(), fakeLister(tt.total, tt.withRemaining), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.total || calls != tt.wantCalls {
+				t.Errorf("countObjects() = %d objects in %d calls, want %d in %d", count, calls, tt.total, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPrintQueryPlan(t *testing.T) {
+	plan := []plannedQuery{
+		{Resource: "discovery", Scope: "cluster", Objects: -1, Calls: 1},
+		{Resource: "deployments", Scope: "web", Objects: 10, Calls: pages(10) + 30},
+	}
+
+	var out bytes.Buffer
+	printQueryPlan(&out, plan, 2)
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[1], "discovery") || !strings.Contains(lines[1], " - ") {
+		t.Errorf("discovery row = %q, want uncounted objects shown as -", lines[1])
+	}
+	if fields := strings.Fields(lines[3]); len(fields) != 2 || fields[1] != "~32" {
+		t.Errorf("total row = %q, want ~32", lines[3])
+	}
+	if !strings.Contains(out.String(), "Counting took 2 API calls") {
+		t.Errorf("output = %q, want the counting cost", out.String())
+	}
+}
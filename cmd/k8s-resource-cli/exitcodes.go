@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+	"net/url"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Exit statuses, so wrappers and CI can tell failures apart without parsing
+// stderr. Every command uses them.
+const (
+	exitOK         = 0
+	exitUsage      = 1 // bad flags, arguments, files or kubeconfig
+	exitConnection = 2 // the Kubernetes or Porter API couldn't be reached or refused us
+	exitPartial    = 3 // the report is missing workloads or figures, see the warnings
+	exitThreshold  = 4 // a workload reached --crit-threshold with --fail-on-threshold
+)
+
+// partialResults is set once something was left out of the report
+var partialResults bool
+
+// partialf warns that the report is missing something, which makes the run
+// end with exitPartial even when the warning is silenced by --quiet
+func partialf(format string, args ...interface{}) {
+	partialResults = true
+	warnf(format, args...)
+}
+
+// runExitStatus is the status a report run ends with once it is written. A
+// breach wins over missing data, since the data that is there already breaches.
+func runExitStatus(breached, partial bool) int {
+	switch {
+	case breached:
+		return exitThreshold
+	case partial:
+		return exitPartial
+	}
+	return exitOK
+}
+
+// apiExitCode tells a failed API call that couldn't reach the server, or was
+// refused by it, from one that asked for something that doesn't exist or
+// isn't valid
+func apiExitCode(err error) int {
+	switch {
+	case apierrors.IsNotFound(err), apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return exitUsage
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+		return exitConnection
+	}
+	var status apierrors.APIStatus
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &status) || errors.As(err, &netErr) || errors.As(err, &urlErr) || errors.Is(err, context.DeadlineExceeded) {
+		return exitConnection
+	}
+	return exitUsage
+}
+
+// parseFlags parses a command's flags, which must be a flag.ContinueOnError
+// set, exiting with exitUsage on a bad flag instead of the 2 of
+// flag.ExitOnError that here means a connection error
+func parseFlags(fs *flag.FlagSet, args []string) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
+}
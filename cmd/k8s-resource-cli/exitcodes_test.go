@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRunExitStatus(t *testing.T) {
+	tests := []struct {
+		breached bool
+		partial  bool
+		want     int
+	}{
+		{false, false, exitOK},
+		{false, true, exitPartial},
+		{true, false, exitThreshold},
+		{true, true, exitThreshold},
+	}
+	for _, tt := range tests {
+		if got := runExitStatus(tt.breached, tt.partial); got != tt.want {
+			t.Errorf("runExitStatus(%t, %t) = %d, want %d", tt.breached, tt.partial, got, tt.want)
+		}
+	}
+}
+
+func TestAPIExitCode(t *testing.T) {
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", apierrors.NewNotFound(deployments, "web"), exitUsage},
+		{"wrapped not found", fmt.Errorf("listing: %w", apierrors.NewNotFound(deployments, "web")), exitUsage},
+		{"unauthorized", apierrors.NewUnauthorized("token expired"), exitConnection},
+		{"forbidden", apierrors.NewForbidden(deployments, "web", errors.New("rbac")), exitConnection},
+		{"server error", apierrors.NewInternalError(errors.New("etcd")), exitConnection},
+		{"unreachable", &url.Error{Op: "Get", URL: "https://10.0.0.1", Err: errors.New("connection refused")}, exitConnection},
+		{"deadline", fmt.Errorf("listing: %w", context.DeadlineExceeded), exitConnection},
+		{"other", errors.New("invalid selector"), exitUsage},
+	}
+	for _, tt := range tests {
+		if got := apiExitCode(tt.err); got != tt.want {
+			t.Errorf("apiExitCode(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
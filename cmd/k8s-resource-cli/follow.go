@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// deploymentState is what --follow reports changes of: the desired replicas
+// and the requests of one pod of the template
+type deploymentState struct {
+	Replicas    int32
+	PodRequests ResourceMetrics
+}
+
+func deploymentStateOf(d appsv1.Deployment) deploymentState {
+	state := deploymentState{Replicas: 1, PodRequests: podRequests(corev1.Pod{Spec: d.Spec.Template.Spec})}
+	if d.Spec.Replicas != nil {
+		state.Replicas = *d.Spec.Replicas
+	}
+	return state
+}
+
+func (s deploymentState) String() string {
+	return fmt.Sprintf("replicas %d, pod requests %s", s.Replicas, formatRequests(s.PodRequests))
+}
+
+func formatRequests(requests ResourceMetrics) string {
+	return formatCPU(requests.CPU) + " / " + formatMemory(requests.Memory)
+}
+
+// deploymentTracker remembers the last state of every followed Deployment
+// and writes a line for each change. Status-only updates, such as pods
+// becoming ready during a rollout, don't change the state and print nothing.
+type deploymentTracker struct {
+	out    io.Writer
+	clk    clock
+	states map[string]deploymentState
+}
+
+func newDeploymentTracker(out io.Writer, clk clock) *deploymentTracker {
+	return &deploymentTracker{out: out, clk: clk, states: make(map[string]deploymentState)}
+}
+
+// Update records a Deployment that was added or modified
+func (t *deploymentTracker) Update(d appsv1.Deployment) {
+	key := d.Namespace + "/" + d.Name
+	state := deploymentStateOf(d)
+	old, known := t.states[key]
+	t.states[key] = state
+	if !known {
+		t.print("ADDED", key, state.String())
+		return
+	}
+
+	var changes []string
+	if state.Replicas != old.Replicas {
+		changes = append(changes, fmt.Sprintf("replicas %d -> %d", old.Replicas, state.Replicas))
+	}
+	if state.PodRequests != old.PodRequests {
+		changes = append(changes, fmt.Sprintf("pod requests %s -> %s", formatRequests(old.PodRequests), formatRequests(state.PodRequests)))
+	}
+	if len(changes) > 0 {
+		t.print("MODIFIED", key, strings.Join(changes, ", "))
+	}
+}
+
+// Delete records a Deployment that was deleted
+func (t *deploymentTracker) Delete(key string) {
+	state, known := t.states[key]
+	if !known {
+		return
+	}
+	delete(t.states, key)
+	t.print("DELETED", key, state.String())
+}
+
+// Sync replaces the known Deployments with a fresh list, reporting what
+// changed since the last one unless this is the first
+func (t *deploymentTracker) Sync(items []appsv1.Deployment, initial bool) {
+	if initial {
+		for _, d := range items {
+			t.states[d.Namespace+"/"+d.Name] = deploymentStateOf(d)
+		}
+		return
+	}
+	listed := make(map[string]bool, len(items))
+	for _, d := range items {
+		listed[d.Namespace+"/"+d.Name] = true
+		t.Update(d)
+	}
+	for key := range t.states {
+		if !listed[key] {
+			t.Delete(key)
+		}
+	}
+}
+
+func (t *deploymentTracker) print(event, key, detail string) {
+	fmt.Fprintf(t.out, "%s  %-8s  %s  %s\n", t.clk.Now().UTC().Format(time.RFC3339), event, key, detail)
+}
+
+// followDeployments prints a line whenever a Deployment's replicas or pod
+// requests change, until the context is done. It lists the Deployments
+// once, then watches from that list's resource version, resuming after the
+// API server closes the watch and listing again when the version has
+// expired.
+func followDeployments(ctx context.Context, clientset *kubernetes.Clientset, namespace, name, labelSelector string, out io.Writer, clk clock) error {
+	listOptions := metav1.ListOptions{LabelSelector: labelSelector}
+	if name != "" {
+		listOptions.FieldSelector = "metadata.name=" + name
+	}
+	tracker := newDeploymentTracker(out, clk)
+
+	for initial := true; ; initial = false {
+		list, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+		if err != nil {
+			return fmt.Errorf("error listing deployments: %w", err)
+		}
+		tracker.Sync(list.Items, initial)
+
+		resourceVersion := list.ResourceVersion
+	watching:
+		for {
+			watchOptions := listOptions
+			watchOptions.ResourceVersion = resourceVersion
+			watchOptions.AllowWatchBookmarks = true
+			watcher, err := clientset.AppsV1().Deployments(namespace).Watch(ctx, watchOptions)
+			if err != nil {
+				return fmt.Errorf("error watching deployments: %w", err)
+			}
+			for event := range watcher.ResultChan() {
+				if event.Type == watch.Error {
+					// Usually 410 Gone: the version is too old to resume from
+					watcher.Stop()
+					break watching
+				}
+				d, ok := event.Object.(*appsv1.Deployment)
+				if !ok {
+					continue
+				}
+				resourceVersion = d.ResourceVersion
+				switch event.Type {
+				case watch.Added, watch.Modified:
+					tracker.Update(*d)
+				case watch.Deleted:
+					tracker.Delete(d.Namespace + "/" + d.Name)
+				}
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func followedDeployment(name string, replicas int32, cpu string) appsv1.Deployment {
+	return appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				}},
+			}}}},
+		},
+	}
+}
+
+func TestDeploymentTracker(t *testing.T) {
+	var buf bytes.Buffer
+	tracker := newDeploymentTracker(&buf, fixedClock(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)))
+
+	tracker.Sync([]appsv1.Deployment{followedDeployment("web", 2, "100m"), followedDeployment("worker", 1, "500m")}, true)
+	if buf.Len() != 0 {
+		t.Fatalf("initial sync printed %q, want nothing", buf.String())
+	}
+
+	scaled := followedDeployment("web", 4, "100m")
+	tracker.Update(scaled)
+	// A status-only update changes nothing that is followed
+	scaled.Status.ReadyReplicas = 4
+	tracker.Update(scaled)
+	tracker.Update(followedDeployment("web", 4, "250m"))
+	tracker.Update(followedDeployment("api", 3, "200m"))
+	tracker.Delete("shop/api")
+	// worker disappeared while the watch was down
+	tracker.Sync([]appsv1.Deployment{followedDeployment("web", 4, "250m")}, false)
+
+	want := "2026-10-16T09:00:00Z  MODIFIED  shop/web  replicas 2 -> 4\n" +
+		"2026-10-16T09:00:00Z  MODIFIED  shop/web  pod requests 100m / 128.00 MB -> 250m / 128.00 MB\n" +
+		"2026-10-16T09:00:00Z  ADDED     shop/api  replicas 3, pod requests 200m / 128.00 MB\n" +
+		"2026-10-16T09:00:00Z  DELETED   shop/api  replicas 3, pod requests 200m / 128.00 MB\n" +
+		"2026-10-16T09:00:00Z  DELETED   shop/worker  replicas 1, pod requests 500m / 128.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("follow output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestDeploymentStateOfDefaultsReplicas(t *testing.T) {
+	d := followedDeployment("web", 0, "100m")
+	d.Spec.Replicas = nil
+	if got := deploymentStateOf(d).Replicas; got != 1 {
+		t.Errorf("replicas = %d, want 1 when unset", got)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const hoursPerDay = 24
+
+// linearTrend is a least-squares fit of value = Intercept + Slope*day, with
+// day counted from the first snapshot in the history
+type linearTrend struct {
+	Intercept float64
+	Slope     float64
+}
+
+func (t linearTrend) At(day float64) float64 {
+	return t.Intercept + t.Slope*day
+}
+
+// Crossing returns the day the trend reaches limit, or false if it never
+// does because the trend is flat or shrinking
+func (t linearTrend) Crossing(limit float64) (float64, bool) {
+	if t.Slope <= 0 {
+		return 0, false
+	}
+	return (limit - t.Intercept) / t.Slope, true
+}
+
+func fitLinear(days, values []float64) linearTrend {
+	n := float64(len(days))
+	if n == 0 {
+		return linearTrend{}
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range days {
+		sumX += days[i]
+		sumY += values[i]
+		sumXY += days[i] * values[i]
+		sumXX += days[i] * days[i]
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		// All samples on the same day, no slope to fit
+		return linearTrend{Intercept: sumY / n}
+	}
+
+	slope := (n*sumXY - sumX*sumY) / denominator
+	return linearTrend{Intercept: (sumY - slope*sumX) / n, Slope: slope}
+}
+
+// forecastSeries is the request history of one namespace, or of the whole
+// cluster, along with the capacity it is measured against (zero if unknown)
+type forecastSeries struct {
+	Scope  string
+	Days   []float64
+	CPU    []float64
+	Memory []float64
+	Last   ResourceMetrics
+	Limit  ResourceMetrics
+}
+
+func (s *forecastSeries) add(day float64, requests ResourceMetrics) {
+	s.Days = append(s.Days, day)
+	s.CPU = append(s.CPU, float64(requests.CPU))
+	s.Memory = append(s.Memory, float64(requests.Memory))
+	s.Last = requests
+}
+
+// buildForecastSeries turns the snapshots into one series per namespace plus
+// a cluster series holding the per-snapshot sum
+func buildForecastSeries(snapshots []historySnapshot, start time.Time) (map[string]*forecastSeries, *forecastSeries) {
+	namespaces := make(map[string]*forecastSeries)
+	cluster := &forecastSeries{Scope: "CLUSTER"}
+
+	for _, snapshot := range snapshots {
+		day := snapshot.Time.Sub(start).Hours() / hoursPerDay
+		var total ResourceMetrics
+		for ns, requests := range snapshot.Namespaces {
+			series, ok := namespaces[ns]
+			if !ok {
+				series = &forecastSeries{Scope: ns}
+				namespaces[ns] = series
+			}
+			series.add(day, requests)
+			total.add(requests)
+		}
+		cluster.add(day, total)
+	}
+
+	return namespaces, cluster
+}
+
+// runForecastCommand implements the "forecast" subcommand, projecting the
+// recorded request history forward and reporting when capacity runs out
+func runForecastCommand(args []string) {
+	var historyFile string
+	var horizonDays int
+	var namespace string
+	var kubeconfig string
+	var kubeContext string
+	var noCluster bool
+	var nowValue string
+
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	fs.StringVar(&historyFile, "history-file", "", "History file written by --history-file runs (required)")
+	fs.IntVar(&horizonDays, "horizon", 90, "How many days ahead to project")
+	fs.StringVar(&namespace, "namespace", "", "Only forecast this namespace")
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	fs.BoolVar(&noCluster, "no-cluster", false, "Don't look up node allocatable and ResourceQuotas, only project the trend")
+	fs.StringVar(&nowValue, "now", "", "Forecast as of this RFC 3339 time instead of the current time, for reproducible output")
+	parseFlags(fs, args)
+
+	clk, err := newClock(nowValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	if historyFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: --history-file is required\n")
+		os.Exit(exitUsage)
+	}
+
+	snapshots, err := readHistory(historyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if len(snapshots) < 2 {
+		fmt.Fprintf(os.Stderr, "Error: need at least 2 history entries to forecast, found %d\n", len(snapshots))
+		os.Exit(exitUsage)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+	start := snapshots[0].Time
+	namespaces, cluster := buildForecastSeries(snapshots, start)
+
+	if !noCluster {
+		config, err := buildRESTConfig(kubeconfig, kubeContext)
+		if err == nil {
+			var clientset *kubernetes.Clientset
+			clientset, err = kubernetes.NewForConfig(config)
+			if err == nil {
+				err = loadForecastLimits(context.Background(), clientset, namespaces, cluster)
+			}
+		}
+		if err != nil {
+			warnf("Error getting cluster capacity, showing trends only: %v", err)
+		}
+	}
+
+	var series []*forecastSeries
+	for _, ns := range sortedKeys(namespaces) {
+		if namespace == "" || ns == namespace {
+			series = append(series, namespaces[ns])
+		}
+	}
+	if namespace == "" {
+		series = append(series, cluster)
+	}
+
+	now := clk.Now()
+	printForecast(os.Stdout, series, start, now.Sub(start).Hours()/hoursPerDay, horizonDays)
+}
+
+// loadForecastLimits fills in node allocatable as the cluster limit and the
+// tightest ResourceQuota requests limit of each namespace
+func loadForecastLimits(ctx context.Context, clientset *kubernetes.Clientset, namespaces map[string]*forecastSeries, cluster *forecastSeries) error {
+	allocatable, err := loadNodeAllocatable(ctx, clientset)
+	if err != nil {
+		return err
+	}
+	cluster.Limit = allocatable
+
+	quotas, err := loadQuotaLimits(ctx, clientset, "")
+	if err != nil {
+		return err
+	}
+	for ns, limit := range quotas {
+		if series, ok := namespaces[ns]; ok {
+			series.Limit = limit
+		}
+	}
+
+	return nil
+}
+
+// exhaustionLabel reports the first resource whose trend crosses its limit
+// before the horizon: "exceeded" if it already has, a date if it will, "-" otherwise
+func exhaustionLabel(cpu, memory linearTrend, limit ResourceMetrics, start time.Time, today, horizon float64) string {
+	earliest := horizon
+	resource := ""
+	for _, check := range []struct {
+		name  string
+		trend linearTrend
+		limit int64
+	}{
+		{"cpu", cpu, limit.CPU},
+		{"memory", memory, limit.Memory},
+	} {
+		if check.limit == 0 {
+			continue
+		}
+		if check.trend.At(today) >= float64(check.limit) {
+			return "exceeded (" + check.name + ")"
+		}
+		if day, ok := check.trend.Crossing(float64(check.limit)); ok && day <= earliest {
+			earliest = day
+			resource = check.name
+		}
+	}
+
+	if resource == "" {
+		return "-"
+	}
+	date := start.Add(time.Duration(earliest * hoursPerDay * float64(time.Hour)))
+	return fmt.Sprintf("%s (%s)", date.Format("2006-01-02"), resource)
+}
+
+func printForecast(out io.Writer, series []*forecastSeries, start time.Time, today float64, horizonDays int) {
+	horizon := today + float64(horizonDays)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "NAMESPACE\tSAMPLES\tCPU\tCPU +%dd\tMEMORY\tMEMORY +%dd\tEXHAUSTED\n", horizonDays, horizonDays)
+	for _, s := range series {
+		cpu := fitLinear(s.Days, s.CPU)
+		memory := fitLinear(s.Days, s.Memory)
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			s.Scope,
+			len(s.Days),
+			formatCPU(s.Last.CPU),
+			formatCPU(max(int64(cpu.At(horizon)), 0)),
+			formatMemory(s.Last.Memory),
+			formatMemory(max(int64(memory.At(horizon)), 0)),
+			exhaustionLabel(cpu, memory, s.Limit, start, today, horizon))
+	}
+	w.Flush()
+}
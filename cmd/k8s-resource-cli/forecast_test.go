@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFitLinear(t *testing.T) {
+	trend := fitLinear([]float64{0, 1, 2, 3}, []float64{100, 110, 120, 130})
+	if math.Abs(trend.Slope-10) > 1e-9 || math.Abs(trend.Intercept-100) > 1e-9 {
+		t.Errorf("fitLinear() = %+v, want slope 10, intercept 100", trend)
+	}
+
+	day, ok := trend.Crossing(200)
+	if !ok || math.Abs(day-10) > 1e-9 {
+		t.Errorf("Crossing(200) = %v, %v, want 10, true", day, ok)
+	}
+
+	flat := fitLinear([]float64{5, 5}, []float64{100, 300})
+	if flat.Slope != 0 || flat.Intercept != 200 {
+		t.Errorf("fitLinear() on one day = %+v, want flat at 200", flat)
+	}
+	if _, ok := flat.Crossing(500); ok {
+		t.Error("flat trend should never cross a higher limit")
+	}
+}
+
+func TestExhaustionLabel(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	growing := linearTrend{Intercept: 1000, Slope: 100}
+	flat := linearTrend{Intercept: 1000}
+
+	tests := []struct {
+		name   string
+		cpu    linearTrend
+		memory linearTrend
+		limit  ResourceMetrics
+		want   string
+	}{
+		{"no limits", growing, growing, ResourceMetrics{}, "-"},
+		{"cpu crosses within horizon", growing, flat, ResourceMetrics{CPU: 3000, Memory: 5000}, "2026-01-21 (cpu)"},
+		{"memory crosses first", growing, linearTrend{Intercept: 1000, Slope: 200}, ResourceMetrics{CPU: 3000, Memory: 3000}, "2026-01-11 (memory)"},
+		{"beyond horizon", growing, flat, ResourceMetrics{CPU: 100000}, "-"},
+		{"already exceeded", flat, flat, ResourceMetrics{CPU: 500}, "exceeded (cpu)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exhaustionLabel(tt.cpu, tt.memory, tt.limit, start, 0, 90); got != tt.want {
+				t.Errorf("exhaustionLabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildForecastSeries(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []historySnapshot{
+		{Time: start, Namespaces: map[string]ResourceMetrics{"prod": {CPU: 1000}, "staging": {CPU: 200}}},
+		{Time: start.Add(48 * time.Hour), Namespaces: map[string]ResourceMetrics{"prod": {CPU: 1500}}},
+	}
+
+	namespaces, cluster := buildForecastSeries(snapshots, start)
+
+	if len(namespaces["prod"].Days) != 2 || namespaces["prod"].Days[1] != 2 {
+		t.Errorf("prod days = %v, want [0 2]", namespaces["prod"].Days)
+	}
+	if len(namespaces["staging"].Days) != 1 {
+		t.Errorf("staging samples = %d, want 1", len(namespaces["staging"].Days))
+	}
+	if cluster.CPU[0] != 1200 || cluster.Last.CPU != 1500 {
+		t.Errorf("cluster cpu = %v (last %d), want [1200 1500]", cluster.CPU, cluster.Last.CPU)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// grafanaDashboardUID is the UID of the generated dashboard, fixed so that
+// importing a newer version replaces the old one instead of adding a copy
+const grafanaDashboardUID = "k8s-resource-cli"
+
+// grafanaPanel is a dashboard panel, with the fields the generated panels use
+type grafanaPanel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Datasource  grafanaDatasource      `json:"datasource"`
+	GridPos     grafanaGridPos         `json:"gridPos"`
+	FieldConfig grafanaFieldConfig     `json:"fieldConfig"`
+	Options     map[string]interface{} `json:"options,omitempty"`
+	Targets     []grafanaTarget        `json:"targets"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults  map[string]interface{} `json:"defaults"`
+	Overrides []interface{}          `json:"overrides"`
+}
+
+type grafanaTarget struct {
+	RefID        string            `json:"refId"`
+	Datasource   grafanaDatasource `json:"datasource"`
+	Expr         string            `json:"expr"`
+	LegendFormat string            `json:"legendFormat"`
+}
+
+// grafanaQuery is a PromQL query of a panel and the legend of its series
+type grafanaQuery struct {
+	expr   string
+	legend string
+}
+
+// grafanaNamespaceFilter limits a metric to the namespaces picked in the
+// dashboard's namespace variable
+const grafanaNamespaceFilter = `{namespace=~"$namespace"}`
+
+// grafanaSum sums a metric of the Prometheus output by the given labels
+func grafanaSum(metric, by string) string {
+	return fmt.Sprintf("sum by (%s) (%s%s)", by, metric, grafanaNamespaceFilter)
+}
+
+// grafanaUtilization is usage over requests per workload, leaving out
+// workloads without requests instead of dividing by zero
+func grafanaUtilization(usage, requests string) string {
+	return fmt.Sprintf("%s / (%s > 0)", grafanaSum(usage, "namespace, workload"), grafanaSum(requests, "namespace, workload"))
+}
+
+// grafanaDashboard builds the dashboard for the metrics of --format prom:
+// requests against usage per namespace, headroom to max replicas, and
+// heatmaps of how much of its requests each workload uses. With datasource
+// empty the panels query the datasource picked in the dashboard.
+func grafanaDashboard(title, datasource string) map[string]interface{} {
+	ds := grafanaDatasource{Type: "prometheus", UID: "${datasource}"}
+	if datasource != "" {
+		ds.UID = datasource
+	}
+
+	var panels []grafanaPanel
+	add := func(typ, title, description, unit string, width int, options map[string]interface{}, queries ...grafanaQuery) {
+		// Panels fill rows of 24 columns, left to right
+		x, y := 0, 0
+		if len(panels) > 0 {
+			last := panels[len(panels)-1].GridPos
+			x, y = last.X+last.W, last.Y
+			if x+width > 24 {
+				x, y = 0, last.Y+last.H
+			}
+		}
+		panel := grafanaPanel{
+			ID: len(panels) + 1, Type: typ, Title: title, Description: description, Datasource: ds,
+			GridPos:     grafanaGridPos{H: 8, W: width, X: x, Y: y},
+			FieldConfig: grafanaFieldConfig{Defaults: map[string]interface{}{"unit": unit}, Overrides: []interface{}{}},
+			Options:     options,
+		}
+		for i, query := range queries {
+			panel.Targets = append(panel.Targets, grafanaTarget{
+				RefID: string(rune('A' + i)), Datasource: ds, Expr: query.expr, LegendFormat: query.legend,
+			})
+		}
+		panels = append(panels, panel)
+	}
+
+	add("timeseries", "CPU requests vs usage by namespace", "Cores requested by the current replicas against cores used.", "short", 12, nil,
+		grafanaQuery{grafanaSum("k8s_resource_cli_requests_cpu_cores", "namespace"), "{{namespace}} requests"},
+		grafanaQuery{grafanaSum("k8s_resource_cli_usage_cpu_cores", "namespace"), "{{namespace}} usage"})
+	add("timeseries", "Memory requests vs usage by namespace", "Memory requested by the current replicas against memory used.", "bytes", 12, nil,
+		grafanaQuery{grafanaSum("k8s_resource_cli_requests_memory_bytes", "namespace"), "{{namespace}} requests"},
+		grafanaQuery{grafanaSum("k8s_resource_cli_usage_memory_bytes", "namespace"), "{{namespace}} usage"})
+
+	heatmap := map[string]interface{}{
+		"calculate": true,
+		"yAxis":     map[string]interface{}{"unit": "percentunit"},
+		"color":     map[string]interface{}{"scheme": "Oranges", "mode": "scheme"},
+	}
+	add("heatmap", "CPU utilization of requests", "How many workloads use what share of their CPU requests; workloads without requests are left out.", "percentunit", 12, heatmap,
+		grafanaQuery{grafanaUtilization("k8s_resource_cli_usage_cpu_cores", "k8s_resource_cli_requests_cpu_cores"), "{{namespace}}/{{workload}}"})
+	add("heatmap", "Memory utilization of requests", "How many workloads use what share of their memory requests; workloads without requests are left out.", "percentunit", 12, heatmap,
+		grafanaQuery{grafanaUtilization("k8s_resource_cli_usage_memory_bytes", "k8s_resource_cli_requests_memory_bytes"), "{{namespace}}/{{workload}}"})
+
+	add("timeseries", "CPU headroom to max replicas by namespace", "Cores the namespace would request on top of today's once its autoscalers reach max replicas.", "short", 12, nil,
+		grafanaQuery{grafanaSum("k8s_resource_cli_max_requests_cpu_cores", "namespace") + " - " + grafanaSum("k8s_resource_cli_requests_cpu_cores", "namespace"), "{{namespace}}"})
+	add("timeseries", "Memory headroom to max replicas by namespace", "Memory the namespace would request on top of today's once its autoscalers reach max replicas.", "bytes", 12, nil,
+		grafanaQuery{grafanaSum("k8s_resource_cli_max_requests_memory_bytes", "namespace") + " - " + grafanaSum("k8s_resource_cli_requests_memory_bytes", "namespace"), "{{namespace}}"})
+
+	var variables []interface{}
+	if datasource == "" {
+		variables = append(variables, map[string]interface{}{
+			"name": "datasource", "label": "Data source", "type": "datasource", "query": "prometheus",
+		})
+	}
+	variables = append(variables, map[string]interface{}{
+		"name": "namespace", "label": "Namespace", "type": "query", "datasource": ds,
+		"query":      "label_values(k8s_resource_cli_requests_cpu_cores, namespace)",
+		"definition": "label_values(k8s_resource_cli_requests_cpu_cores, namespace)",
+		"refresh":    2, "multi": true, "includeAll": true, "sort": 1,
+		"current": map[string]interface{}{"text": "All", "value": "$__all"},
+	})
+
+	return map[string]interface{}{
+		"uid":           grafanaDashboardUID,
+		"title":         title,
+		"tags":          []string{"kubernetes", "k8s-resource-cli"},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"editable":      true,
+		"refresh":       "5m",
+		"time":          map[string]string{"from": "now-7d", "to": "now"},
+		"templating":    map[string]interface{}{"list": variables},
+		"panels":        panels,
+	}
+}
+
+// runGrafanaCommand implements the "grafana dashboard" subcommand: it prints
+// a dashboard for the Prometheus output, ready to import into Grafana
+func runGrafanaCommand(args []string) {
+	const usage = "Usage: k8s-resource-cli grafana dashboard [--title title] [--datasource uid]\n"
+	if len(args) == 0 || args[0] != "dashboard" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	var title string
+	var datasource string
+	fs := flag.NewFlagSet("grafana dashboard", flag.ContinueOnError)
+	fs.StringVar(&title, "title", "Kubernetes Resources", "Dashboard title")
+	fs.StringVar(&datasource, "datasource", "", "UID of the Prometheus datasource to query; by default the dashboard has a datasource picker")
+	parseFlags(fs, args[1:])
+
+	if fs.NArg() > 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	// PromQL comparisons stay readable instead of becoming \u003e
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(grafanaDashboard(title, datasource)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestGrafanaDashboardMetrics(t *testing.T) {
+	known := make(map[string]bool)
+	for _, metric := range promMetrics {
+		known[metric.name] = true
+	}
+	dashboard := grafanaDashboard("Kubernetes Resources", "")
+	panels := dashboard["panels"].([]grafanaPanel)
+	if len(panels) == 0 {
+		t.Fatal("dashboard has no panels")
+	}
+	metricName := regexp.MustCompile(`k8s_resource_cli_\w+`)
+	for _, panel := range panels {
+		if panel.Datasource.UID != "${datasource}" {
+			t.Errorf("panel %q datasource = %q, want ${datasource}", panel.Title, panel.Datasource.UID)
+		}
+		if len(panel.Targets) == 0 {
+			t.Errorf("panel %q has no queries", panel.Title)
+		}
+		for _, target := range panel.Targets {
+			for _, name := range metricName.FindAllString(target.Expr, -1) {
+				if !known[name] {
+					t.Errorf("panel %q queries %s, which --format prom doesn't write", panel.Title, name)
+				}
+			}
+		}
+	}
+}
+
+func TestGrafanaDashboardLayout(t *testing.T) {
+	panels := grafanaDashboard("Kubernetes Resources", "")["panels"].([]grafanaPanel)
+	want := []grafanaGridPos{
+		{H: 8, W: 12, X: 0, Y: 0}, {H: 8, W: 12, X: 12, Y: 0},
+		{H: 8, W: 12, X: 0, Y: 8}, {H: 8, W: 12, X: 12, Y: 8},
+		{H: 8, W: 12, X: 0, Y: 16}, {H: 8, W: 12, X: 12, Y: 16},
+	}
+	if len(panels) != len(want) {
+		t.Fatalf("got %d panels, want %d", len(panels), len(want))
+	}
+	for i, panel := range panels {
+		if panel.GridPos != want[i] {
+			t.Errorf("panel %q gridPos = %+v, want %+v", panel.Title, panel.GridPos, want[i])
+		}
+		if panel.ID != i+1 {
+			t.Errorf("panel %q id = %d, want %d", panel.Title, panel.ID, i+1)
+		}
+	}
+}
+
+func TestGrafanaDashboardDatasource(t *testing.T) {
+	dashboard := grafanaDashboard("Capacity", "prom-uid")
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Title      string `json:"title"`
+		Templating struct {
+			List []struct {
+				Name string `json:"name"`
+			} `json:"list"`
+		} `json:"templating"`
+		Panels []struct {
+			Datasource grafanaDatasource `json:"datasource"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Title != "Capacity" {
+		t.Errorf("title = %q, want Capacity", decoded.Title)
+	}
+	for _, variable := range decoded.Templating.List {
+		if variable.Name == "datasource" {
+			t.Error("datasource variable present with a fixed --datasource")
+		}
+	}
+	for _, panel := range decoded.Panels {
+		if panel.Datasource.UID != "prom-uid" {
+			t.Errorf("panel datasource = %q, want prom-uid", panel.Datasource.UID)
+		}
+	}
+}
+
+func TestGrafanaUtilization(t *testing.T) {
+	got := grafanaUtilization("k8s_resource_cli_usage_cpu_cores", "k8s_resource_cli_requests_cpu_cores")
+	want := `sum by (namespace, workload) (k8s_resource_cli_usage_cpu_cores{namespace=~"$namespace"}) / ` +
+		`(sum by (namespace, workload) (k8s_resource_cli_requests_cpu_cores{namespace=~"$namespace"}) > 0)`
+	if got != want {
+		t.Errorf("grafanaUtilization() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// dependsOnAnnotation lists the workloads a Deployment or CronJob calls, as
+// comma-separated names in its own namespace or namespace/name elsewhere
+const dependsOnAnnotation = "graph.k8s-resource-cli/depends-on"
+
+// Node widths in inches of --format dot, for the smallest and the largest
+// CPU figure of the report
+const (
+	dotMinWidth = 0.75
+	dotMaxWidth = 3.0
+)
+
+// dependsOn reads the depends-on annotation into namespace/name keys
+func dependsOn(annotations map[string]string, namespace string) []string {
+	var keys []string
+	for _, name := range strings.Split(annotations[dependsOnAnnotation], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !strings.Contains(name, "/") {
+			name = namespace + "/" + name
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// dotPrinter writes the workloads as a Graphviz graph for architecture
+// reviews: a cluster per namespace, a box per workload with its width
+// proportional to its CPU figure, and an edge per depends-on annotation.
+// Dependencies outside the report are drawn dashed.
+type dotPrinter struct {
+	out         io.Writer
+	outputType  string
+	deployments []DeploymentMetrics
+}
+
+func (p *dotPrinter) Add(dm DeploymentMetrics) {
+	p.deployments = append(p.deployments, dm)
+}
+
+// dotCPU is the CPU figure of the output type a node is sized by
+func dotCPU(outputType string, dm DeploymentMetrics) int64 {
+	switch outputType {
+	case OutputTypeUsage:
+		return dm.Usage.CPU
+	case OutputTypeMaxRequests:
+		return dm.EffectiveMaxRequests().CPU
+	}
+	return dm.Requests.CPU
+}
+
+func (p *dotPrinter) Flush() {
+	var largest int64
+	byNamespace := make(map[string][]DeploymentMetrics)
+	namespaceTotals := make(map[string]*jsonTotals)
+	reported := make(map[string]bool)
+	for _, dm := range p.deployments {
+		largest = max(largest, dotCPU(p.outputType, dm))
+		byNamespace[dm.Namespace] = append(byNamespace[dm.Namespace], dm)
+		addGroupTotals(namespaceTotals, dm.Namespace, dm)
+		reported[dm.Namespace+"/"+dm.Name] = true
+	}
+
+	fmt.Fprintln(p.out, "digraph workloads {")
+	fmt.Fprintln(p.out, `  graph [rankdir=LR, fontname="Helvetica"];`)
+	fmt.Fprintln(p.out, `  node [shape=box, style="rounded,filled", fillcolor="#dae8fc", fontname="Helvetica"];`)
+	for i, namespace := range sortedKeys(byNamespace) {
+		cpu, memory := formatForOutput(p.outputType, namespaceTotals[namespace].Usage, namespaceTotals[namespace].Requests, namespaceTotals[namespace].MaxRequests)
+		fmt.Fprintf(p.out, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(p.out, "    label=%s;\n", dotQuote(fmt.Sprintf("%s (%s, %s)", namespace, cpu, memory)))
+		for _, dm := range byNamespace[namespace] {
+			width := dotMinWidth
+			if largest > 0 {
+				width += (dotMaxWidth - dotMinWidth) * float64(dotCPU(p.outputType, dm)) / float64(largest)
+			}
+			cpu, memory := formatForOutput(p.outputType, dm.Usage, dm.Requests, dm.EffectiveMaxRequests())
+			fmt.Fprintf(p.out, "    %s [label=%s, width=%.2f];\n", dotQuote(dm.Namespace+"/"+dm.Name),
+				dotQuote(fmt.Sprintf("%s\n%s, %s", displayName(dm), cpu, memory)), width)
+		}
+		fmt.Fprintln(p.out, "  }")
+	}
+
+	var edges []string
+	external := make(map[string]bool)
+	for _, dm := range p.deployments {
+		for _, target := range dm.DependsOn {
+			edges = append(edges, fmt.Sprintf("  %s -> %s;\n", dotQuote(dm.Namespace+"/"+dm.Name), dotQuote(target)))
+			if !reported[target] {
+				external[target] = true
+			}
+		}
+	}
+	for _, target := range sortedKeys(external) {
+		fmt.Fprintf(p.out, "  %s [style=\"rounded,dashed\", fillcolor=none];\n", dotQuote(target))
+	}
+	sort.Strings(edges)
+	for _, edge := range edges {
+		fmt.Fprint(p.out, edge)
+	}
+	fmt.Fprintln(p.out, "}")
+}
+
+// dotQuote quotes a DOT ID, escaping double quotes and turning newlines
+// into centered line breaks
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
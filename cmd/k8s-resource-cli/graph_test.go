@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDependsOn(t *testing.T) {
+	annotations := map[string]string{dependsOnAnnotation: "api, payments/gateway,,"}
+	want := []string{"shop/api", "payments/gateway"}
+	if got := dependsOn(annotations, "shop"); !reflect.DeepEqual(got, want) {
+		t.Errorf("dependsOn() = %v, want %v", got, want)
+	}
+	if got := dependsOn(nil, "shop"); got != nil {
+		t.Errorf("dependsOn() without the annotation = %v, want nil", got)
+	}
+}
+
+func TestDotPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatDOT, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", Requests: ResourceMetrics{CPU: 1000, Memory: 1048576},
+		DependsOn: []string{"shop/api", "payments/gateway"}})
+	p.Add(DeploymentMetrics{Name: "api", Namespace: "shop", Type: "Deployment", Requests: ResourceMetrics{CPU: 500, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "report", Namespace: "ops", Type: "CronJob"})
+	p.Flush()
+
+	want := `digraph workloads {
+  graph [rankdir=LR, fontname="Helvetica"];
+  node [shape=box, style="rounded,filled", fillcolor="#dae8fc", fontname="Helvetica"];
+  subgraph cluster_0 {
+    label="ops (0m, 0 B)";
+    "ops/report" [label="report\n0m, 0 B", width=0.75];
+  }
+  subgraph cluster_1 {
+    label="shop (1.50 cores, 2.00 MB)";
+    "shop/web" [label="web\n1.00 cores, 1.00 MB", width=3.00];
+    "shop/api" [label="api\n500m, 1.00 MB", width=1.88];
+  }
+  "payments/gateway" [style="rounded,dashed", fillcolor=none];
+  "shop/web" -> "payments/gateway";
+  "shop/web" -> "shop/api";
+}
+`
+	if buf.String() != want {
+		t.Errorf("dot output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// unallocatedGroup collects workloads carrying none of the grouping labels
+const unallocatedGroup = "UNALLOCATED"
+
+// parseLabelChain splits a comma-separated --group-by-labels value, dropping blanks
+func parseLabelChain(value string) []string {
+	var chain []string
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			chain = append(chain, label)
+		}
+	}
+	return chain
+}
+
+// allocationKey returns label=value for the first label in the chain the
+// workload carries with a non-empty value, or unallocatedGroup if none match
+func allocationKey(labels map[string]string, chain []string) string {
+	for _, label := range chain {
+		if value := labels[label]; value != "" {
+			return label + "=" + value
+		}
+	}
+	return unallocatedGroup
+}
+
+type allocationGroup struct {
+	Key         string          `json:"key"`
+	Workloads   int             `json:"workloads"`
+	Usage       ResourceMetrics `json:"usage"`
+	Requests    ResourceMetrics `json:"requests"`
+	MaxRequests ResourceMetrics `json:"max_requests"`
+}
+
+// groupPrinter rolls workloads up into one row per allocation key instead of
+// one row per workload. Only the running sums per group are kept.
+type groupPrinter struct {
+	out        io.Writer
+	outputType string
+	totalOnly  bool
+	format     string
+	chain      []string
+	meta       *runMetadata
+	groups     map[string]*allocationGroup
+}
+
+func newGroupPrinter(out io.Writer, outputType string, totalOnly bool, format string, chain []string, meta *runMetadata) *groupPrinter {
+	return &groupPrinter{
+		meta:       meta,
+		out:        out,
+		outputType: outputType,
+		totalOnly:  totalOnly,
+		format:     format,
+		chain:      chain,
+		groups:     make(map[string]*allocationGroup),
+	}
+}
+
+func (p *groupPrinter) Add(dm DeploymentMetrics) {
+	key := allocationKey(dm.Labels, p.chain)
+	group, ok := p.groups[key]
+	if !ok {
+		group = &allocationGroup{Key: key}
+		p.groups[key] = group
+	}
+	group.Workloads++
+	group.Usage.add(dm.Usage)
+	group.Requests.add(dm.Requests)
+	group.MaxRequests.add(dm.EffectiveMaxRequests())
+}
+
+// sortedGroups orders groups by key with the unallocated bucket last
+func (p *groupPrinter) sortedGroups() []*allocationGroup {
+	groups := make([]*allocationGroup, 0, len(p.groups))
+	for _, group := range p.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if (groups[i].Key == unallocatedGroup) != (groups[j].Key == unallocatedGroup) {
+			return groups[j].Key == unallocatedGroup
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+func (p *groupPrinter) Flush() {
+	groups := p.sortedGroups()
+
+	total := allocationGroup{Key: "TOTAL"}
+	for _, group := range groups {
+		total.Workloads += group.Workloads
+		total.Usage.add(group.Usage)
+		total.Requests.add(group.Requests)
+		total.MaxRequests.add(group.MaxRequests)
+	}
+
+	if p.format == FormatJSON {
+		output := struct {
+			Metadata *runMetadata       `json:"metadata,omitempty"`
+			Groups   []*allocationGroup `json:"groups"`
+			Total    allocationGroup    `json:"total"`
+		}{Metadata: p.meta, Groups: groups, Total: total}
+		if p.totalOnly {
+			output.Groups = []*allocationGroup{}
+		}
+		if err := json.NewEncoder(p.out).Encode(output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON output: %v\n", err)
+		}
+		return
+	}
+
+	if len(groups) == 0 {
+		fmt.Fprintln(p.out, "No deployments found")
+		return
+	}
+
+	table := resultTable{headers: []string{"GROUP", "WORKLOADS", "CPU", "MEMORY"}}
+	for _, group := range groups {
+		cpu, memory := formatForOutput(p.outputType, group.Usage, group.Requests, group.MaxRequests)
+		table.rows = append(table.rows, []string{group.Key, fmt.Sprintf("%d", group.Workloads), cpu, memory})
+	}
+	cpu, memory := formatForOutput(p.outputType, total.Usage, total.Requests, total.MaxRequests)
+	table.total = []string{"TOTAL", fmt.Sprintf("%d", total.Workloads), cpu, memory}
+
+	if p.format == FormatMarkdown {
+		printMarkdownResults(p.out, table, p.totalOnly)
+	} else {
+		printTableResults(p.out, table, p.totalOnly)
+	}
+}
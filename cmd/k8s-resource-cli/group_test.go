@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelChain(t *testing.T) {
+	got := parseLabelChain(" team, owner,,app.kubernetes.io/part-of ")
+	want := []string{"team", "owner", "app.kubernetes.io/part-of"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLabelChain() = %v, want %v", got, want)
+	}
+	if got := parseLabelChain(""); got != nil {
+		t.Errorf("parseLabelChain(\"\") = %v, want nil", got)
+	}
+}
+
+func TestAllocationKey(t *testing.T) {
+	chain := []string{"team", "owner", "app.kubernetes.io/part-of"}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{"first label", map[string]string{"team": "payments", "owner": "alice"}, "team=payments"},
+		{"falls back", map[string]string{"owner": "alice"}, "owner=alice"},
+		{"skips empty value", map[string]string{"team": "", "app.kubernetes.io/part-of": "shop"}, "app.kubernetes.io/part-of=shop"},
+		{"unallocated", map[string]string{"app": "web"}, unallocatedGroup},
+		{"no labels", nil, unallocatedGroup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allocationKey(tt.labels, chain); got != tt.want {
+				t.Errorf("allocationKey(%v) = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newGroupPrinter(&buf, OutputTypeRequests, false, FormatTable, []string{"team", "owner"}, nil)
+	p.Add(DeploymentMetrics{Name: "legacy", Requests: ResourceMetrics{CPU: 100}})
+	p.Add(DeploymentMetrics{Name: "web", Labels: map[string]string{"team": "shop"}, Requests: ResourceMetrics{CPU: 200}})
+	p.Add(DeploymentMetrics{Name: "cart", Labels: map[string]string{"team": "shop"}, Requests: ResourceMetrics{CPU: 300}})
+	p.Add(DeploymentMetrics{Name: "etl", Labels: map[string]string{"owner": "data"}, Requests: ResourceMetrics{CPU: 400}})
+	p.Flush()
+
+	want := "GROUP         WORKLOADS   CPU          MEMORY\n" +
+		"owner=data    1           400m         0 B\n" +
+		"team=shop     2           500m         0 B\n" +
+		"UNALLOCATED   1           100m         0 B\n" +
+		"TOTAL         4           1.00 cores   0 B\n"
+	if buf.String() != want {
+		t.Errorf("group output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
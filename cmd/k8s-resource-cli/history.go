@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// historySnapshot is one line of the history file: the total requests per
+// namespace (or Porter target) seen by a single run, and the UID of each
+// Kubernetes workload by kind/namespace/name so a recreated workload can be
+// told apart from one that was there all along
+type historySnapshot struct {
+	Time       time.Time                  `json:"time"`
+	Namespaces map[string]ResourceMetrics `json:"namespaces"`
+	Workloads  map[string]string          `json:"workloads,omitempty"`
+}
+
+// historyRecorder sums requests per namespace as workloads are collected so
+// the run can be appended to the history file once it completes
+type historyRecorder struct {
+	snapshot historySnapshot
+}
+
+func newHistoryRecorder() *historyRecorder {
+	return &historyRecorder{snapshot: historySnapshot{
+		Namespaces: make(map[string]ResourceMetrics),
+		Workloads:  make(map[string]string),
+	}}
+}
+
+func (r *historyRecorder) Add(dm DeploymentMetrics) {
+	requests := r.snapshot.Namespaces[dm.Namespace]
+	requests.add(dm.Requests)
+	r.snapshot.Namespaces[dm.Namespace] = requests
+	if dm.UID != "" {
+		r.snapshot.Workloads[dm.Key()] = dm.UID
+	}
+}
+
+// recreatedWorkloads returns the workloads both snapshots have under the same
+// kind/namespace/name but with a different UID, i.e. deleted and created again
+// in between. Snapshots written before UIDs were recorded have none to compare.
+func recreatedWorkloads(before, after historySnapshot) []string {
+	var keys []string
+	for key, uid := range after.Workloads {
+		if previous, ok := before.Workloads[key]; ok && previous != uid {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Snapshot returns the totals collected so far, stamped with the run's time
+func (r *historyRecorder) Snapshot(now time.Time) historySnapshot {
+	r.snapshot.Time = now.UTC()
+	return r.snapshot
+}
+
+// Save appends the snapshot as a single JSON line, creating the file if needed
+func (r *historyRecorder) Save(path string, now time.Time) error {
+	data, err := json.Marshal(r.Snapshot(now))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readHistory loads every snapshot from a history file, oldest first as written
+func readHistory(path string) ([]historySnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snapshots []historySnapshot
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snapshot historySnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("invalid history entry on line %d: %w", line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
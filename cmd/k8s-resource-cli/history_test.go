@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, cpu := range []int64{1000, 1200} {
+		r := newHistoryRecorder()
+		r.Add(DeploymentMetrics{Name: "web", Namespace: "prod", Type: "Deployment", UID: fmt.Sprintf("uid-%d", i), Requests: ResourceMetrics{CPU: cpu / 2, Memory: 1024}})
+		r.Add(DeploymentMetrics{Name: "api", Namespace: "prod", Requests: ResourceMetrics{CPU: cpu / 2, Memory: 1024}})
+		if err := r.Save(path, first.Add(time.Duration(i)*24*time.Hour)); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	snapshots, err := readHistory(path)
+	if err != nil {
+		t.Fatalf("readHistory() error = %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("len(snapshots) = %d, want 2", len(snapshots))
+	}
+	if got := snapshots[1].Namespaces["prod"]; got.CPU != 1200 || got.Memory != 2048 {
+		t.Errorf("second snapshot prod = %+v, want CPU 1200, Memory 2048", got)
+	}
+	if got := snapshots[1].Workloads["Deployment/prod/web"]; got != "uid-1" {
+		t.Errorf("second snapshot web UID = %q, want uid-1", got)
+	}
+	if got := recreatedWorkloads(snapshots[0], snapshots[1]); len(got) != 1 || got[0] != "Deployment/prod/web" {
+		t.Errorf("recreatedWorkloads() = %v, want [Deployment/prod/web]", got)
+	}
+	if !snapshots[0].Time.Equal(first) {
+		t.Errorf("first snapshot time = %v, want %v", snapshots[0].Time, first)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// hpaLimits is what HPA targets are checked against: the largest single node
+// and the ResourceQuota requests limit per namespace
+type hpaLimits struct {
+	LargestNode ResourceMetrics
+	Quotas      map[string]ResourceMetrics
+}
+
+// checkHPA returns the problems with a deployment's HPA configuration given
+// its current per-pod CPU requests. A pod at the target utilization must fit
+// on a node, and all pods at max replicas must fit in the namespace quota.
+// For a ContainerResource target only that container is taken to the target.
+func checkHPA(dm DeploymentMetrics, limits hpaLimits) []string {
+	if dm.TargetCPU <= 0 || dm.CurrentReplicas <= 0 || dm.Requests.CPU == 0 {
+		return nil
+	}
+
+	var problems []string
+	perPod := dm.Requests.CPU / int64(dm.CurrentReplicas)
+	perPodAtTarget := perPod * int64(dm.TargetCPU) / 100
+	subject := "a pod"
+	if dm.ScaledRequests != nil {
+		// Only the scaled container runs at the target, the rest of the pod
+		// is counted at its requests
+		scaled := dm.ScaledRequests.CPU
+		perPodAtTarget = perPod - scaled + scaled*int64(dm.TargetCPU)/100
+		subject = "a pod with container " + dm.ScaledContainer
+	}
+
+	if limits.LargestNode.CPU > 0 && perPodAtTarget > limits.LargestNode.CPU {
+		problems = append(problems, fmt.Sprintf(
+			"%s at the %d%% CPU target would use %s, more than the largest node allocatable (%s), so the HPA can't reach its target",
+			subject, dm.TargetCPU, formatCPU(perPodAtTarget), formatCPU(limits.LargestNode.CPU)))
+	}
+
+	// Usage above requests doesn't count against a requests quota, so the
+	// quota bounds how many replicas can be scheduled at all
+	quota := limits.Quotas[dm.Namespace]
+	if maxRequests := perPod * int64(dm.MaxReplicas); quota.CPU > 0 && maxRequests > quota.CPU {
+		problems = append(problems, fmt.Sprintf(
+			"%d max replicas request %s CPU, beyond the namespace quota (%s), so the HPA can't scale to max",
+			dm.MaxReplicas, formatCPU(maxRequests), formatCPU(quota.CPU)))
+	}
+
+	return problems
+}
+
+// hpaChecker collects the problems found in each workload for printing after the report
+type hpaChecker struct {
+	limits   hpaLimits
+	findings []string
+}
+
+// newHPAChecker looks up node sizes and quotas for the namespace (all if
+// empty). Lookup errors are reported and leave that check disabled.
+func newHPAChecker(ctx context.Context, clientset *kubernetes.Clientset, namespace string) *hpaChecker {
+	c := &hpaChecker{}
+	var err error
+	if _, c.limits.LargestNode, err = loadNodeSizes(ctx, clientset); err != nil {
+		warnf("Error getting node sizes for --check-hpa: %v", err)
+	}
+	if c.limits.Quotas, err = loadQuotaLimits(ctx, clientset, namespace); err != nil {
+		warnf("Error getting resource quotas for --check-hpa: %v", err)
+	}
+	return c
+}
+
+func (c *hpaChecker) Add(dm DeploymentMetrics) {
+	for _, problem := range checkHPA(dm, c.limits) {
+		c.findings = append(c.findings, fmt.Sprintf("%s/%s: %s", dm.Namespace, dm.Name, problem))
+	}
+}
+
+func (c *hpaChecker) Print(out io.Writer) {
+	for _, finding := range c.findings {
+		fmt.Fprintf(out, "Warning: HPA check %s\n", finding)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCheckHPA(t *testing.T) {
+	limits := hpaLimits{
+		LargestNode: ResourceMetrics{CPU: 4000},
+		Quotas:      map[string]ResourceMetrics{"quota": {CPU: 5000}},
+	}
+
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want []string
+	}{
+		{
+			name: "no HPA target",
+			dm:   DeploymentMetrics{Namespace: "quota", CurrentReplicas: 2, MaxReplicas: 20, Requests: ResourceMetrics{CPU: 2000}},
+		},
+		{
+			name: "fits",
+			dm:   DeploymentMetrics{Namespace: "quota", CurrentReplicas: 2, MaxReplicas: 5, TargetCPU: 80, Requests: ResourceMetrics{CPU: 2000}},
+		},
+		{
+			name: "target beyond largest node",
+			dm:   DeploymentMetrics{Namespace: "other", CurrentReplicas: 1, MaxReplicas: 3, TargetCPU: 300, Requests: ResourceMetrics{CPU: 2000}},
+			want: []string{"largest node"},
+		},
+		{
+			name: "max replicas beyond quota",
+			dm:   DeploymentMetrics{Namespace: "quota", CurrentReplicas: 2, MaxReplicas: 10, TargetCPU: 70, Requests: ResourceMetrics{CPU: 2000}},
+			want: []string{"namespace quota"},
+		},
+		{
+			name: "container target with large sidecar fits",
+			dm: DeploymentMetrics{Namespace: "other", CurrentReplicas: 1, MaxReplicas: 3, TargetCPU: 300,
+				Requests: ResourceMetrics{CPU: 2000}, ScaledContainer: "app", ScaledRequests: &ResourceMetrics{CPU: 500}},
+		},
+		{
+			name: "container target beyond largest node",
+			dm: DeploymentMetrics{Namespace: "other", CurrentReplicas: 1, MaxReplicas: 3, TargetCPU: 300,
+				Requests: ResourceMetrics{CPU: 2000}, ScaledContainer: "app", ScaledRequests: &ResourceMetrics{CPU: 1500}},
+			want: []string{"container app"},
+		},
+		{
+			name: "no pods running",
+			dm:   DeploymentMetrics{Namespace: "quota", MaxReplicas: 10, TargetCPU: 500},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkHPA(tt.dm, limits)
+			if len(got) != len(tt.want) {
+				t.Fatalf("checkHPA() = %v, want %d problems", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("checkHPA()[%d] = %q, want it to mention %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHPACheckerPrint(t *testing.T) {
+	c := &hpaChecker{limits: hpaLimits{LargestNode: ResourceMetrics{CPU: 1000}}}
+	c.Add(DeploymentMetrics{Name: "api", Namespace: "shop", CurrentReplicas: 1, MaxReplicas: 2, TargetCPU: 200, Requests: ResourceMetrics{CPU: 1000}})
+
+	var buf bytes.Buffer
+	c.Print(&buf)
+	if !strings.HasPrefix(buf.String(), "Warning: HPA check shop/api: ") {
+		t.Errorf("Print() = %q", buf.String())
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Usage as a share of requests that --hpa-coverage calls headroom when both
+// CPU and memory stay below it, and risk when either reaches it
+const (
+	hpaCoverageHeadroom = 0.5
+	hpaCoverageRisk     = 0.8
+)
+
+// Assessments of a deployment without an HPA
+const (
+	coverageHeadroom = "headroom" // usage far below requests: room to scale in, or an HPA with a low min
+	coverageRisk     = "risk"     // usage near requests: nothing adds replicas when load grows
+	coverageSteady   = "steady"
+	coverageUnknown  = "unknown" // no replicas, requests or usage to compare
+)
+
+// assessCoverage tells whether a deployment without an HPA has headroom or
+// is at risk, from its usage against its requests
+func assessCoverage(dm DeploymentMetrics) string {
+	if dm.CurrentReplicas == 0 || dm.Requests.CPU == 0 || dm.Requests.Memory == 0 || (dm.Usage.CPU == 0 && dm.Usage.Memory == 0) {
+		return coverageUnknown
+	}
+	cpu := float64(dm.Usage.CPU) / float64(dm.Requests.CPU)
+	memory := float64(dm.Usage.Memory) / float64(dm.Requests.Memory)
+	switch {
+	case cpu >= hpaCoverageRisk || memory >= hpaCoverageRisk:
+		return coverageRisk
+	case cpu < hpaCoverageHeadroom && memory < hpaCoverageHeadroom:
+		return coverageHeadroom
+	}
+	return coverageSteady
+}
+
+// namespaceCoverage counts a namespace's deployments and how they're scaled
+type namespaceCoverage struct {
+	deployments int
+	autoscaled  int
+	headroom    int
+	risk        int
+}
+
+// hpaCoverage collects which Kubernetes deployments an HPA scales, per
+// namespace, for printing after the report. CronJobs and static pods can't
+// be autoscaled and aren't counted.
+type hpaCoverage struct {
+	namespaces map[string]*namespaceCoverage
+	uncovered  []DeploymentMetrics
+}
+
+func newHPACoverage() *hpaCoverage {
+	return &hpaCoverage{namespaces: make(map[string]*namespaceCoverage)}
+}
+
+func (c *hpaCoverage) Add(dm DeploymentMetrics) {
+	if dm.Type != "Deployment" || dm.Source != SourceKubernetes {
+		return
+	}
+	ns := c.namespaces[dm.Namespace]
+	if ns == nil {
+		ns = &namespaceCoverage{}
+		c.namespaces[dm.Namespace] = ns
+	}
+	ns.deployments++
+	// An HPA always has a min replicas, 1 when it doesn't set one
+	if dm.MinReplicas > 0 {
+		ns.autoscaled++
+		return
+	}
+	switch assessCoverage(dm) {
+	case coverageHeadroom:
+		ns.headroom++
+	case coverageRisk:
+		ns.risk++
+	}
+	c.uncovered = append(c.uncovered, dm)
+}
+
+// Print writes the coverage per namespace, then the deployments without an
+// HPA with their utilization, at-risk ones first
+func (c *hpaCoverage) Print(out io.Writer, format string) {
+	write := printTableResults
+	if format == FormatMarkdown {
+		write = printMarkdownResults
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "HPA coverage")
+	namespaces := make([]string, 0, len(c.namespaces))
+	for namespace := range c.namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	summary := resultTable{headers: []string{"NAMESPACE", "DEPLOYMENTS", "WITH HPA", "COVERAGE", "HEADROOM", "RISK"}}
+	var total namespaceCoverage
+	for _, namespace := range namespaces {
+		ns := c.namespaces[namespace]
+		summary.rows = append(summary.rows, coverageRow(namespace, *ns))
+		total.deployments += ns.deployments
+		total.autoscaled += ns.autoscaled
+		total.headroom += ns.headroom
+		total.risk += ns.risk
+	}
+	summary.total = coverageRow("TOTAL", total)
+	write(out, summary, false)
+
+	if len(c.uncovered) == 0 {
+		return
+	}
+	order := map[string]int{coverageRisk: 0, coverageHeadroom: 1, coverageSteady: 2, coverageUnknown: 3}
+	sort.SliceStable(c.uncovered, func(i, j int) bool {
+		a, b := c.uncovered[i], c.uncovered[j]
+		if order[assessCoverage(a)] != order[assessCoverage(b)] {
+			return order[assessCoverage(a)] < order[assessCoverage(b)]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Deployments without an HPA")
+	details := resultTable{headers: []string{"NAMESPACE", "DEPLOYMENT", "REPLICAS", "CPU%", "MEM%", "ASSESSMENT"}}
+	var replicas int32
+	for _, dm := range c.uncovered {
+		replicas += dm.CurrentReplicas
+		details.rows = append(details.rows, []string{
+			dm.Namespace, dm.Name, fmt.Sprintf("%d", dm.CurrentReplicas),
+			formatUtilization(dm.Usage.CPU, dm.Requests.CPU), formatUtilization(dm.Usage.Memory, dm.Requests.Memory),
+			assessCoverage(dm),
+		})
+	}
+	details.total = []string{"TOTAL", "", fmt.Sprintf("%d", replicas), "", "", ""}
+	write(out, details, false)
+}
+
+func coverageRow(name string, ns namespaceCoverage) []string {
+	return []string{name, fmt.Sprintf("%d", ns.deployments), fmt.Sprintf("%d", ns.autoscaled),
+		formatUtilization(int64(ns.autoscaled), int64(ns.deployments)), fmt.Sprintf("%d", ns.headroom), fmt.Sprintf("%d", ns.risk)}
+}
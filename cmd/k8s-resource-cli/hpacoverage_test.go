@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAssessCoverage(t *testing.T) {
+	requests := ResourceMetrics{CPU: 1000, Memory: 1048576}
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want string
+	}{
+		{"idle", DeploymentMetrics{CurrentReplicas: 2, Requests: requests, Usage: ResourceMetrics{CPU: 100, Memory: 104857}}, coverageHeadroom},
+		{"cpu near requests", DeploymentMetrics{CurrentReplicas: 2, Requests: requests, Usage: ResourceMetrics{CPU: 850, Memory: 104857}}, coverageRisk},
+		{"memory near requests", DeploymentMetrics{CurrentReplicas: 2, Requests: requests, Usage: ResourceMetrics{CPU: 100, Memory: 943718}}, coverageRisk},
+		{"in between", DeploymentMetrics{CurrentReplicas: 2, Requests: requests, Usage: ResourceMetrics{CPU: 600, Memory: 104857}}, coverageSteady},
+		{"scaled to zero", DeploymentMetrics{Requests: requests}, coverageUnknown},
+		{"no requests", DeploymentMetrics{CurrentReplicas: 1, Usage: ResourceMetrics{CPU: 100, Memory: 104857}}, coverageUnknown},
+		{"no usage", DeploymentMetrics{CurrentReplicas: 1, Requests: requests}, coverageUnknown},
+	}
+	for _, tt := range tests {
+		if got := assessCoverage(tt.dm); got != tt.want {
+			t.Errorf("%s: assessCoverage() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHPACoveragePrint(t *testing.T) {
+	requests := ResourceMetrics{CPU: 1000, Memory: 1048576}
+	c := newHPACoverage()
+	c.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", Source: SourceKubernetes, CurrentReplicas: 3, MinReplicas: 2,
+		Requests: requests, Usage: ResourceMetrics{CPU: 900, Memory: 943718}})
+	c.Add(DeploymentMetrics{Name: "cart", Namespace: "shop", Type: "Deployment", Source: SourceKubernetes, CurrentReplicas: 2,
+		Requests: requests, Usage: ResourceMetrics{CPU: 100, Memory: 104857}})
+	c.Add(DeploymentMetrics{Name: "worker", Namespace: "jobs", Type: "Deployment", Source: SourceKubernetes, CurrentReplicas: 1,
+		Requests: requests, Usage: ResourceMetrics{CPU: 900, Memory: 104857}})
+	c.Add(DeploymentMetrics{Name: "nightly", Namespace: "jobs", Type: "CronJob", Source: SourceKubernetes})
+	c.Add(DeploymentMetrics{Name: "app", Namespace: "porter", Type: "Deployment", Source: SourcePorter, CurrentReplicas: 1})
+
+	var buf bytes.Buffer
+	c.Print(&buf, FormatTable)
+	want := "\nHPA coverage\n" +
+		"NAMESPACE   DEPLOYMENTS   WITH HPA   COVERAGE   HEADROOM   RISK\n" +
+		"jobs        1             0          0%         0          1\n" +
+		"shop        2             1          50%        1          0\n" +
+		"TOTAL       3             1          33%        1          1\n" +
+		"\nDeployments without an HPA\n" +
+		"NAMESPACE   DEPLOYMENT   REPLICAS   CPU%   MEM%   ASSESSMENT\n" +
+		"jobs        worker       1          90%    10%    risk\n" +
+		"shop        cart         2          10%    10%    headroom\n" +
+		"TOTAL                    3                        \n"
+	if buf.String() != want {
+		t.Errorf("Print() =\n%q\nwant\n%q", buf.String(), want)
+	}
+}
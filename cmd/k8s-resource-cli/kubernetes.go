@@ -4,26 +4,97 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/metrics/pkg/client/clientset/versioned"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-func getNamespaceFromKubeconfig(kubeconfigPath string) (string, error) {
-	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+// serviceAccountNamespaceFile is mounted into every pod that runs with a service account
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// buildRESTConfig loads the kubeconfig, optionally overriding the current context.
+// When no kubeconfig file exists and we're running inside a pod, the in-cluster
+// service account config is used instead.
+func buildRESTConfig(kubeconfigPath, kubeContext string) (*rest.Config, error) {
+	if !kubeconfigExists(kubeconfigPath) && kubeContext == "" {
+		if config, err := rest.InClusterConfig(); err == nil {
+			return config, nil
+		}
+	}
+
+	loadingRules := kubeconfigLoadingRules(kubeconfigPath)
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
+
+// kubeconfigLoadingRules loads a kubeconfig path as-is, or merges every file
+// of a KUBECONFIG-style list the way kubectl does, the first file to set a
+// value winning
+func kubeconfigLoadingRules(path string) *clientcmd.ClientConfigLoadingRules {
+	if paths := filepath.SplitList(path); len(paths) > 1 {
+		return &clientcmd.ClientConfigLoadingRules{Precedence: paths}
+	}
+	return &clientcmd.ClientConfigLoadingRules{ExplicitPath: path}
+}
+
+// loadKubeconfig loads the kubeconfig, merged when it lists several files
+func loadKubeconfig(path string) (*clientcmdapi.Config, error) {
+	return kubeconfigLoadingRules(path).Load()
+}
+
+// kubeconfigExists reports whether the kubeconfig, or any file of a list, exists
+func kubeconfigExists(path string) bool {
+	for _, file := range filepath.SplitList(path) {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func readServiceAccountNamespace(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	namespace := strings.TrimSpace(string(data))
+	if namespace == "" {
+		return "", fmt.Errorf("service account namespace file %s is empty", path)
+	}
+
+	return namespace, nil
+}
+
+func getNamespaceFromKubeconfig(kubeconfigPath, kubeContext string) (string, error) {
+	config, err := loadKubeconfig(kubeconfigPath)
 	if err != nil {
 		return "", err
 	}
 
-	if config.CurrentContext == "" {
+	contextName := config.CurrentContext
+	if kubeContext != "" {
+		contextName = kubeContext
+	}
+	if contextName == "" {
 		return "", fmt.Errorf("no current context")
 	}
 
-	context, ok := config.Contexts[config.CurrentContext]
+	context, ok := config.Contexts[contextName]
 	if !ok {
-		return "", fmt.Errorf("current context not found")
+		return "", fmt.Errorf("context %s not found", contextName)
 	}
 
 	if context.Namespace != "" {
@@ -33,7 +104,7 @@ func getNamespaceFromKubeconfig(kubeconfigPath string) (string, error) {
 	return "default", nil
 }
 
-func getDeploymentMetrics(ctx context.Context, clientset *kubernetes.Clientset, metricsClientset *versioned.Clientset, namespace, name string) (DeploymentMetrics, error) {
+func getDeploymentMetrics(ctx context.Context, clientset *kubernetes.Clientset, usage *podUsageIndex, caps clusterCapabilities, namespace, name string) (DeploymentMetrics, error) {
 	// Get the deployment first to get replicas information
 	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
@@ -41,10 +112,27 @@ func getDeploymentMetrics(ctx context.Context, clientset *kubernetes.Clientset,
 	}
 
 	dm := DeploymentMetrics{
-		Name:            name,
-		Namespace:       namespace,
-		Type:            "Deployment",
-		CurrentReplicas: deployment.Status.Replicas,
+		Name:              name,
+		Namespace:         namespace,
+		Type:              "Deployment",
+		Source:            SourceKubernetes,
+		UID:               string(deployment.UID),
+		Labels:            deployment.Labels,
+		OS:                workloadOS(deployment.Spec.Template.Spec),
+		CurrentReplicas:   deployment.Status.Replicas,
+		ReadyReplicas:     deployment.Status.ReadyReplicas,
+		AvailableReplicas: deployment.Status.AvailableReplicas,
+		Paused:            deployment.Spec.Paused,
+	}
+	perPod := podRequests(corev1.Pod{Spec: deployment.Spec.Template.Spec})
+	dm.PodRequests = &perPod
+	dm.QOSClass = string(podQOSClass(deployment.Spec.Template.Spec))
+	dm.Containers = len(deployment.Spec.Template.Spec.Containers)
+	dm.PriorityClass = deployment.Spec.Template.Spec.PriorityClassName
+	dm.Images = podImages(deployment.Spec.Template.Spec)
+	dm.DependsOn = dependsOn(deployment.Annotations, namespace)
+	if dm.ExpectedPeak, err = expectedPeak(deployment.Annotations); err != nil {
+		warnf("Deployment %s/%s: %v", namespace, name, err)
 	}
 
 	if deployment.Spec.Replicas != nil {
@@ -73,62 +161,138 @@ func getDeploymentMetrics(ctx context.Context, clientset *kubernetes.Clientset,
 
 	// Calculate requests from pod specs
 	for _, pod := range pods.Items {
-		for _, container := range pod.Spec.Containers {
-			if cpu := container.Resources.Requests.Cpu(); cpu != nil {
-				dm.Requests.CPU += cpu.MilliValue()
-			}
-			if memory := container.Resources.Requests.Memory(); memory != nil {
-				dm.Requests.Memory += memory.Value()
-			}
+		dm.Requests.add(podRequests(pod))
+		dm.Limits.add(podLimits(pod))
+	}
+
+	dm.Nodes = nodeSpread(pods.Items)
+	dm.Restarts = podRestarts(pods.Items)
+	dm.Owners = buildOwnerTree("ReplicaSet", pods.Items, usage)
+
+	// Get current usage from the pod metrics fetched up front
+	for _, pod := range pods.Items {
+		if podUsage, ok := usage.Pod(namespace, pod.Name); ok {
+			dm.Usage.CPU += podUsage.CPU
+			dm.Usage.Memory += podUsage.Memory
 		}
 	}
 
-	// Get current usage from metrics API
-	podMetricsList, err := metricsClientset.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
-	})
+	hpa, found, err := findHPA(ctx, clientset, caps, namespace, name)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Error getting pod metrics: %v\n", err)
-	} else {
-		for _, podMetrics := range podMetricsList.Items {
-			for _, container := range podMetrics.Containers {
-				if cpu := container.Usage.Cpu(); cpu != nil {
-					dm.Usage.CPU += cpu.MilliValue()
-				}
-				if memory := container.Usage.Memory(); memory != nil {
-					dm.Usage.Memory += memory.Value()
+		partialf("Error listing HPA: %v", err)
+	}
+	if !found {
+		return dm, nil
+	}
+
+	dm.MinReplicas = hpa.MinReplicas
+	dm.MaxReplicas = hpa.MaxReplicas
+	dm.TargetCPU = hpa.TargetCPU
+	if hpa.Container != "" && len(pods.Items) > 0 {
+		// A ContainerResource target only looks at this container's requests
+		var scaled ResourceMetrics
+		for _, pod := range pods.Items {
+			scaled.add(containerRequests(pod, hpa.Container))
+		}
+		scaled.CPU /= int64(len(pods.Items))
+		scaled.Memory /= int64(len(pods.Items))
+		dm.ScaledContainer = hpa.Container
+		dm.ScaledRequests = &scaled
+	}
+
+	// Calculate max requests based on HPA max replicas. Every replica brings
+	// all its containers, so this scales whole pods even when the HPA
+	// targets a single container. A Deployment scaled to zero has no pods
+	// to average, so its pod template is projected instead.
+	if dm.MaxReplicas > dm.DesiredReplicas {
+		if len(pods.Items) > 0 {
+			dm.MaxRequests = hpaMaxRequests(dm.Requests, len(pods.Items), dm.MaxReplicas)
+		} else {
+			dm.MaxRequests = hpaMaxRequests(*dm.PodRequests, 1, dm.MaxReplicas)
+		}
+	}
+
+	return dm, nil
+}
+
+// hpaMaxRequests projects the requests at max replicas from the average pod
+// of the current ones
+func hpaMaxRequests(requests ResourceMetrics, pods int, maxReplicas int32) ResourceMetrics {
+	return ResourceMetrics{
+		CPU:    requests.CPU / int64(pods) * int64(maxReplicas),
+		Memory: requests.Memory / int64(pods) * int64(maxReplicas),
+	}
+}
+
+// hpaInfo is what the report needs from the HPA scaling a deployment. Container
+// is set when the CPU target is a ContainerResource metric.
+type hpaInfo struct {
+	MinReplicas int32
+	MaxReplicas int32
+	TargetCPU   int32
+	Container   string
+}
+
+// findHPA looks up the HPA targeting the deployment, through autoscaling/v2
+// when the cluster serves it so ContainerResource metrics are visible
+func findHPA(ctx context.Context, clientset *kubernetes.Clientset, caps clusterCapabilities, namespace, name string) (hpaInfo, bool, error) {
+	if caps.AutoscalingV2 {
+		hpaList, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return hpaInfo{}, false, err
+		}
+		for _, hpa := range hpaList.Items {
+			if hpa.Spec.ScaleTargetRef.Name == name && hpa.Spec.ScaleTargetRef.Kind == "Deployment" {
+				info := hpaInfo{MinReplicas: 1, MaxReplicas: hpa.Spec.MaxReplicas}
+				if hpa.Spec.MinReplicas != nil {
+					info.MinReplicas = *hpa.Spec.MinReplicas
 				}
+				info.TargetCPU, info.Container = hpaCPUTarget(hpa.Spec.Metrics)
+				return info, true, nil
 			}
 		}
+		return hpaInfo{}, false, nil
 	}
 
-	// Get HPA information
+	if !caps.AutoscalingV1 {
+		return hpaInfo{}, false, nil
+	}
 	hpaList, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Error listing HPA: %v\n", err)
-	} else {
-		for _, hpa := range hpaList.Items {
-			if hpa.Spec.ScaleTargetRef.Name == name && hpa.Spec.ScaleTargetRef.Kind == "Deployment" {
-				dm.MaxReplicas = hpa.Spec.MaxReplicas
-				// Calculate max requests based on HPA max replicas
-				if dm.MaxReplicas > dm.DesiredReplicas && len(pods.Items) > 0 {
-					// Get requests per pod (average from current pods)
-					requestsPerPod := ResourceMetrics{
-						CPU:    dm.Requests.CPU / int64(len(pods.Items)),
-						Memory: dm.Requests.Memory / int64(len(pods.Items)),
-					}
-					dm.MaxRequests.CPU = requestsPerPod.CPU * int64(dm.MaxReplicas)
-					dm.MaxRequests.Memory = requestsPerPod.Memory * int64(dm.MaxReplicas)
-				}
-				break
+		return hpaInfo{}, false, err
+	}
+	for _, hpa := range hpaList.Items {
+		if hpa.Spec.ScaleTargetRef.Name == name && hpa.Spec.ScaleTargetRef.Kind == "Deployment" {
+			info := hpaInfo{MinReplicas: 1, MaxReplicas: hpa.Spec.MaxReplicas}
+			if hpa.Spec.MinReplicas != nil {
+				info.MinReplicas = *hpa.Spec.MinReplicas
+			}
+			if hpa.Spec.TargetCPUUtilizationPercentage != nil {
+				info.TargetCPU = *hpa.Spec.TargetCPUUtilizationPercentage
 			}
+			return info, true, nil
 		}
 	}
+	return hpaInfo{}, false, nil
+}
 
-	return dm, nil
+// hpaCPUTarget returns the CPU utilization target of an autoscaling/v2 metric
+// list and, for a ContainerResource metric, the container it applies to
+func hpaCPUTarget(metrics []autoscalingv2.MetricSpec) (int32, string) {
+	for _, metric := range metrics {
+		switch {
+		case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil &&
+			metric.Resource.Name == corev1.ResourceCPU && metric.Resource.Target.AverageUtilization != nil:
+			return *metric.Resource.Target.AverageUtilization, ""
+		case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil &&
+			metric.ContainerResource.Name == corev1.ResourceCPU && metric.ContainerResource.Target.AverageUtilization != nil:
+			return *metric.ContainerResource.Target.AverageUtilization, metric.ContainerResource.Container
+		}
+	}
+	return 0, ""
 }
 
-func getCronJobMetrics(ctx context.Context, clientset *kubernetes.Clientset, metricsClientset *versioned.Clientset, namespace, name string) (DeploymentMetrics, error) {
+func getCronJobMetrics(ctx context.Context, clientset *kubernetes.Clientset, usage *podUsageIndex, caps clusterCapabilities, namespace, name string, jobRuns *jobRunIndex, suspendedAsZero bool, now time.Time, warnings *workerLog) (DeploymentMetrics, error) {
 	// Get the cronjob first to get job template information
 	cronJob, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
@@ -137,12 +301,7 @@ func getCronJobMetrics(ctx context.Context, clientset *kubernetes.Clientset, met
 
 	// For cronjobs, we look at the jobTemplate spec to understand resource requirements
 	// We use parallelism and completions from the job template
-	var desiredReplicas int32 = 1
-	if cronJob.Spec.JobTemplate.Spec.Completions != nil {
-		desiredReplicas = *cronJob.Spec.JobTemplate.Spec.Completions
-	} else if cronJob.Spec.JobTemplate.Spec.Parallelism != nil {
-		desiredReplicas = *cronJob.Spec.JobTemplate.Spec.Parallelism
-	}
+	requests, desiredReplicas := jobSpecRequests(cronJob.Spec.JobTemplate.Spec)
 
 	// Count active jobs created by this cronjob
 	var currentReplicas int32
@@ -156,50 +315,459 @@ func getCronJobMetrics(ctx context.Context, clientset *kubernetes.Clientset, met
 		Name:            name,
 		Namespace:       namespace,
 		Type:            "CronJob",
+		Source:          SourceKubernetes,
+		UID:             string(cronJob.UID),
+		Labels:          cronJob.Labels,
+		OS:              workloadOS(cronJob.Spec.JobTemplate.Spec.Template.Spec),
 		CurrentReplicas: currentReplicas,
-		DesiredReplicas: desiredReplicas,
-		MaxReplicas:     desiredReplicas, // CronJobs don't scale, max equals desired
+		// Jobs have no readiness, a running one counts as ready
+		ReadyReplicas:     currentReplicas,
+		AvailableReplicas: currentReplicas,
+		DesiredReplicas:   desiredReplicas,
+		MaxReplicas:       desiredReplicas, // CronJobs don't scale, max equals desired
+		Requests:          requests,
+	}
+	perPod := podRequests(corev1.Pod{Spec: cronJob.Spec.JobTemplate.Spec.Template.Spec})
+	dm.PodRequests = &perPod
+	dm.QOSClass = string(podQOSClass(cronJob.Spec.JobTemplate.Spec.Template.Spec))
+	dm.Containers = len(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers)
+	dm.PriorityClass = cronJob.Spec.JobTemplate.Spec.Template.Spec.PriorityClassName
+	dm.Images = podImages(cronJob.Spec.JobTemplate.Spec.Template.Spec)
+	perPodLimits := podLimits(corev1.Pod{Spec: cronJob.Spec.JobTemplate.Spec.Template.Spec})
+	dm.Limits = ResourceMetrics{CPU: perPodLimits.CPU * int64(desiredReplicas), Memory: perPodLimits.Memory * int64(desiredReplicas)}
+	dm.DependsOn = dependsOn(cronJob.Annotations, namespace)
+	if dm.ExpectedPeak, err = expectedPeak(cronJob.Annotations); err != nil {
+		warnings.warnf("CronJob %s/%s: %v", namespace, name, err)
 	}
 
-	// Calculate resource requests from the job template spec
-	for _, container := range cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers {
-		if cpu := container.Resources.Requests.Cpu(); cpu != nil {
-			dm.Requests.CPU += cpu.MilliValue() * int64(desiredReplicas)
-		}
-		if memory := container.Resources.Requests.Memory(); memory != nil {
-			dm.Requests.Memory += memory.Value() * int64(desiredReplicas)
+	// Average what recent runs actually requested, which can differ from
+	// the current template if it was changed
+	if jobRuns != nil {
+		runs, err := jobRuns.Runs(ctx, cronJob)
+		if err != nil {
+			warnings.partialf("Error listing jobs for cronjob %s: %v", name, err)
+		} else if len(runs) > 0 {
+			var total ResourceMetrics
+			for _, job := range runs {
+				jobRequests, _ := jobSpecRequests(job.Spec)
+				total.add(jobRequests)
+			}
+			dm.Requests = ResourceMetrics{
+				CPU:    total.CPU / int64(len(runs)),
+				Memory: total.Memory / int64(len(runs)),
+			}
+			dm.JobRuns = len(runs)
 		}
 	}
 
 	// Get pods from active jobs created by this cronjob for usage metrics
+	// and the owner graph, looking the jobs up in parallel
 	if len(cronJob.Status.Active) > 0 {
-		// List all pods owned by jobs created by this cronjob
+		var activePods []corev1.Pod
+		var mu sync.Mutex
+		var wg sync.WaitGroup
 		for _, activeJob := range cronJob.Status.Active {
-			pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-				LabelSelector: fmt.Sprintf("job-name=%s", activeJob.Name),
-			})
-			if err == nil {
-				// Get current usage from metrics API for these pods
+			wg.Add(1)
+			go func(jobName string) {
+				defer wg.Done()
+				pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+					LabelSelector: fmt.Sprintf("job-name=%s", jobName),
+				})
+				if err != nil {
+					warnings.partialf("Error listing pods of job %s for cronjob %s: %v", jobName, name, err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				activePods = append(activePods, pods.Items...)
 				for _, pod := range pods.Items {
-					podMetrics, err := metricsClientset.MetricsV1beta1().PodMetricses(namespace).Get(ctx, pod.Name, metav1.GetOptions{})
-					if err == nil {
-						for _, container := range podMetrics.Containers {
-							if cpu := container.Usage.Cpu(); cpu != nil {
-								dm.Usage.CPU += cpu.MilliValue()
-							}
-							if memory := container.Usage.Memory(); memory != nil {
-								dm.Usage.Memory += memory.Value()
-							}
-						}
+					if podUsage, ok := usage.Pod(namespace, pod.Name); ok {
+						dm.Usage.add(podUsage)
 					}
 				}
-			}
+			}(activeJob.Name)
 		}
+		wg.Wait()
+		dm.Owners = buildOwnerTree("Job", activePods, usage)
+		dm.Nodes = nodeSpread(activePods)
+		dm.Restarts = podRestarts(activePods)
 	}
 
 	// For cronjobs, max requests equals current requests (no HPA)
 	dm.MaxRequests.CPU = dm.Requests.CPU
 	dm.MaxRequests.Memory = dm.Requests.Memory
 
+	// A suspended cronjob starts no new runs, so optionally plan for none
+	dm.Schedule = cronJob.Spec.Schedule
+	dm.Suspended = cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+	if dm.Suspended && suspendedAsZero {
+		dm.MaxReplicas = 0
+		dm.MaxRequests = ResourceMetrics{}
+	}
+
+	// When the reserved burst actually happens, for capacity reviews
+	if !dm.Suspended {
+		if dm.NextRun, err = nextCronRun(cronJob.Spec.Schedule, cronJob.Spec.TimeZone, now); err != nil {
+			warnings.debugf(verbosityDecisions, "%v, leaving out the next run of cronjob %s in namespace %s", err, name, namespace)
+		}
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		last := cronJob.Status.LastSuccessfulTime.Time
+		dm.LastSuccessfulRun = &last
+	}
+
 	return dm, nil
 }
+
+// cronJobWorkers bounds how many CronJobs are collected at once
+const cronJobWorkers = 8
+
+// cronJobResult is what collecting one CronJob gave, with the warnings its
+// worker held back
+type cronJobResult struct {
+	cronJob  batchv1.CronJob
+	metrics  DeploymentMetrics
+	err      error
+	warnings *workerLog
+}
+
+// collectCronJobs runs fetch for every cronjob on a bounded worker pool and
+// hands each result to handle on the calling goroutine as it completes, so
+// handle may print and emit rows
+func collectCronJobs(cronJobs []batchv1.CronJob, fetch func(batchv1.CronJob, *workerLog) (DeploymentMetrics, error), handle func(cronJobResult)) {
+	queue := make(chan batchv1.CronJob)
+	results := make(chan cronJobResult)
+
+	var wg sync.WaitGroup
+	for range min(cronJobWorkers, len(cronJobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cronJob := range queue {
+				warnings := &workerLog{}
+				metrics, err := fetch(cronJob, warnings)
+				results <- cronJobResult{cronJob: cronJob, metrics: metrics, err: err, warnings: warnings}
+			}
+		}()
+	}
+	go func() {
+		for _, cronJob := range cronJobs {
+			queue <- cronJob
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		handle(result)
+	}
+}
+
+// jobSpecRequests returns the requests of one run of a job spec along with
+// how many pods a run has, from completions or else parallelism
+func jobSpecRequests(spec batchv1.JobSpec) (ResourceMetrics, int32) {
+	var pods int32 = 1
+	if spec.Completions != nil {
+		pods = *spec.Completions
+	} else if spec.Parallelism != nil {
+		pods = *spec.Parallelism
+	}
+
+	var requests ResourceMetrics
+	for _, container := range spec.Template.Spec.Containers {
+		if cpu := container.Resources.Requests.Cpu(); cpu != nil {
+			requests.CPU += cpu.MilliValue() * int64(pods)
+		}
+		if memory := container.Resources.Requests.Memory(); memory != nil {
+			requests.Memory += memory.Value() * int64(pods)
+		}
+	}
+
+	return requests, pods
+}
+
+// jobRunIndex holds the successfully completed jobs of each namespace by
+// owner UID, so every CronJob of a namespace shares one list of its jobs
+type jobRunIndex struct {
+	clientset   *kubernetes.Clientset
+	limit       int
+	mu          sync.Mutex
+	byNamespace map[string]*namespaceJobRuns
+}
+
+// namespaceJobRuns is the job list of one namespace, loaded once by whichever
+// CronJob of the namespace asks first
+type namespaceJobRuns struct {
+	once    sync.Once
+	byOwner map[types.UID][]batchv1.Job
+	err     error
+}
+
+func newJobRunIndex(clientset *kubernetes.Clientset, limit int) *jobRunIndex {
+	return &jobRunIndex{
+		clientset:   clientset,
+		limit:       limit,
+		byNamespace: make(map[string]*namespaceJobRuns),
+	}
+}
+
+// Runs returns up to limit successfully completed jobs owned by the cronjob,
+// most recent first, listing the jobs of its namespace on first use. Only
+// callers in the same namespace wait for that list.
+func (idx *jobRunIndex) Runs(ctx context.Context, cronJob *batchv1.CronJob) ([]batchv1.Job, error) {
+	idx.mu.Lock()
+	runs, ok := idx.byNamespace[cronJob.Namespace]
+	if !ok {
+		runs = &namespaceJobRuns{}
+		idx.byNamespace[cronJob.Namespace] = runs
+	}
+	idx.mu.Unlock()
+
+	runs.once.Do(func() {
+		var jobs []batchv1.Job
+		listOptions := metav1.ListOptions{Limit: listPageSize}
+		for {
+			jobList, err := idx.clientset.BatchV1().Jobs(cronJob.Namespace).List(ctx, listOptions)
+			if err != nil {
+				runs.err = err
+				return
+			}
+			jobs = append(jobs, jobList.Items...)
+			if jobList.Continue == "" {
+				break
+			}
+			listOptions.Continue = jobList.Continue
+		}
+		runs.byOwner = completedJobsByOwner(jobs)
+	})
+	if runs.err != nil {
+		return nil, runs.err
+	}
+
+	return latestJobRuns(append([]batchv1.Job(nil), runs.byOwner[cronJob.UID]...), idx.limit), nil
+}
+
+// completedJobsByOwner groups the successfully completed jobs by the UID of
+// each of their owners
+func completedJobsByOwner(jobs []batchv1.Job) map[types.UID][]batchv1.Job {
+	byOwner := make(map[types.UID][]batchv1.Job)
+	for _, job := range jobs {
+		if job.Status.CompletionTime == nil {
+			continue
+		}
+		for _, ref := range job.OwnerReferences {
+			byOwner[ref.UID] = append(byOwner[ref.UID], job)
+		}
+	}
+	return byOwner
+}
+
+// latestJobRuns sorts runs by completion time, newest first, and keeps limit of them
+func latestJobRuns(runs []batchv1.Job, limit int) []batchv1.Job {
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[j].Status.CompletionTime.Before(runs[i].Status.CompletionTime)
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
+
+// podLimits sums the CPU and memory limits of a pod's containers. A
+// container without a limit adds nothing, though it may use the whole node.
+func podLimits(pod corev1.Pod) ResourceMetrics {
+	var limits ResourceMetrics
+	for _, container := range pod.Spec.Containers {
+		if cpu := container.Resources.Limits.Cpu(); cpu != nil {
+			limits.CPU += cpu.MilliValue()
+		}
+		if memory := container.Resources.Limits.Memory(); memory != nil {
+			limits.Memory += memory.Value()
+		}
+	}
+	return limits
+}
+
+// podRequests sums the CPU and memory requests of a pod's containers
+func podRequests(pod corev1.Pod) ResourceMetrics {
+	return containerRequests(pod, "")
+}
+
+// containerRequests sums the requests of the pod's containers with the given
+// name, or of all of them if name is empty
+func containerRequests(pod corev1.Pod, name string) ResourceMetrics {
+	var requests ResourceMetrics
+	for _, container := range pod.Spec.Containers {
+		if name != "" && container.Name != name {
+			continue
+		}
+		if cpu := container.Resources.Requests.Cpu(); cpu != nil {
+			requests.CPU += cpu.MilliValue()
+		}
+		if memory := container.Resources.Requests.Memory(); memory != nil {
+			requests.Memory += memory.Value()
+		}
+	}
+	return requests
+}
+
+// nodeSpread counts the distinct nodes the pods are scheduled on
+func nodeSpread(pods []corev1.Pod) int {
+	nodes := make(map[string]bool)
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			nodes[pod.Spec.NodeName] = true
+		}
+	}
+	return len(nodes)
+}
+
+// podRestarts sums the restart counts of the pods' containers, init
+// containers included, so crash-looping pods show up next to their usage
+func podRestarts(pods []corev1.Pod) int32 {
+	var restarts int32
+	for _, pod := range pods {
+		for _, status := range append(append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+			restarts += status.RestartCount
+		}
+	}
+	return restarts
+}
+
+// workloadOS returns the operating system a pod spec is pinned to through a
+// kubernetes.io/os node selector or required node affinity. Unpinned pods are
+// taken to be Linux, since Windows pods must select Windows nodes explicitly.
+func workloadOS(spec corev1.PodSpec) string {
+	if value := spec.NodeSelector[corev1.LabelOSStable]; value != "" {
+		return value
+	}
+	if spec.Affinity != nil && spec.Affinity.NodeAffinity != nil && spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			for _, expr := range term.MatchExpressions {
+				if expr.Key == corev1.LabelOSStable && expr.Operator == corev1.NodeSelectorOpIn && len(expr.Values) == 1 {
+					return expr.Values[0]
+				}
+			}
+		}
+	}
+	return "linux"
+}
+
+// podImages lists the distinct images of a pod spec's containers, init
+// containers included, in the order they appear
+func podImages(spec corev1.PodSpec) []string {
+	var images []string
+	seen := make(map[string]bool)
+	for _, container := range append(append([]corev1.Container(nil), spec.InitContainers...), spec.Containers...) {
+		if container.Image != "" && !seen[container.Image] {
+			seen[container.Image] = true
+			images = append(images, container.Image)
+		}
+	}
+	return images
+}
+
+// isMirrorPod reports whether the pod is the API server's mirror of a static
+// pod the kubelet runs from a manifest, like kube-apiserver or etcd on
+// self-managed control planes
+func isMirrorPod(pod corev1.Pod) bool {
+	_, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]
+	return ok
+}
+
+// podReady reports whether the pod's Ready condition is true
+func podReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// staticPodMetrics reports a static pod as a single-replica workload. It
+// can't be scaled, so max requests equal its requests.
+func staticPodMetrics(pod corev1.Pod, usage *podUsageIndex) DeploymentMetrics {
+	dm := DeploymentMetrics{
+		Name:            pod.Name,
+		Namespace:       pod.Namespace,
+		Type:            "StaticPod",
+		Source:          SourceKubernetes,
+		UID:             string(pod.UID),
+		Labels:          pod.Labels,
+		OS:              workloadOS(pod.Spec),
+		DesiredReplicas: 1,
+		MaxReplicas:     1,
+		Requests:        podRequests(pod),
+		Limits:          podLimits(pod),
+		QOSClass:        string(podQOSClass(pod.Spec)),
+		Containers:      len(pod.Spec.Containers),
+		Images:          podImages(pod.Spec),
+	}
+	if pod.Status.Phase == corev1.PodRunning {
+		dm.CurrentReplicas = 1
+		dm.Nodes = 1
+		dm.Restarts = podRestarts([]corev1.Pod{pod})
+		if podReady(pod) {
+			dm.ReadyReplicas = 1
+			dm.AvailableReplicas = 1
+		}
+	}
+	dm.MaxRequests = dm.Requests
+	if podUsage, ok := usage.Pod(pod.Namespace, pod.Name); ok {
+		dm.Usage = podUsage
+	}
+	return dm
+}
+
+// loadNodeAllocatable sums the allocatable CPU and memory of every node
+func loadNodeAllocatable(ctx context.Context, clientset *kubernetes.Clientset) (ResourceMetrics, error) {
+	total, _, err := loadNodeSizes(ctx, clientset)
+	return total, err
+}
+
+// loadNodeSizes returns the summed allocatable of all nodes and the largest
+// allocatable CPU and memory found on any single node
+func loadNodeSizes(ctx context.Context, clientset *kubernetes.Clientset) (ResourceMetrics, ResourceMetrics, error) {
+	var total, largest ResourceMetrics
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return total, largest, fmt.Errorf("error listing nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		cpu := node.Status.Allocatable.Cpu().MilliValue()
+		memory := node.Status.Allocatable.Memory().Value()
+		total.CPU += cpu
+		total.Memory += memory
+		largest.CPU = max(largest.CPU, cpu)
+		largest.Memory = max(largest.Memory, memory)
+	}
+	return total, largest, nil
+}
+
+// loadQuotaLimits returns the tightest ResourceQuota requests limit of each
+// namespace that has one, zero for a resource without a quota
+func loadQuotaLimits(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (map[string]ResourceMetrics, error) {
+	quotas, err := clientset.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing resource quotas: %w", err)
+	}
+
+	limits := make(map[string]ResourceMetrics)
+	for _, quota := range quotas.Items {
+		limit := limits[quota.Namespace]
+		for _, name := range []corev1.ResourceName{corev1.ResourceRequestsCPU, corev1.ResourceCPU} {
+			if hard, ok := quota.Spec.Hard[name]; ok && (limit.CPU == 0 || hard.MilliValue() < limit.CPU) {
+				limit.CPU = hard.MilliValue()
+			}
+		}
+		for _, name := range []corev1.ResourceName{corev1.ResourceRequestsMemory, corev1.ResourceMemory} {
+			if hard, ok := quota.Spec.Hard[name]; ok && (limit.Memory == 0 || hard.Value() < limit.Memory) {
+				limit.Memory = hard.Value()
+			}
+		}
+		limits[quota.Namespace] = limit
+	}
+	return limits, nil
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestReadServiceAccountNamespace(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "namespace")
+	if err := os.WriteFile(path, []byte("team-a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readServiceAccountNamespace(path)
+	if err != nil {
+		t.Fatalf("readServiceAccountNamespace() error = %v", err)
+	}
+	if got != "team-a" {
+		t.Errorf("readServiceAccountNamespace() = %v, want team-a", got)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readServiceAccountNamespace(empty); err == nil {
+		t.Error("readServiceAccountNamespace() on empty file should return an error")
+	}
+
+	if _, err := readServiceAccountNamespace(filepath.Join(dir, "missing")); err == nil {
+		t.Error("readServiceAccountNamespace() on missing file should return an error")
+	}
+}
+
+func TestJobSpecRequests(t *testing.T) {
+	completions := int32(3)
+	spec := batchv1.JobSpec{
+		Completions: &completions,
+		Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("128Mi"),
+			}}},
+		}}},
+	}
+
+	requests, pods := jobSpecRequests(spec)
+	if pods != 3 {
+		t.Errorf("pods = %d, want 3", pods)
+	}
+	if requests.CPU != 750 || requests.Memory != 3*128*1024*1024 {
+		t.Errorf("requests = %+v, want CPU 750, Memory %d", requests, 3*128*1024*1024)
+	}
+
+	if _, pods := jobSpecRequests(batchv1.JobSpec{}); pods != 1 {
+		t.Errorf("pods without completions or parallelism = %d, want 1", pods)
+	}
+}
+
+func TestHPAMaxRequests(t *testing.T) {
+	// Two pods of an app container (100m, 256 MB) and a sidecar (400m, 512 MB).
+	// Even when a ContainerResource target scales on the app container, every
+	// new replica brings its sidecar, so whole pods are projected.
+	requests := ResourceMetrics{CPU: 1000, Memory: 1536}
+
+	if got := hpaMaxRequests(requests, 2, 3); got != (ResourceMetrics{CPU: 1500, Memory: 2304}) {
+		t.Errorf("hpaMaxRequests() = %+v, want CPU 1500, Memory 2304", got)
+	}
+}
+
+func TestDeploymentMetricsScaledToZeroWithHPA(t *testing.T) {
+	var zero int32
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &zero,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "worker"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "worker",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("250m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				}},
+			}}}},
+		},
+	}
+	hpas := autoscalingv2.HorizontalPodAutoscalerList{Items: []autoscalingv2.HorizontalPodAutoscaler{{
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "worker"},
+			MaxReplicas:    4,
+		},
+	}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/apps/v1/namespaces/shop/deployments/worker":
+			json.NewEncoder(w).Encode(deployment)
+		case "/api/v1/namespaces/shop/pods":
+			json.NewEncoder(w).Encode(corev1.PodList{})
+		case "/apis/autoscaling/v2/namespaces/shop/horizontalpodautoscalers":
+			json.NewEncoder(w).Encode(hpas)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm, err := getDeploymentMetrics(context.Background(), clientset, nil, clusterCapabilities{AutoscalingV2: true}, "shop", "worker")
+	if err != nil {
+		t.Fatalf("getDeploymentMetrics() error = %v", err)
+	}
+
+	want := ResourceMetrics{CPU: 1000, Memory: 4 * 128 * 1024 * 1024}
+	if got := dm.EffectiveMaxRequests(); got != want {
+		t.Errorf("EffectiveMaxRequests() = %+v, want %+v from the template at 4 max replicas", got, want)
+	}
+	if dm.Requests != (ResourceMetrics{}) {
+		t.Errorf("Requests = %+v, want none without pods", dm.Requests)
+	}
+}
+
+func TestCollectCronJobs(t *testing.T) {
+	var cronJobs []batchv1.CronJob
+	for i := range 20 {
+		cronJobs = append(cronJobs, batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("job-%d", i), Namespace: "batch"}})
+	}
+
+	var running, peak atomic.Int32
+	fetch := func(cronJob batchv1.CronJob, warnings *workerLog) (DeploymentMetrics, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if cronJob.Name == "job-3" {
+			warnings.partialf("Error listing pods of job %s", cronJob.Name)
+			return DeploymentMetrics{}, errors.New("forbidden")
+		}
+		return DeploymentMetrics{Name: cronJob.Name}, nil
+	}
+
+	seen := make(map[string]bool)
+	var failed []string
+	collectCronJobs(cronJobs, fetch, func(result cronJobResult) {
+		seen[result.cronJob.Name] = true
+		if result.err != nil {
+			failed = append(failed, result.cronJob.Name)
+		} else if result.metrics.Name != result.cronJob.Name {
+			t.Errorf("metrics of %s = %s", result.cronJob.Name, result.metrics.Name)
+		}
+		if got := len(result.warnings.lines); (result.cronJob.Name == "job-3") != (got == 1) {
+			t.Errorf("%s held back %d warnings", result.cronJob.Name, got)
+		}
+	})
+
+	if len(seen) != 20 {
+		t.Errorf("handled %d cronjobs, want 20", len(seen))
+	}
+	if len(failed) != 1 || failed[0] != "job-3" {
+		t.Errorf("failed = %v, want [job-3]", failed)
+	}
+	if got := peak.Load(); got < 2 || got > cronJobWorkers {
+		t.Errorf("peak concurrency = %d, want between 2 and %d", got, cronJobWorkers)
+	}
+}
+
+func TestLatestJobRuns(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	job := func(name string, hours int) batchv1.Job {
+		completed := metav1.NewTime(base.Add(time.Duration(hours) * time.Hour))
+		return batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     batchv1.JobStatus{CompletionTime: &completed},
+		}
+	}
+
+	runs := latestJobRuns([]batchv1.Job{job("a", 1), job("c", 3), job("b", 2)}, 2)
+	if len(runs) != 2 || runs[0].Name != "c" || runs[1].Name != "b" {
+		t.Errorf("latestJobRuns() = %v, %v, want c, b", runs[0].Name, runs[1].Name)
+	}
+}
+
+func TestCompletedJobsByOwner(t *testing.T) {
+	completed := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	job := func(name string, owner types.UID, done bool) batchv1.Job {
+		j := batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", UID: owner}},
+		}}
+		if done {
+			j.Status.CompletionTime = &completed
+		}
+		return j
+	}
+
+	byOwner := completedJobsByOwner([]batchv1.Job{
+		job("report-1", "abc", true),
+		job("report-2", "abc", false),
+		job("cleanup-1", "xyz", true),
+		{ObjectMeta: metav1.ObjectMeta{Name: "manual"}, Status: batchv1.JobStatus{CompletionTime: &completed}},
+	})
+	if got := byOwner["abc"]; len(got) != 1 || got[0].Name != "report-1" {
+		t.Errorf("jobs of abc = %v, want only the completed report-1", got)
+	}
+	if got := byOwner["xyz"]; len(got) != 1 || got[0].Name != "cleanup-1" {
+		t.Errorf("jobs of xyz = %v, want cleanup-1", got)
+	}
+	if len(byOwner) != 2 {
+		t.Errorf("len(byOwner) = %d, want 2, jobs without an owner left out", len(byOwner))
+	}
+}
+
+func TestStaticPodMetrics(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "kube-apiserver-cp1",
+			Namespace:   "kube-system",
+			Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "abc123"},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "kube-apiserver",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			}},
+		}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+
+	if !isMirrorPod(pod) {
+		t.Fatal("isMirrorPod() = false for a pod with the mirror annotation")
+	}
+	if isMirrorPod(corev1.Pod{}) {
+		t.Error("isMirrorPod() = true for a regular pod")
+	}
+
+	usage := &podUsageIndex{byNamespace: map[string]map[string]ResourceMetrics{
+		"kube-system": {"kube-apiserver-cp1": {CPU: 400, Memory: 600 << 20}},
+	}}
+	dm := staticPodMetrics(pod, usage)
+
+	if dm.Type != "StaticPod" || dm.CurrentReplicas != 1 || dm.MaxReplicas != 1 {
+		t.Errorf("staticPodMetrics() = %+v, want a running single-replica StaticPod", dm)
+	}
+	if dm.Requests.CPU != 250 || dm.MaxRequests != dm.Requests {
+		t.Errorf("staticPodMetrics() requests = %+v, max = %+v", dm.Requests, dm.MaxRequests)
+	}
+	if dm.Usage.CPU != 400 {
+		t.Errorf("staticPodMetrics() usage CPU = %d, want 400", dm.Usage.CPU)
+	}
+}
+
+func TestHPACPUTarget(t *testing.T) {
+	utilization := func(v int32) *int32 { return &v }
+
+	tests := []struct {
+		name          string
+		metrics       []autoscalingv2.MetricSpec
+		wantTarget    int32
+		wantContainer string
+	}{
+		{"no metrics", nil, 0, ""},
+		{
+			name: "pod cpu",
+			metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: utilization(70)}},
+			}},
+			wantTarget: 70,
+		},
+		{
+			name: "container cpu after memory",
+			metrics: []autoscalingv2.MetricSpec{
+				{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{Name: corev1.ResourceMemory,
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: utilization(80)}},
+				},
+				{
+					Type: autoscalingv2.ContainerResourceMetricSourceType,
+					ContainerResource: &autoscalingv2.ContainerResourceMetricSource{Name: corev1.ResourceCPU, Container: "app",
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: utilization(60)}},
+				},
+			},
+			wantTarget:    60,
+			wantContainer: "app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, container := hpaCPUTarget(tt.metrics)
+			if target != tt.wantTarget || container != tt.wantContainer {
+				t.Errorf("hpaCPUTarget() = %d, %q, want %d, %q", target, container, tt.wantTarget, tt.wantContainer)
+			}
+		})
+	}
+}
+
+func TestContainerRequests(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+		{Name: "proxy", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}},
+	}}}
+
+	if got := containerRequests(pod, "app").CPU; got != 500 {
+		t.Errorf("containerRequests(app) CPU = %d, want 500", got)
+	}
+	if got := podRequests(pod).CPU; got != 1500 {
+		t.Errorf("podRequests() CPU = %d, want 1500", got)
+	}
+}
+
+func TestWorkloadOS(t *testing.T) {
+	windowsAffinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"windows"}}},
+		}}},
+	}}
+
+	tests := []struct {
+		name string
+		spec corev1.PodSpec
+		want string
+	}{
+		{"unpinned", corev1.PodSpec{}, "linux"},
+		{"node selector", corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelOSStable: "windows"}}, "windows"},
+		{"required affinity", corev1.PodSpec{Affinity: windowsAffinity}, "windows"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := workloadOS(tt.spec); got != tt.want {
+				t.Errorf("workloadOS() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodRestarts(t *testing.T) {
+	pods := []corev1.Pod{
+		{Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{Name: "migrate", RestartCount: 2}},
+			ContainerStatuses:     []corev1.ContainerStatus{{Name: "app", RestartCount: 5}, {Name: "sidecar", RestartCount: 1}},
+		}},
+		{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: 3}}}},
+		{Status: corev1.PodStatus{Phase: corev1.PodPending}},
+	}
+	if got := podRestarts(pods); got != 11 {
+		t.Errorf("podRestarts() = %d, want 11", got)
+	}
+	if got := podRestarts(nil); got != 0 {
+		t.Errorf("podRestarts(nil) = %d, want 0", got)
+	}
+}
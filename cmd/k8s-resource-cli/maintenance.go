@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// drainedNode is a node to be rotated with the pods a drain evicts from it
+type drainedNode struct {
+	Name        string
+	Allocatable ResourceMetrics
+	Pods        int
+	Requests    ResourceMetrics
+}
+
+// pdbLimit is a PodDisruptionBudget with more of its pods on the drained
+// nodes than it lets go at once
+type pdbLimit struct {
+	Namespace string
+	Name      string
+	Allowed   int32
+	Evicted   int
+}
+
+// maintenancePlan is what rotating a set of nodes takes: the requests to
+// place elsewhere, the free requests the other nodes have for them, and what
+// stands in the way of draining
+type maintenancePlan struct {
+	Nodes      []drainedNode
+	Missing    []string // names in the nodes file the cluster doesn't have
+	Evicted    ResourceMetrics
+	Spare      ResourceMetrics // unrequested allocatable of the schedulable nodes that stay
+	Extra      ResourceMetrics // what the evicted pods need beyond Spare
+	ExtraNodes int             // nodes the size of the largest drained one that add Extra
+	PDBs       []pdbLimit
+	Unmanaged  []string // namespace/name of pods without a controller, deleted for good
+	LocalData  []string // namespace/name of pods whose emptyDir data is lost
+}
+
+// readNodesFile reads node names, one per line; blank lines and lines
+// starting with # are skipped
+func readNodesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// evictedByDrain reports whether draining the node evicts the pod: DaemonSet
+// pods stay and mirror pods can't be evicted
+func evictedByDrain(pod corev1.Pod) bool {
+	if isMirrorPod(pod) {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// planMaintenance works out the capacity needed to drain the named nodes all
+// at once. Evicted pods can only land on schedulable nodes that aren't drained
+// themselves, and only in allocatable no other pod requests; how they pack
+// is left to the scheduler, so the figures are a lower bound.
+func planMaintenance(nodes []corev1.Node, pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget, names []string) maintenancePlan {
+	var plan maintenancePlan
+	drained := make(map[string]*drainedNode)
+	byName := make(map[string]corev1.Node, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+	for _, name := range names {
+		node, ok := byName[name]
+		if !ok {
+			plan.Missing = append(plan.Missing, name)
+			continue
+		}
+		if _, ok := drained[name]; ok {
+			continue
+		}
+		plan.Nodes = append(plan.Nodes, drainedNode{Name: name, Allocatable: ResourceMetrics{
+			CPU:    node.Status.Allocatable.Cpu().MilliValue(),
+			Memory: node.Status.Allocatable.Memory().Value(),
+		}})
+		drained[name] = nil
+	}
+	for i := range plan.Nodes {
+		drained[plan.Nodes[i].Name] = &plan.Nodes[i]
+	}
+
+	requested := make(map[string]ResourceMetrics)
+	var evicted []corev1.Pod
+	for _, pod := range pods {
+		podReq := podRequests(pod)
+		used := requested[pod.Spec.NodeName]
+		used.add(podReq)
+		requested[pod.Spec.NodeName] = used
+
+		node := drained[pod.Spec.NodeName]
+		if node == nil || !evictedByDrain(pod) {
+			continue
+		}
+		node.Pods++
+		node.Requests.add(podReq)
+		plan.Evicted.add(podReq)
+		evicted = append(evicted, pod)
+
+		key := pod.Namespace + "/" + pod.Name
+		if metav1.GetControllerOf(&pod) == nil {
+			plan.Unmanaged = append(plan.Unmanaged, key)
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				plan.LocalData = append(plan.LocalData, key)
+				break
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		if _, ok := drained[node.Name]; ok || node.Spec.Unschedulable || !nodeReady(node) {
+			continue
+		}
+		used := requested[node.Name]
+		plan.Spare.add(ResourceMetrics{
+			CPU:    max(node.Status.Allocatable.Cpu().MilliValue()-used.CPU, 0),
+			Memory: max(node.Status.Allocatable.Memory().Value()-used.Memory, 0),
+		})
+	}
+	plan.Extra = ResourceMetrics{CPU: max(plan.Evicted.CPU-plan.Spare.CPU, 0), Memory: max(plan.Evicted.Memory-plan.Spare.Memory, 0)}
+
+	var largest ResourceMetrics
+	for _, node := range plan.Nodes {
+		if node.Allocatable.CPU > largest.CPU || (node.Allocatable.CPU == largest.CPU && node.Allocatable.Memory > largest.Memory) {
+			largest = node.Allocatable
+		}
+	}
+	if largest.CPU > 0 && largest.Memory > 0 {
+		plan.ExtraNodes = int(math.Ceil(max(float64(plan.Extra.CPU)/float64(largest.CPU), float64(plan.Extra.Memory)/float64(largest.Memory))))
+	}
+
+	for _, pdb := range pdbs {
+		// A nil selector matches no pods, but an empty one matches every
+		// pod in the namespace
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		count := 0
+		for _, pod := range evicted {
+			if pod.Namespace == pdb.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+				count++
+			}
+		}
+		if count > int(pdb.Status.DisruptionsAllowed) {
+			plan.PDBs = append(plan.PDBs, pdbLimit{Namespace: pdb.Namespace, Name: pdb.Name, Allowed: pdb.Status.DisruptionsAllowed, Evicted: count})
+		}
+	}
+	sort.Slice(plan.PDBs, func(i, j int) bool {
+		if plan.PDBs[i].Namespace != plan.PDBs[j].Namespace {
+			return plan.PDBs[i].Namespace < plan.PDBs[j].Namespace
+		}
+		return plan.PDBs[i].Name < plan.PDBs[j].Name
+	})
+	sort.Strings(plan.Unmanaged)
+	sort.Strings(plan.LocalData)
+	return plan
+}
+
+// nodeReady reports whether the node's Ready condition is true
+func nodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// runMaintenanceCommand implements the "maintenance" subcommand: the extra
+// capacity needed to drain the nodes in a file, and a checklist for it
+func runMaintenanceCommand(args []string) {
+	var kubeconfig string
+	var kubeContext string
+	var nodesFile string
+
+	fs := flag.NewFlagSet("maintenance", flag.ContinueOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	fs.StringVar(&nodesFile, "nodes-file", "", "File with the names of the nodes to rotate, one per line (required)")
+	parseFlags(fs, args)
+
+	if nodesFile == "" || fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Usage: k8s-resource-cli maintenance --nodes-file nodes.txt\n")
+		os.Exit(exitUsage)
+	}
+	names, err := readNodesFile(nodesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading nodes file: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if len(names) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s names no nodes\n", nodesFile)
+		os.Exit(exitUsage)
+	}
+
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+	var pods []corev1.Pod
+	if err := forEachScheduledPod(ctx, clientset, func(pod corev1.Pod) { pods = append(pods, pod) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pod disruption budgets: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+
+	plan := planMaintenance(nodes.Items, pods, pdbs.Items, names)
+	for _, name := range plan.Missing {
+		partialf("node %s from %s not found, leaving it out", name, nodesFile)
+	}
+	if len(plan.Nodes) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: none of the nodes in %s exist\n", nodesFile)
+		os.Exit(exitUsage)
+	}
+	printMaintenancePlan(os.Stdout, plan)
+	os.Exit(runExitStatus(false, partialResults))
+}
+
+func printMaintenancePlan(out io.Writer, plan maintenancePlan) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "NODE\tPODS\tCPU REQUESTS\tMEMORY REQUESTS\n")
+	pods := 0
+	for _, node := range plan.Nodes {
+		pods += node.Pods
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", node.Name, node.Pods, formatCPU(node.Requests.CPU), formatMemory(node.Requests.Memory))
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%s\t%s\n", pods, formatCPU(plan.Evicted.CPU), formatMemory(plan.Evicted.Memory))
+	w.Flush()
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Free on the remaining nodes: %s CPU, %s memory\n", formatCPU(plan.Spare.CPU), formatMemory(plan.Spare.Memory))
+	if plan.Extra.CPU > 0 || plan.Extra.Memory > 0 {
+		fmt.Fprintf(out, "Extra capacity needed: %s CPU, %s memory\n", formatCPU(plan.Extra.CPU), formatMemory(plan.Extra.Memory))
+		fmt.Fprintf(out, "Nodes to add, sized like the largest drained one: %d\n", plan.ExtraNodes)
+	} else {
+		fmt.Fprintln(out, "Extra capacity needed: none")
+	}
+
+	var names []string
+	for _, node := range plan.Nodes {
+		names = append(names, node.Name)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Checklist:")
+	if plan.Extra.CPU > 0 || plan.Extra.Memory > 0 {
+		fmt.Fprintf(out, "- [ ] Add capacity for %s CPU and %s memory and wait until it is Ready\n", formatCPU(plan.Extra.CPU), formatMemory(plan.Extra.Memory))
+	}
+	fmt.Fprintf(out, "- [ ] Cordon the nodes: kubectl cordon %s\n", strings.Join(names, " "))
+	for _, pdb := range plan.PDBs {
+		if pdb.Allowed == 0 {
+			fmt.Fprintf(out, "- [ ] PodDisruptionBudget %s/%s allows no disruptions now; scale up its pods or relax it, or the drain hangs on its pods here (%d)\n",
+				pdb.Namespace, pdb.Name, pdb.Evicted)
+		} else {
+			fmt.Fprintf(out, "- [ ] PodDisruptionBudget %s/%s allows %d disruptions but %d of its pods are on these nodes; drain them one at a time, waiting for replacements to be Ready\n",
+				pdb.Namespace, pdb.Name, pdb.Allowed, pdb.Evicted)
+		}
+	}
+	if len(plan.Unmanaged) > 0 {
+		fmt.Fprintf(out, "- [ ] Pods without a controller are deleted and not recreated (drain needs --force): %s\n", strings.Join(plan.Unmanaged, ", "))
+	}
+	if len(plan.LocalData) > 0 {
+		fmt.Fprintf(out, "- [ ] Pods lose their emptyDir data (drain needs --delete-emptydir-data): %s\n", strings.Join(plan.LocalData, ", "))
+	}
+	fmt.Fprintf(out, "- [ ] Drain each node: kubectl drain <node> --ignore-daemonsets\n")
+	fmt.Fprintf(out, "- [ ] Check nothing is left Pending: kubectl get pods -A --field-selector status.phase=Pending\n")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testMaintenanceNode(name, cpu, memory string, ready bool) corev1.Node {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory)},
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func testMaintenancePod(namespace, name, node, owner, cpu, memory string, labels map[string]string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec: corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory)},
+		}}}},
+	}
+	if owner != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: name, Controller: &controller}}
+	}
+	return pod
+}
+
+func TestPlanMaintenance(t *testing.T) {
+	nodes := []corev1.Node{
+		testMaintenanceNode("a", "4", "8Gi", true),
+		testMaintenanceNode("b", "4", "8Gi", true),
+		testMaintenanceNode("c", "4", "8Gi", true),
+		testMaintenanceNode("down", "4", "8Gi", false),
+	}
+	web := map[string]string{"app": "web"}
+	pods := []corev1.Pod{
+		testMaintenancePod("shop", "web-1", "a", "ReplicaSet", "2", "2Gi", web),
+		testMaintenancePod("shop", "web-2", "b", "ReplicaSet", "2", "2Gi", web),
+		testMaintenancePod("kube-system", "agent-a", "a", "DaemonSet", "500m", "1Gi", nil),
+		testMaintenancePod("kube-system", "agent-b", "b", "DaemonSet", "500m", "1Gi", nil),
+		testMaintenancePod("shop", "debug", "b", "", "1", "1Gi", nil),
+		testMaintenancePod("shop", "cache", "c", "ReplicaSet", "3", "4Gi", nil),
+	}
+	pdbs := []policyv1.PodDisruptionBudget{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: web}},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "everything"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 2},
+	}, {
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "nothing"},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0},
+	}}
+
+	plan := planMaintenance(nodes, pods, pdbs, []string{"a", "b", "a", "gone"})
+	want := []drainedNode{
+		{Name: "a", Allocatable: ResourceMetrics{CPU: 4000, Memory: 8 << 30}, Pods: 1, Requests: ResourceMetrics{CPU: 2000, Memory: 2 << 30}},
+		{Name: "b", Allocatable: ResourceMetrics{CPU: 4000, Memory: 8 << 30}, Pods: 2, Requests: ResourceMetrics{CPU: 3000, Memory: 3 << 30}},
+	}
+	if !reflect.DeepEqual(plan.Nodes, want) {
+		t.Errorf("Nodes = %+v, want %+v", plan.Nodes, want)
+	}
+	if !reflect.DeepEqual(plan.Missing, []string{"gone"}) {
+		t.Errorf("Missing = %v, want [gone]", plan.Missing)
+	}
+	// Only c takes pods: the other node isn't Ready
+	if plan.Spare != (ResourceMetrics{CPU: 1000, Memory: 4 << 30}) {
+		t.Errorf("Spare = %+v", plan.Spare)
+	}
+	if plan.Extra != (ResourceMetrics{CPU: 4000, Memory: 1 << 30}) {
+		t.Errorf("Extra = %+v", plan.Extra)
+	}
+	if plan.ExtraNodes != 1 {
+		t.Errorf("ExtraNodes = %d, want 1", plan.ExtraNodes)
+	}
+	// An empty selector covers every pod of the namespace, a nil one none
+	if !reflect.DeepEqual(plan.PDBs, []pdbLimit{{Namespace: "shop", Name: "everything", Allowed: 2, Evicted: 3}, {Namespace: "shop", Name: "web", Allowed: 1, Evicted: 2}}) {
+		t.Errorf("PDBs = %+v", plan.PDBs)
+	}
+	if !reflect.DeepEqual(plan.Unmanaged, []string{"shop/debug"}) {
+		t.Errorf("Unmanaged = %v, want [shop/debug]", plan.Unmanaged)
+	}
+}
+
+func TestPrintMaintenancePlan(t *testing.T) {
+	plan := maintenancePlan{
+		Nodes:      []drainedNode{{Name: "a", Pods: 2, Requests: ResourceMetrics{CPU: 2000, Memory: 2097152}}},
+		Evicted:    ResourceMetrics{CPU: 2000, Memory: 2097152},
+		Spare:      ResourceMetrics{CPU: 1000, Memory: 4194304},
+		Extra:      ResourceMetrics{CPU: 1000},
+		ExtraNodes: 1,
+		PDBs:       []pdbLimit{{Namespace: "shop", Name: "web", Allowed: 0, Evicted: 2}},
+	}
+	var buf bytes.Buffer
+	printMaintenancePlan(&buf, plan)
+	want := "NODE    PODS   CPU REQUESTS   MEMORY REQUESTS\n" +
+		"a       2      2.00 cores     2.00 MB\n" +
+		"TOTAL   2      2.00 cores     2.00 MB\n" +
+		"\n" +
+		"Free on the remaining nodes: 1.00 cores CPU, 4.00 MB memory\n" +
+		"Extra capacity needed: 1.00 cores CPU, 0 B memory\n" +
+		"Nodes to add, sized like the largest drained one: 1\n" +
+		"\n" +
+		"Checklist:\n" +
+		"- [ ] Add capacity for 1.00 cores CPU and 0 B memory and wait until it is Ready\n" +
+		"- [ ] Cordon the nodes: kubectl cordon a\n" +
+		"- [ ] PodDisruptionBudget shop/web allows no disruptions now; scale up its pods or relax it, or the drain hangs on its pods here (2)\n" +
+		"- [ ] Drain each node: kubectl drain <node> --ignore-daemonsets\n" +
+		"- [ ] Check nothing is left Pending: kubectl get pods -A --field-selector status.phase=Pending\n"
+	if buf.String() != want {
+		t.Errorf("printMaintenancePlan() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestReadNodesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.txt")
+	if err := os.WriteFile(path, []byte("# pool a\nnode-1\n\n  node-2  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names, err := readNodesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "node-1,node-2" {
+		t.Errorf("readNodesFile() = %v, want [node-1 node-2]", names)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// runMetadata describes how a report was produced, so archived structured
+// output can be traced back to the cluster and command line that made it
+type runMetadata struct {
+	GeneratedAt     time.Time         `json:"generated_at"`
+	Version         string            `json:"version"`
+	Context         string            `json:"context,omitempty"`
+	Cluster         string            `json:"cluster,omitempty"`
+	PorterProjectID string            `json:"porter_project_id,omitempty"`
+	Flags           map[string]string `json:"flags"`
+}
+
+// secretFlags are never copied into run metadata
+var secretFlags = map[string]bool{
+	"porter-token":  true,
+	"porter-header": true, // may carry auth proxy credentials
+}
+
+// explicitFlags returns the flags set on the command line, minus secrets
+func explicitFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		if !secretFlags[f.Name] {
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	return flags
+}
+
+// kubeconfigContextCluster returns the context in use and the cluster it
+// points at, or empty strings when the kubeconfig can't be read (e.g. in-cluster)
+func kubeconfigContextCluster(kubeconfigPath, kubeContext string) (string, string) {
+	config, err := loadKubeconfig(kubeconfigPath)
+	if err != nil {
+		return "", ""
+	}
+
+	contextName := config.CurrentContext
+	if kubeContext != "" {
+		contextName = kubeContext
+	}
+	if context, ok := config.Contexts[contextName]; ok {
+		return contextName, context.Cluster
+	}
+	return contextName, ""
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestExplicitFlags(t *testing.T) {
+	var namespace, token, output string
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&namespace, "namespace", "", "")
+	fs.StringVar(&token, "porter-token", "", "")
+	fs.StringVar(&output, "output", "requests", "")
+	if err := fs.Parse([]string{"--namespace", "prod", "--porter-token", "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := explicitFlags(fs)
+	if flags["namespace"] != "prod" {
+		t.Errorf("flags[namespace] = %v, want prod", flags["namespace"])
+	}
+	if _, ok := flags["porter-token"]; ok {
+		t.Error("porter-token must not be recorded")
+	}
+	if _, ok := flags["output"]; ok {
+		t.Error("flags left at their default should not be recorded")
+	}
+}
+
+func TestKubeconfigContextCluster(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.CurrentContext = "prod"
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod-cluster"}
+	config.Contexts["staging"] = &clientcmdapi.Context{Cluster: "staging-cluster"}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx, cluster := kubeconfigContextCluster(path, ""); ctx != "prod" || cluster != "prod-cluster" {
+		t.Errorf("kubeconfigContextCluster() = %v, %v, want prod, prod-cluster", ctx, cluster)
+	}
+	if ctx, cluster := kubeconfigContextCluster(path, "staging"); ctx != "staging" || cluster != "staging-cluster" {
+		t.Errorf("kubeconfigContextCluster(staging) = %v, %v, want staging, staging-cluster", ctx, cluster)
+	}
+	if ctx, cluster := kubeconfigContextCluster(filepath.Join(t.TempDir(), "missing"), ""); ctx != "" || cluster != "" {
+		t.Errorf("kubeconfigContextCluster() on missing file = %v, %v, want empty", ctx, cluster)
+	}
+}
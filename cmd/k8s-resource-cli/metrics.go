@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+const (
+	// metricsShardWorkers bounds how many namespaces are queried at once
+	metricsShardWorkers = 8
+	// metricsShardAttempts is how often a failing namespace is tried before giving up
+	metricsShardAttempts = 3
+)
+
+// metricsRetryDelay is the backoff unit between attempts, multiplied by the attempt number
+var metricsRetryDelay = 500 * time.Millisecond
+
+// podUsageIndex maps namespace and pod name to current usage. It is filled
+// once per run so workloads look up their pods locally instead of each
+// issuing its own metrics query. A nil index reports no usage.
+type podUsageIndex struct {
+	byNamespace map[string]map[string]ResourceMetrics
+}
+
+// Pod returns the usage of one pod and whether metrics-server reported it
+func (idx *podUsageIndex) Pod(namespace, name string) (ResourceMetrics, bool) {
+	if idx == nil {
+		return ResourceMetrics{}, false
+	}
+	usage, ok := idx.byNamespace[namespace][name]
+	return usage, ok
+}
+
+// loadPodUsage lists pod metrics for the namespace, or for every namespace
+// when it is empty. Cluster-wide queries are sharded per namespace and run in
+// parallel so no single metrics-server response has to hold every pod;
+// namespaces that still fail after retries are reported and left out.
+func loadPodUsage(ctx context.Context, clientset *kubernetes.Clientset, metricsClientset *versioned.Clientset, namespace string) *podUsageIndex {
+	namespaces := []string{namespace}
+	if namespace == "" {
+		var err error
+		namespaces, err = listNamespaceNames(ctx, clientset)
+		if err != nil {
+			// Listing namespaces takes a cluster-scoped permission that a
+			// single cluster-wide metrics query doesn't, so fall back to one
+			debugf(verbosityDecisions, "Error listing namespaces for pod metrics (%v), querying all pod metrics at once", err)
+			byNamespace, err := listClusterPodUsage(ctx, metricsClientset)
+			if err != nil {
+				partialf("Error getting pod metrics: %v", err)
+				return &podUsageIndex{}
+			}
+			return &podUsageIndex{byNamespace: byNamespace}
+		}
+	}
+
+	byNamespace, failed := shardByNamespace(ctx, namespaces, func(ctx context.Context, ns string) (map[string]ResourceMetrics, error) {
+		return listNamespacePodUsage(ctx, metricsClientset, ns)
+	})
+
+	for _, ns := range sortedKeys(failed) {
+		partialf("Error getting pod metrics for namespace %s: %v", ns, failed[ns])
+	}
+
+	return &podUsageIndex{byNamespace: byNamespace}
+}
+
+// shardByNamespace runs fetch for every namespace on a bounded worker pool,
+// retrying each failing namespace with a linear backoff. It returns the
+// merged results and the last error of every namespace that never succeeded.
+func shardByNamespace(ctx context.Context, namespaces []string, fetch func(context.Context, string) (map[string]ResourceMetrics, error)) (map[string]map[string]ResourceMetrics, map[string]error) {
+	results := make(map[string]map[string]ResourceMetrics)
+	failed := make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+
+	workers := metricsShardWorkers
+	if len(namespaces) < workers {
+		workers = len(namespaces)
+	}
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ns := range queue {
+				usage, err := fetchWithRetry(ctx, ns, fetch)
+				mu.Lock()
+				if err != nil {
+					failed[ns] = err
+				} else {
+					results[ns] = usage
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, ns := range namespaces {
+		queue <- ns
+	}
+	close(queue)
+	wg.Wait()
+
+	return results, failed
+}
+
+func fetchWithRetry(ctx context.Context, namespace string, fetch func(context.Context, string) (map[string]ResourceMetrics, error)) (map[string]ResourceMetrics, error) {
+	var err error
+	for attempt := 1; attempt <= metricsShardAttempts; attempt++ {
+		var usage map[string]ResourceMetrics
+		usage, err = fetch(ctx, namespace)
+		if err == nil {
+			return usage, nil
+		}
+		if attempt == metricsShardAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(attempt) * metricsRetryDelay):
+		}
+	}
+	return nil, err
+}
+
+func listNamespacePodUsage(ctx context.Context, metricsClientset *versioned.Clientset, namespace string) (map[string]ResourceMetrics, error) {
+	podMetricsList, err := metricsClientset.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]ResourceMetrics, len(podMetricsList.Items))
+	for _, podMetrics := range podMetricsList.Items {
+		usage[podMetrics.Name] = podMetricsUsage(podMetrics)
+	}
+
+	return usage, nil
+}
+
+// listClusterPodUsage lists the pod metrics of every namespace in one query
+func listClusterPodUsage(ctx context.Context, metricsClientset *versioned.Clientset) (map[string]map[string]ResourceMetrics, error) {
+	podMetricsList, err := metricsClientset.MetricsV1beta1().PodMetricses("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	byNamespace := make(map[string]map[string]ResourceMetrics)
+	for _, podMetrics := range podMetricsList.Items {
+		if byNamespace[podMetrics.Namespace] == nil {
+			byNamespace[podMetrics.Namespace] = make(map[string]ResourceMetrics)
+		}
+		byNamespace[podMetrics.Namespace][podMetrics.Name] = podMetricsUsage(podMetrics)
+	}
+
+	return byNamespace, nil
+}
+
+// podMetricsUsage sums the usage of a pod's containers
+func podMetricsUsage(podMetrics metricsv1beta1.PodMetrics) ResourceMetrics {
+	var pod ResourceMetrics
+	for _, container := range podMetrics.Containers {
+		if cpu := container.Usage.Cpu(); cpu != nil {
+			pod.CPU += cpu.MilliValue()
+		}
+		if memory := container.Usage.Memory(); memory != nil {
+			pod.Memory += memory.Value()
+		}
+	}
+	return pod
+}
+
+func listNamespaceNames(ctx context.Context, clientset *kubernetes.Clientset) ([]string, error) {
+	var names []string
+	listOptions := metav1.ListOptions{Limit: listPageSize}
+	for {
+		namespaceList, err := clientset.CoreV1().Namespaces().List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range namespaceList.Items {
+			names = append(names, ns.Name)
+		}
+		if namespaceList.Continue == "" {
+			break
+		}
+		listOptions.Continue = namespaceList.Continue
+	}
+	return names, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// kubeletSummary is the part of the kubelet /stats/summary response needed
+// for pod usage. Memory uses the working set, as metrics-server does.
+type kubeletSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Containers []struct {
+			CPU *struct {
+				UsageNanoCores *uint64 `json:"usageNanoCores"`
+			} `json:"cpu"`
+			Memory *struct {
+				WorkingSetBytes *uint64 `json:"workingSetBytes"`
+			} `json:"memory"`
+		} `json:"containers"`
+	} `json:"pods"`
+}
+
+// loadKubeletPodUsage builds the usage index from each node's kubelet Summary
+// API, reached through the API server node proxy. It stands in for
+// metrics-server on minimal clusters that don't run it.
+func loadKubeletPodUsage(ctx context.Context, clientset *kubernetes.Clientset, namespace string) *podUsageIndex {
+	idx := &podUsageIndex{byNamespace: make(map[string]map[string]ResourceMetrics)}
+
+	var nodes []string
+	listOptions := metav1.ListOptions{Limit: listPageSize}
+	for {
+		nodeList, err := clientset.CoreV1().Nodes().List(ctx, listOptions)
+		if err != nil {
+			partialf("Error listing nodes for kubelet stats: %v", err)
+			return idx
+		}
+		for _, node := range nodeList.Items {
+			nodes = append(nodes, node.Name)
+		}
+		if nodeList.Continue == "" {
+			break
+		}
+		listOptions.Continue = nodeList.Continue
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, metricsShardWorkers)
+	failed := make(map[string]error)
+
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			data, err := clientset.CoreV1().RESTClient().Get().
+				AbsPath("/api/v1/nodes", node, "proxy", "stats", "summary").
+				DoRaw(ctx)
+			if err == nil {
+				err = mergeKubeletSummary(idx, data, namespace, &mu)
+			}
+			if err != nil {
+				mu.Lock()
+				failed[node] = err
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	for _, node := range sortedKeys(failed) {
+		partialf("Error getting kubelet stats for node %s: %v", node, failed[node])
+	}
+
+	return idx
+}
+
+// mergeKubeletSummary adds the pods of one node summary to the index,
+// keeping only the given namespace unless it is empty
+func mergeKubeletSummary(idx *podUsageIndex, data []byte, namespace string, mu *sync.Mutex) error {
+	var summary kubeletSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return fmt.Errorf("invalid summary response: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, pod := range summary.Pods {
+		if namespace != "" && pod.PodRef.Namespace != namespace {
+			continue
+		}
+		var usage ResourceMetrics
+		for _, container := range pod.Containers {
+			if container.CPU != nil && container.CPU.UsageNanoCores != nil {
+				usage.CPU += int64(*container.CPU.UsageNanoCores / 1000000)
+			}
+			if container.Memory != nil && container.Memory.WorkingSetBytes != nil {
+				usage.Memory += int64(*container.Memory.WorkingSetBytes)
+			}
+		}
+		if idx.byNamespace[pod.PodRef.Namespace] == nil {
+			idx.byNamespace[pod.PodRef.Namespace] = make(map[string]ResourceMetrics)
+		}
+		idx.byNamespace[pod.PodRef.Namespace][pod.PodRef.Name] = usage
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+func TestShardByNamespace(t *testing.T) {
+	metricsRetryDelay = 0
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	fetch := func(ctx context.Context, ns string) (map[string]ResourceMetrics, error) {
+		mu.Lock()
+		calls[ns]++
+		n := calls[ns]
+		mu.Unlock()
+
+		switch ns {
+		case "flaky":
+			if n < 2 {
+				return nil, errors.New("the server is currently unable to handle the request")
+			}
+		case "broken":
+			return nil, errors.New("response too large")
+		}
+		return map[string]ResourceMetrics{ns + "-pod": {CPU: 100, Memory: 1024}}, nil
+	}
+
+	results, failed := shardByNamespace(context.Background(), []string{"prod", "flaky", "broken"}, fetch)
+
+	if len(results) != 2 {
+		t.Errorf("len(results) = %d, want 2", len(results))
+	}
+	if results["flaky"]["flaky-pod"].CPU != 100 {
+		t.Errorf("flaky namespace should succeed on retry, got %v", results["flaky"])
+	}
+	if _, ok := failed["broken"]; !ok {
+		t.Error("broken namespace should be reported as failed")
+	}
+	if calls["broken"] != metricsShardAttempts {
+		t.Errorf("broken namespace tried %d times, want %d", calls["broken"], metricsShardAttempts)
+	}
+	if calls["prod"] != 1 {
+		t.Errorf("prod namespace tried %d times, want 1", calls["prod"])
+	}
+}
+
+func TestLoadPodUsageWithoutNamespaceList(t *testing.T) {
+	podMetrics := metricsv1beta1.PodMetricsList{Items: []metricsv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "shop"},
+			Containers: []metricsv1beta1.ContainerMetrics{{Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("120m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "billing"},
+			Containers: []metricsv1beta1.ContainerMetrics{{Usage: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("30m")}}},
+		},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonForbidden, Code: http.StatusForbidden})
+		case "/apis/metrics.k8s.io/v1beta1/pods":
+			json.NewEncoder(w).Encode(podMetrics)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metricsClientset, err := versioned.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := loadPodUsage(context.Background(), clientset, metricsClientset, "")
+	if got, ok := idx.Pod("shop", "web-1"); !ok || got != (ResourceMetrics{CPU: 120, Memory: 64 << 20}) {
+		t.Errorf("Pod(shop, web-1) = %+v, %v, want CPU 120, Memory 64Mi", got, ok)
+	}
+	if got, ok := idx.Pod("billing", "api-1"); !ok || got.CPU != 30 {
+		t.Errorf("Pod(billing, api-1) = %+v, %v, want CPU 30", got, ok)
+	}
+}
+
+func TestPodUsageIndex(t *testing.T) {
+	idx := &podUsageIndex{byNamespace: map[string]map[string]ResourceMetrics{
+		"prod": {"web-1": {CPU: 250, Memory: 2048}},
+	}}
+
+	if got, ok := idx.Pod("prod", "web-1"); !ok || got.CPU != 250 {
+		t.Errorf("Pod(prod, web-1) = %v, %v, want CPU 250", got, ok)
+	}
+	if _, ok := idx.Pod("staging", "web-1"); ok {
+		t.Error("Pod() in an unknown namespace should not be found")
+	}
+
+	var empty *podUsageIndex
+	if _, ok := empty.Pod("prod", "web-1"); ok {
+		t.Error("nil index should report no usage")
+	}
+}
+
+func TestMergeKubeletSummary(t *testing.T) {
+	data := []byte(`{"node":{"nodeName":"node-1"},"pods":[
+		{"podRef":{"name":"web-1","namespace":"prod"},"containers":[
+			{"name":"app","cpu":{"usageNanoCores":150000000},"memory":{"workingSetBytes":1048576}},
+			{"name":"sidecar","cpu":{"usageNanoCores":50000000},"memory":{"workingSetBytes":524288}}
+		]},
+		{"podRef":{"name":"job-1","namespace":"batch"},"containers":[
+			{"name":"run","cpu":{"usageNanoCores":1000000000}}
+		]}
+	]}`)
+
+	var mu sync.Mutex
+	idx := &podUsageIndex{byNamespace: make(map[string]map[string]ResourceMetrics)}
+	if err := mergeKubeletSummary(idx, data, "prod", &mu); err != nil {
+		t.Fatalf("mergeKubeletSummary() error = %v", err)
+	}
+
+	got, ok := idx.Pod("prod", "web-1")
+	if !ok {
+		t.Fatal("web-1 should be in the index")
+	}
+	if got.CPU != 200 || got.Memory != 1572864 {
+		t.Errorf("web-1 usage = %+v, want CPU 200, Memory 1572864", got)
+	}
+	if _, ok := idx.Pod("batch", "job-1"); ok {
+		t.Error("pods outside the namespace should be skipped")
+	}
+
+	if err := mergeKubeletSummary(idx, []byte("not json"), "", &mu); err == nil {
+		t.Error("mergeKubeletSummary() on invalid JSON should return an error")
+	}
+}
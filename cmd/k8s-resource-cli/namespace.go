@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// budgetFile is the namespace init budget definition, YAML or JSON.
+// Quantities are Kubernetes quantities, e.g. 500m or 16Gi.
+type budgetFile struct {
+	Namespace    string `json:"namespace"`
+	CPU          string `json:"cpu"`    // total CPU requests
+	Memory       string `json:"memory"` // total memory requests
+	LimitsCPU    string `json:"limitsCpu"`
+	LimitsMemory string `json:"limitsMemory"`
+	Pods         string `json:"pods"`
+	// DefaultRequest and DefaultLimit are given to containers that set none
+	DefaultRequest struct {
+		CPU    string `json:"cpu"`
+		Memory string `json:"memory"`
+	} `json:"defaultRequest"`
+	DefaultLimit struct {
+		CPU    string `json:"cpu"`
+		Memory string `json:"memory"`
+	} `json:"defaultLimit"`
+}
+
+// budgetObjects are the manifests a budget becomes; limitRange is nil when
+// the budget sets no container defaults
+type budgetObjects struct {
+	namespace  corev1.Namespace
+	quota      corev1.ResourceQuota
+	limitRange *corev1.LimitRange
+}
+
+// loadBudget reads a budget definition
+func loadBudget(path string) (budgetFile, error) {
+	var budget budgetFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return budget, err
+	}
+	if err := yaml.Unmarshal(data, &budget); err != nil {
+		return budget, fmt.Errorf("invalid budget file %s: %w", path, err)
+	}
+	return budget, nil
+}
+
+// budgetResources parses the quantities that are set into a resource list
+func budgetResources(values map[corev1.ResourceName]string) (corev1.ResourceList, error) {
+	list := corev1.ResourceList{}
+	for name, value := range values {
+		if value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': %w", name, value, err)
+		}
+		list[name] = quantity
+	}
+	return list, nil
+}
+
+// buildBudgetObjects turns a budget into a Namespace, a ResourceQuota on
+// requests.cpu and requests.memory, the keys reports and forecast read
+// quotas from, and a LimitRange with the container defaults
+func buildBudgetObjects(budget budgetFile) (budgetObjects, error) {
+	var objects budgetObjects
+	if budget.Namespace == "" {
+		return objects, fmt.Errorf("the budget has no namespace")
+	}
+	if budget.CPU == "" && budget.Memory == "" {
+		return objects, fmt.Errorf("the budget for %s sets neither cpu nor memory", budget.Namespace)
+	}
+
+	hard, err := budgetResources(map[corev1.ResourceName]string{
+		corev1.ResourceRequestsCPU:    budget.CPU,
+		corev1.ResourceRequestsMemory: budget.Memory,
+		corev1.ResourceLimitsCPU:      budget.LimitsCPU,
+		corev1.ResourceLimitsMemory:   budget.LimitsMemory,
+		corev1.ResourcePods:           budget.Pods,
+	})
+	if err != nil {
+		return objects, err
+	}
+	defaultRequest, err := budgetResources(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    budget.DefaultRequest.CPU,
+		corev1.ResourceMemory: budget.DefaultRequest.Memory,
+	})
+	if err != nil {
+		return objects, fmt.Errorf("defaultRequest: %w", err)
+	}
+	defaultLimit, err := budgetResources(map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    budget.DefaultLimit.CPU,
+		corev1.ResourceMemory: budget.DefaultLimit.Memory,
+	})
+	if err != nil {
+		return objects, fmt.Errorf("defaultLimit: %w", err)
+	}
+
+	labels := map[string]string{"app.kubernetes.io/managed-by": "k8s-resource-cli"}
+	objects.namespace = corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: budget.Namespace, Labels: labels},
+	}
+	objects.quota = corev1.ResourceQuota{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ResourceQuota"},
+		ObjectMeta: metav1.ObjectMeta{Name: "budget", Namespace: budget.Namespace, Labels: labels},
+		Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	if len(defaultRequest) > 0 || len(defaultLimit) > 0 {
+		item := corev1.LimitRangeItem{Type: corev1.LimitTypeContainer}
+		if len(defaultRequest) > 0 {
+			item.DefaultRequest = defaultRequest
+		}
+		if len(defaultLimit) > 0 {
+			item.Default = defaultLimit
+		}
+		objects.limitRange = &corev1.LimitRange{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "LimitRange"},
+			ObjectMeta: metav1.ObjectMeta{Name: "budget-defaults", Namespace: budget.Namespace, Labels: labels},
+			Spec:       corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{item}},
+		}
+	}
+	return objects, nil
+}
+
+// writeBudgetObjects writes the manifests as one multi-document YAML stream
+func writeBudgetObjects(out io.Writer, objects budgetObjects) error {
+	manifests := []interface{}{objects.namespace, objects.quota}
+	if objects.limitRange != nil {
+		manifests = append(manifests, objects.limitRange)
+	}
+	for i, manifest := range manifests {
+		data, err := yaml.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		out.Write(data)
+	}
+	return nil
+}
+
+// runNamespaceCommand implements the "namespace" subcommand. "namespace init"
+// prints the Namespace, ResourceQuota and LimitRange for a budget definition,
+// ready for kubectl apply -f -.
+func runNamespaceCommand(args []string) {
+	if len(args) == 0 || args[0] != "init" {
+		fmt.Fprintf(os.Stderr, "Usage: k8s-resource-cli namespace init --budget budget.yaml [namespace]\n")
+		os.Exit(exitUsage)
+	}
+
+	var budgetPath string
+	fs := flag.NewFlagSet("namespace init", flag.ContinueOnError)
+	fs.StringVar(&budgetPath, "budget", "", "Budget definition file, YAML or JSON (required)")
+	parseFlags(fs, args[1:])
+
+	if budgetPath == "" || fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: k8s-resource-cli namespace init --budget budget.yaml [namespace]\n")
+		os.Exit(exitUsage)
+	}
+
+	budget, err := loadBudget(budgetPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading budget: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if fs.NArg() == 1 {
+		budget.Namespace = fs.Arg(0)
+	}
+
+	objects, err := buildBudgetObjects(budget)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if objects.limitRange == nil {
+		warnf("the budget sets no container defaults, so the quota will reject pods whose containers set no requests")
+	}
+	if err := writeBudgetObjects(os.Stdout, objects); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+}
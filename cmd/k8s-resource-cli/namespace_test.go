@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestBuildBudgetObjects(t *testing.T) {
+	var budget budgetFile
+	budget.Namespace = "payments"
+	budget.CPU = "8"
+	budget.Memory = "16Gi"
+	budget.DefaultRequest.CPU = "100m"
+
+	objects, err := buildBudgetObjects(budget)
+	if err != nil {
+		t.Fatalf("buildBudgetObjects() error = %v", err)
+	}
+	hard := objects.quota.Spec.Hard
+	if cpu := hard[corev1.ResourceRequestsCPU]; cpu.MilliValue() != 8000 {
+		t.Errorf("requests.cpu = %s, want 8", cpu.String())
+	}
+	if _, ok := hard[corev1.ResourcePods]; ok {
+		t.Errorf("pods should only be set when the budget gives it")
+	}
+	if objects.limitRange == nil || objects.limitRange.Spec.Limits[0].DefaultRequest.Cpu().MilliValue() != 100 {
+		t.Errorf("limitRange = %+v, want a 100m default CPU request", objects.limitRange)
+	}
+
+	var buf bytes.Buffer
+	if err := writeBudgetObjects(&buf, objects); err != nil {
+		t.Fatalf("writeBudgetObjects() error = %v", err)
+	}
+	if got := strings.Count(buf.String(), "\n---\n"); got != 2 {
+		t.Errorf("writeBudgetObjects() wrote %d separators, want 2:\n%s", got, buf.String())
+	}
+}
+
+func TestBuildBudgetObjectsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget budgetFile
+	}{
+		{"no namespace", budgetFile{CPU: "1"}},
+		{"no resources", budgetFile{Namespace: "payments"}},
+		{"invalid quantity", budgetFile{Namespace: "payments", Memory: "lots"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildBudgetObjects(tt.budget); err == nil {
+				t.Errorf("buildBudgetObjects() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// oversizedResources returns the resources a single pod requests more of
+// than the largest node can allocate. Such pods can never be scheduled.
+func oversizedResources(perPod, largest ResourceMetrics) []string {
+	var problems []string
+	if largest.CPU > 0 && perPod.CPU > largest.CPU {
+		problems = append(problems, fmt.Sprintf("%s CPU, more than the largest node allocatable (%s)", formatCPU(perPod.CPU), formatCPU(largest.CPU)))
+	}
+	if largest.Memory > 0 && perPod.Memory > largest.Memory {
+		problems = append(problems, fmt.Sprintf("%s memory, more than the largest node allocatable (%s)", formatMemory(perPod.Memory), formatMemory(largest.Memory)))
+	}
+	return problems
+}
+
+// nodeFitChecker marks workloads whose pods don't fit on any node, collecting
+// the details for printing after the report
+type nodeFitChecker struct {
+	largest  ResourceMetrics
+	findings []string
+}
+
+// Mark sets Unschedulable on the workload when its pods are too big for the
+// largest node
+func (c *nodeFitChecker) Mark(dm *DeploymentMetrics) {
+	if dm.PodRequests == nil {
+		return
+	}
+	for _, problem := range oversizedResources(*dm.PodRequests, c.largest) {
+		dm.Unschedulable = true
+		c.findings = append(c.findings, fmt.Sprintf("%s/%s: a pod requests %s", dm.Namespace, dm.Name, problem))
+	}
+}
+
+func (c *nodeFitChecker) Print(out io.Writer) {
+	for _, finding := range c.findings {
+		fmt.Fprintf(out, "Warning: unschedulable %s\n", finding)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOversizedResources(t *testing.T) {
+	largest := ResourceMetrics{CPU: 4000, Memory: 16 << 30}
+
+	tests := []struct {
+		name   string
+		perPod ResourceMetrics
+		want   []string
+	}{
+		{name: "fits", perPod: ResourceMetrics{CPU: 4000, Memory: 8 << 30}},
+		{name: "too much CPU", perPod: ResourceMetrics{CPU: 8000}, want: []string{"8.00 cores CPU"}},
+		{name: "too much of both", perPod: ResourceMetrics{CPU: 4500, Memory: 32 << 30}, want: []string{"CPU", "memory"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := oversizedResources(tt.perPod, largest)
+			if len(got) != len(tt.want) {
+				t.Fatalf("oversizedResources() = %v, want %d problems", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("oversizedResources()[%d] = %q, want it to mention %q", i, got[i], want)
+				}
+			}
+		})
+	}
+
+	if got := oversizedResources(ResourceMetrics{CPU: 8000}, ResourceMetrics{}); got != nil {
+		t.Errorf("oversizedResources() without node sizes = %v, want none", got)
+	}
+}
+
+func TestNodeFitChecker(t *testing.T) {
+	c := &nodeFitChecker{largest: ResourceMetrics{CPU: 2000}}
+	big := DeploymentMetrics{Name: "batch", Namespace: "etl", PodRequests: &ResourceMetrics{CPU: 3000}}
+	small := DeploymentMetrics{Name: "api", Namespace: "shop", PodRequests: &ResourceMetrics{CPU: 500}}
+	c.Mark(&big)
+	c.Mark(&small)
+
+	if !big.Unschedulable || small.Unschedulable {
+		t.Errorf("Unschedulable = %v/%v, want true/false", big.Unschedulable, small.Unschedulable)
+	}
+
+	var buf bytes.Buffer
+	c.Print(&buf)
+	if buf.String() != "Warning: unschedulable etl/batch: a pod requests 3.00 cores CPU, more than the largest node allocatable (2.00 cores)\n" {
+		t.Errorf("Print() = %q", buf.String())
+	}
+}
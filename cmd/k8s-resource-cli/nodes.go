@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// pressureConditions are the node conditions that mean the kubelet is short
+// of a resource and may start evicting pods
+var pressureConditions = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+}
+
+// nodeView is one node with the requests of the pods scheduled on it
+type nodeView struct {
+	Name        string
+	OS          string // kubernetes.io/os label
+	Arch        string // kubernetes.io/arch label
+	Allocatable ResourceMetrics
+	Requests    ResourceMetrics
+	Pressure    []string
+}
+
+// requestedPercent returns requests as a percentage of allocatable, per resource
+func (n nodeView) requestedPercent() (float64, float64) {
+	var cpu, memory float64
+	if n.Allocatable.CPU > 0 {
+		cpu = float64(n.Requests.CPU) / float64(n.Allocatable.CPU) * 100
+	}
+	if n.Allocatable.Memory > 0 {
+		memory = float64(n.Requests.Memory) / float64(n.Allocatable.Memory) * 100
+	}
+	return cpu, memory
+}
+
+// status classifies the node for rebalancing: "rebalance" when it is under
+// pressure and requested at or above the threshold, "pressure" or "high"
+// when only one holds, "-" otherwise
+func (n nodeView) status(threshold float64) string {
+	cpu, memory := n.requestedPercent()
+	high := max(cpu, memory) >= threshold
+	switch {
+	case len(n.Pressure) > 0 && high:
+		return "rebalance"
+	case len(n.Pressure) > 0:
+		return "pressure"
+	case high:
+		return "high"
+	}
+	return "-"
+}
+
+// nodePressure lists the pressure conditions that are currently true
+func nodePressure(node corev1.Node) []string {
+	var pressure []string
+	for _, wanted := range pressureConditions {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == wanted && condition.Status == corev1.ConditionTrue {
+				pressure = append(pressure, string(condition.Type))
+			}
+		}
+	}
+	return pressure
+}
+
+// forEachScheduledPod calls fn for every pod bound to a node that has not finished
+func forEachScheduledPod(ctx context.Context, clientset *kubernetes.Clientset, fn func(pod corev1.Pod)) error {
+	listOptions := metav1.ListOptions{FieldSelector: "status.phase!=Succeeded,status.phase!=Failed", Limit: listPageSize}
+	for {
+		pods, err := clientset.CoreV1().Pods("").List(ctx, listOptions)
+		if err != nil {
+			return fmt.Errorf("error listing pods: %w", err)
+		}
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName != "" {
+				fn(pod)
+			}
+		}
+		if pods.Continue == "" {
+			return nil
+		}
+		listOptions.Continue = pods.Continue
+	}
+}
+
+// loadNodeViews lists the nodes matching the label selector with the summed
+// requests of the pods on each
+func loadNodeViews(ctx context.Context, clientset *kubernetes.Clientset, selector string) ([]nodeView, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing nodes: %w", err)
+	}
+
+	byName := make(map[string]*nodeView, len(nodes.Items))
+	views := make([]nodeView, len(nodes.Items))
+	for i, node := range nodes.Items {
+		views[i] = nodeView{
+			Name: node.Name,
+			OS:   node.Labels[corev1.LabelOSStable],
+			Arch: node.Labels[corev1.LabelArchStable],
+			Allocatable: ResourceMetrics{
+				CPU:    node.Status.Allocatable.Cpu().MilliValue(),
+				Memory: node.Status.Allocatable.Memory().Value(),
+			},
+			Pressure: nodePressure(node),
+		}
+		byName[node.Name] = &views[i]
+	}
+
+	err = forEachScheduledPod(ctx, clientset, func(pod corev1.Pod) {
+		if view, ok := byName[pod.Spec.NodeName]; ok {
+			view.Requests.add(podRequests(pod))
+		}
+	})
+	return views, err
+}
+
+// sortNodeViews puts the nodes most in need of rebalancing first: pressured
+// and highly requested, then pressured, then by their most requested resource
+func sortNodeViews(views []nodeView, threshold float64) {
+	rank := map[string]int{"rebalance": 0, "pressure": 1, "high": 2, "-": 3}
+	sort.SliceStable(views, func(i, j int) bool {
+		ri, rj := rank[views[i].status(threshold)], rank[views[j].status(threshold)]
+		if ri != rj {
+			return ri < rj
+		}
+		ci, mi := views[i].requestedPercent()
+		cj, mj := views[j].requestedPercent()
+		if max(ci, mi) != max(cj, mj) {
+			return max(ci, mi) > max(cj, mj)
+		}
+		return views[i].Name < views[j].Name
+	})
+}
+
+// runNodesCommand implements the "nodes" subcommand, listing nodes with how
+// much of their allocatable is requested and any pressure conditions
+func runNodesCommand(args []string) {
+	var kubeconfig string
+	var kubeContext string
+	var selector string
+	var threshold float64
+
+	fs := flag.NewFlagSet("nodes", flag.ContinueOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	fs.StringVar(&selector, "selector", "", "Node label selector")
+	fs.StringVar(&selector, "l", "", "Node label selector (shorthand)")
+	fs.Float64Var(&threshold, "threshold", 85, "Requested percentage of allocatable at which a node counts as highly requested")
+	parseFlags(fs, args)
+
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	views, err := loadNodeViews(context.Background(), clientset, selector)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+	sortNodeViews(views, threshold)
+	printNodeViews(os.Stdout, views, threshold)
+}
+
+func printNodeViews(out io.Writer, views []nodeView, threshold float64) {
+	if len(views) == 0 {
+		fmt.Fprintln(out, "No nodes found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "NODE\tOS\tARCH\tCPU REQUESTS\tCPU %%\tMEMORY REQUESTS\tMEMORY %%\tPRESSURE\tSTATUS\n")
+	for _, n := range views {
+		pressure := "-"
+		if len(n.Pressure) > 0 {
+			pressure = strings.Join(n.Pressure, ",")
+		}
+		printNodeViewRow(w, n, pressure, n.status(threshold))
+	}
+
+	// Linux and Windows capacity can't stand in for each other, so mixed
+	// clusters get a total per OS. Architectures get one too, to follow a
+	// migration from amd64 to arm64 pools.
+	printNodeViewSubtotals(w, views, func(n nodeView) string { return n.OS })
+	printNodeViewSubtotals(w, views, func(n nodeView) string { return n.Arch })
+
+	total := nodeView{Name: "TOTAL"}
+	for _, n := range views {
+		total.Allocatable.add(n.Allocatable)
+		total.Requests.add(n.Requests)
+	}
+	printNodeViewRow(w, total, "", "")
+	w.Flush()
+}
+
+// printNodeViewSubtotals prints a TOTAL row per value of key, when the
+// nodes have more than one
+func printNodeViewSubtotals(w io.Writer, views []nodeView, key func(nodeView) string) {
+	sums := make(map[string]*nodeView)
+	for _, n := range views {
+		value := key(n)
+		sum, ok := sums[value]
+		if !ok {
+			sum = &nodeView{Name: "TOTAL (" + valueOrDash(value) + ")"}
+			sums[value] = sum
+		}
+		sum.Allocatable.add(n.Allocatable)
+		sum.Requests.add(n.Requests)
+	}
+	if len(sums) < 2 {
+		return
+	}
+	for _, value := range sortedKeys(sums) {
+		printNodeViewRow(w, *sums[value], "", "")
+	}
+}
+
+func printNodeViewRow(w io.Writer, n nodeView, pressure, status string) {
+	cpu, memory := n.requestedPercent()
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.0f%%\t%s\t%.0f%%\t%s\t%s\n",
+		n.Name, n.OS, n.Arch,
+		formatCPUPair(n.Requests.CPU, n.Allocatable.CPU), cpu,
+		formatMemoryPair(n.Requests.Memory, n.Allocatable.Memory), memory,
+		pressure, status)
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
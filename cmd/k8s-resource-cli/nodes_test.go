@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNodePressure(t *testing.T) {
+	node := corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+		{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+		{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue},
+		{Type: corev1.NodePIDPressure, Status: corev1.ConditionFalse},
+	}}}
+
+	want := []string{"MemoryPressure", "DiskPressure"}
+	if got := nodePressure(node); !reflect.DeepEqual(got, want) {
+		t.Errorf("nodePressure() = %v, want %v", got, want)
+	}
+	if got := nodePressure(corev1.Node{}); got != nil {
+		t.Errorf("nodePressure() without conditions = %v, want nil", got)
+	}
+}
+
+func TestNodeViewStatus(t *testing.T) {
+	alloc := ResourceMetrics{CPU: 1000, Memory: 1000}
+
+	tests := []struct {
+		name string
+		view nodeView
+		want string
+	}{
+		{"idle", nodeView{Allocatable: alloc, Requests: ResourceMetrics{CPU: 100, Memory: 100}}, "-"},
+		{"high memory", nodeView{Allocatable: alloc, Requests: ResourceMetrics{CPU: 100, Memory: 900}}, "high"},
+		{"pressure only", nodeView{Allocatable: alloc, Pressure: []string{"DiskPressure"}}, "pressure"},
+		{"pressure and high", nodeView{Allocatable: alloc, Requests: ResourceMetrics{CPU: 950}, Pressure: []string{"MemoryPressure"}}, "rebalance"},
+		{"no allocatable", nodeView{Requests: ResourceMetrics{CPU: 100}}, "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.view.status(85); got != tt.want {
+				t.Errorf("status() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortNodeViews(t *testing.T) {
+	alloc := ResourceMetrics{CPU: 1000, Memory: 1000}
+	views := []nodeView{
+		{Name: "quiet", Allocatable: alloc, Requests: ResourceMetrics{CPU: 100}},
+		{Name: "busy", Allocatable: alloc, Requests: ResourceMetrics{CPU: 700}},
+		{Name: "pressured", Allocatable: alloc, Pressure: []string{"DiskPressure"}},
+		{Name: "hot", Allocatable: alloc, Requests: ResourceMetrics{CPU: 900}, Pressure: []string{"MemoryPressure"}},
+		{Name: "full", Allocatable: alloc, Requests: ResourceMetrics{Memory: 990}},
+	}
+	sortNodeViews(views, 85)
+
+	var got []string
+	for _, v := range views {
+		got = append(got, v.Name)
+	}
+	want := []string{"hot", "pressured", "full", "busy", "quiet"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortNodeViews() order = %v, want %v", got, want)
+	}
+}
+
+func TestPrintNodeViews(t *testing.T) {
+	var buf bytes.Buffer
+	printNodeViews(&buf, []nodeView{{
+		Name:        "node-1",
+		Allocatable: ResourceMetrics{CPU: 4000, Memory: 8 << 30},
+		Requests:    ResourceMetrics{CPU: 3600, Memory: 2 << 30},
+		Pressure:    []string{"MemoryPressure", "PIDPressure"},
+	}}, 85)
+
+	output := buf.String()
+	for _, want := range []string{"node-1", "3.60 / 4.00 cores", "90%", "25%", "MemoryPressure,PIDPressure", "rebalance"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("printNodeViews() output missing %q:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	printNodeViews(&buf, []nodeView{
+		{Name: "linux-1", OS: "linux", Allocatable: ResourceMetrics{CPU: 4000}, Requests: ResourceMetrics{CPU: 1000}},
+		{Name: "win-1", OS: "windows", Allocatable: ResourceMetrics{CPU: 2000}, Requests: ResourceMetrics{CPU: 1500}},
+	}, 85)
+	for _, want := range []string{"TOTAL (linux)", "TOTAL (windows)", "1.50 / 2.00 cores", "2.50 / 6.00 cores"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("printNodeViews() mixed OS output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	printNodeViews(&buf, []nodeView{
+		{Name: "x86-1", OS: "linux", Arch: "amd64", Allocatable: ResourceMetrics{CPU: 4000}, Requests: ResourceMetrics{CPU: 3000}},
+		{Name: "x86-2", OS: "linux", Arch: "amd64", Allocatable: ResourceMetrics{CPU: 4000}, Requests: ResourceMetrics{CPU: 1000}},
+		{Name: "graviton-1", OS: "linux", Arch: "arm64", Allocatable: ResourceMetrics{CPU: 8000}, Requests: ResourceMetrics{CPU: 2000}},
+	}, 85)
+	for _, want := range []string{"TOTAL (amd64)", "4.00 / 8.00 cores", "TOTAL (arm64)", "2.00 / 8.00 cores", "6.00 / 16.00 cores"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("printNodeViews() mixed arch output missing %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "TOTAL (linux)") {
+		t.Errorf("printNodeViews() printed an OS total for a single-OS cluster:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printNodeViews(&buf, nil, 85)
+	if buf.String() != "No nodes found\n" {
+		t.Errorf("printNodeViews(nil) = %q", buf.String())
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// roundUpTo rounds value up to the next multiple of step, never returning
+// less than one step for a non-zero value
+func roundUpTo(value, step int64) int64 {
+	if value <= 0 || step <= 0 {
+		return value
+	}
+	return (value + step - 1) / step * step
+}
+
+// cpuQuantity and memoryQuantity render values the way they'd be written in
+// a pod spec, so suggestions can be pasted as-is
+func cpuQuantity(millis int64) string {
+	return resource.NewMilliQuantity(millis, resource.DecimalSI).String()
+}
+
+func memoryQuantity(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
+
+// normalizedWorkload is the per-pod request suggestion for one workload
+type normalizedWorkload struct {
+	Name      string          `json:"name"`
+	Namespace string          `json:"namespace"`
+	Type      string          `json:"type"`
+	Pods      int32           `json:"pods"`
+	Current   ResourceMetrics `json:"current"`
+	Suggested ResourceMetrics `json:"suggested"`
+}
+
+// packNodes estimates how many nodes of the given size the pods need using
+// first-fit decreasing on the pod's larger share of a node. Pods too big for
+// any node get a node each.
+func packNodes(pods []ResourceMetrics, node ResourceMetrics) int {
+	if node.CPU <= 0 || node.Memory <= 0 {
+		return 0
+	}
+
+	share := func(pod ResourceMetrics) float64 {
+		return max(float64(pod.CPU)/float64(node.CPU), float64(pod.Memory)/float64(node.Memory))
+	}
+	sorted := append([]ResourceMetrics(nil), pods...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return share(sorted[i]) > share(sorted[j])
+	})
+
+	var free []ResourceMetrics
+	for _, pod := range sorted {
+		placed := false
+		for i := range free {
+			if pod.CPU <= free[i].CPU && pod.Memory <= free[i].Memory {
+				free[i].CPU -= pod.CPU
+				free[i].Memory -= pod.Memory
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			free = append(free, ResourceMetrics{CPU: max(node.CPU-pod.CPU, 0), Memory: max(node.Memory-pod.Memory, 0)})
+		}
+	}
+	return len(free)
+}
+
+// normalizePrinter suggests per-pod requests rounded up to fixed steps, which
+// leaves the scheduler fewer odd-sized gaps, and estimates the node count
+// before and after on the largest node size
+type normalizePrinter struct {
+	out       io.Writer
+	format    string
+	meta      *runMetadata
+	step      ResourceMetrics
+	node      ResourceMetrics
+	workloads []normalizedWorkload
+}
+
+func newNormalizePrinter(out io.Writer, format string, step ResourceMetrics, meta *runMetadata) *normalizePrinter {
+	return &normalizePrinter{out: out, format: format, step: step, meta: meta}
+}
+
+// SetNodeSize sets the node allocatable pods are packed onto for the estimate
+func (p *normalizePrinter) SetNodeSize(node ResourceMetrics) {
+	p.node = node
+}
+
+func (p *normalizePrinter) Add(dm DeploymentMetrics) {
+	if dm.CurrentReplicas <= 0 {
+		return
+	}
+	current := ResourceMetrics{
+		CPU:    dm.Requests.CPU / int64(dm.CurrentReplicas),
+		Memory: dm.Requests.Memory / int64(dm.CurrentReplicas),
+	}
+	p.workloads = append(p.workloads, normalizedWorkload{
+		Name:      dm.Name,
+		Namespace: dm.Namespace,
+		Type:      dm.Type,
+		Pods:      dm.CurrentReplicas,
+		Current:   current,
+		Suggested: ResourceMetrics{CPU: roundUpTo(current.CPU, p.step.CPU), Memory: roundUpTo(current.Memory, p.step.Memory)},
+	})
+}
+
+// packing returns the estimated node count for the current and suggested requests
+func (p *normalizePrinter) packing() (int, int) {
+	var current, suggested []ResourceMetrics
+	for _, w := range p.workloads {
+		for i := int32(0); i < w.Pods; i++ {
+			current = append(current, w.Current)
+			suggested = append(suggested, w.Suggested)
+		}
+	}
+	return packNodes(current, p.node), packNodes(suggested, p.node)
+}
+
+func (p *normalizePrinter) Flush() {
+	nodesBefore, nodesAfter := p.packing()
+
+	if p.format == FormatJSON {
+		output := struct {
+			Metadata    *runMetadata         `json:"metadata,omitempty"`
+			Items       []normalizedWorkload `json:"items"`
+			NodeSize    ResourceMetrics      `json:"node_size"`
+			NodesBefore int                  `json:"nodes_before"`
+			NodesAfter  int                  `json:"nodes_after"`
+		}{Metadata: p.meta, Items: p.workloads, NodeSize: p.node, NodesBefore: nodesBefore, NodesAfter: nodesAfter}
+		if output.Items == nil {
+			output.Items = []normalizedWorkload{}
+		}
+		if err := json.NewEncoder(p.out).Encode(output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON output: %v\n", err)
+		}
+		return
+	}
+
+	if len(p.workloads) == 0 {
+		fmt.Fprintln(p.out, "No deployments found")
+		return
+	}
+
+	table := resultTable{headers: []string{"NAME", "NAMESPACE", "PODS", "CPU", "SUGGESTED CPU", "MEMORY", "SUGGESTED MEMORY"}}
+	for _, w := range p.workloads {
+		table.rows = append(table.rows, []string{
+			w.Name, w.Namespace, fmt.Sprintf("%d", w.Pods),
+			cpuQuantity(w.Current.CPU), cpuQuantity(w.Suggested.CPU),
+			memoryQuantity(w.Current.Memory), memoryQuantity(w.Suggested.Memory),
+		})
+	}
+	table.total = []string{"NODES", "", "", fmt.Sprintf("%d", nodesBefore), fmt.Sprintf("%d", nodesAfter), "", ""}
+	if p.node.CPU == 0 || p.node.Memory == 0 {
+		table.total = []string{"NODES", "", "", "-", "-", "", ""}
+	}
+
+	if p.format == FormatMarkdown {
+		printMarkdownResults(p.out, table, false)
+	} else {
+		printTableResults(p.out, table, false)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRoundUpTo(t *testing.T) {
+	tests := []struct {
+		value, step, want int64
+	}{
+		{0, 50, 0},
+		{1, 50, 50},
+		{50, 50, 50},
+		{51, 50, 100},
+		{130, 50, 150},
+		{100, 0, 100},
+	}
+
+	for _, tt := range tests {
+		if got := roundUpTo(tt.value, tt.step); got != tt.want {
+			t.Errorf("roundUpTo(%d, %d) = %d, want %d", tt.value, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestQuantities(t *testing.T) {
+	if got := cpuQuantity(150); got != "150m" {
+		t.Errorf("cpuQuantity(150) = %q, want 150m", got)
+	}
+	if got := cpuQuantity(2000); got != "2" {
+		t.Errorf("cpuQuantity(2000) = %q, want 2", got)
+	}
+	if got := memoryQuantity(192 << 20); got != "192Mi" {
+		t.Errorf("memoryQuantity(192Mi) = %q, want 192Mi", got)
+	}
+}
+
+func TestNormalizeStepDefaults(t *testing.T) {
+	if got, err := parseResourceValue("50m", true); err != nil || got != 50 {
+		t.Errorf("parseResourceValue(50m) = %d, %v", got, err)
+	}
+	if got, err := parseResourceValue("64Mi", false); err != nil || got != 64<<20 {
+		t.Errorf("parseResourceValue(64Mi) = %d, %v", got, err)
+	}
+}
+
+func TestPackNodes(t *testing.T) {
+	node := ResourceMetrics{CPU: 1000, Memory: 1000}
+
+	tests := []struct {
+		name string
+		pods []ResourceMetrics
+		want int
+	}{
+		{"no pods", nil, 0},
+		{"fits one node", []ResourceMetrics{{CPU: 500, Memory: 200}, {CPU: 500, Memory: 200}}, 1},
+		{"cpu bound", []ResourceMetrics{{CPU: 600, Memory: 100}, {CPU: 600, Memory: 100}}, 2},
+		{"decreasing fills gaps", []ResourceMetrics{{CPU: 300}, {CPU: 700}, {CPU: 300}, {CPU: 700}}, 2},
+		{"oversized pod", []ResourceMetrics{{CPU: 2000, Memory: 100}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := packNodes(tt.pods, node); got != tt.want {
+				t.Errorf("packNodes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := packNodes([]ResourceMetrics{{CPU: 100}}, ResourceMetrics{}); got != 0 {
+		t.Errorf("packNodes() with unknown node size = %d, want 0", got)
+	}
+}
+
+func TestNormalizePrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newNormalizePrinter(&buf, FormatTable, ResourceMetrics{CPU: 50, Memory: 64 << 20}, nil)
+	p.SetNodeSize(ResourceMetrics{CPU: 4000, Memory: 16 << 30})
+	p.Add(DeploymentMetrics{Name: "api", Namespace: "shop", CurrentReplicas: 2,
+		Requests: ResourceMetrics{CPU: 260, Memory: 2 * 200 << 20}})
+	p.Add(DeploymentMetrics{Name: "idle", Namespace: "shop"})
+
+	if len(p.workloads) != 1 {
+		t.Fatalf("workloads = %d, want 1 (workloads without pods are skipped)", len(p.workloads))
+	}
+	if got := p.workloads[0].Suggested; got.CPU != 150 || got.Memory != 256<<20 {
+		t.Errorf("suggested = %+v, want 150m / 256Mi", got)
+	}
+
+	p.Flush()
+	output := buf.String()
+	for _, want := range []string{"SUGGESTED CPU", "130m", "150m", "200Mi", "256Mi", "NODES"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Flush() output missing %q:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// podSecurityLabel holds the Pod Security Standard a namespace enforces
+const podSecurityLabel = "pod-security.kubernetes.io/enforce"
+
+// namespaceLabelAliases are short names accepted by --namespace-labels
+var namespaceLabelAliases = map[string]string{
+	"pod-security": podSecurityLabel,
+}
+
+// namespaceLabelHeaders are the column headers of well-known labels; others
+// use the label key in upper case
+var namespaceLabelHeaders = map[string]string{
+	podSecurityLabel: "POD SECURITY",
+}
+
+// parseNamespaceLabels splits a comma-separated --namespace-labels value,
+// expanding aliases
+func parseNamespaceLabels(value string) []string {
+	keys := parseLabelChain(value)
+	for i, key := range keys {
+		if label, ok := namespaceLabelAliases[key]; ok {
+			keys[i] = label
+		}
+	}
+	return keys
+}
+
+func namespaceLabelHeader(key string) string {
+	if header, ok := namespaceLabelHeaders[key]; ok {
+		return header
+	}
+	return strings.ToUpper(key)
+}
+
+// namespaceLabelKeys returns the namespace labels the workloads were
+// annotated with, which are the columns the report shows
+func namespaceLabelKeys(deployments []DeploymentMetrics) []string {
+	keys := make(map[string]bool)
+	for _, dm := range deployments {
+		for key := range dm.NamespaceLabels {
+			keys[key] = true
+		}
+	}
+	return sortedKeys(keys)
+}
+
+// loadNamespaceLabels returns the requested labels of the namespace, or of
+// every namespace if empty. Labels a namespace lacks are included as empty
+// strings so every workload reports the same keys.
+func loadNamespaceLabels(ctx context.Context, clientset *kubernetes.Clientset, namespace string, keys []string) (map[string]map[string]string, error) {
+	pick := func(labels map[string]string) map[string]string {
+		picked := make(map[string]string, len(keys))
+		for _, key := range keys {
+			picked[key] = labels[key]
+		}
+		return picked
+	}
+
+	result := make(map[string]map[string]string)
+	if namespace != "" {
+		ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return result, fmt.Errorf("error getting namespace: %w", err)
+		}
+		result[ns.Name] = pick(ns.Labels)
+		return result, nil
+	}
+
+	listOptions := metav1.ListOptions{Limit: listPageSize}
+	for {
+		namespaces, err := clientset.CoreV1().Namespaces().List(ctx, listOptions)
+		if err != nil {
+			return result, fmt.Errorf("error listing namespaces: %w", err)
+		}
+		for _, ns := range namespaces.Items {
+			result[ns.Name] = pick(ns.Labels)
+		}
+		if namespaces.Continue == "" {
+			return result, nil
+		}
+		listOptions.Continue = namespaces.Continue
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNamespaceLabels(t *testing.T) {
+	got := parseNamespaceLabels("pod-security, environment,,team")
+	want := []string{podSecurityLabel, "environment", "team"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNamespaceLabels() = %v, want %v", got, want)
+	}
+}
+
+func TestNamespaceLabelHeader(t *testing.T) {
+	if got := namespaceLabelHeader(podSecurityLabel); got != "POD SECURITY" {
+		t.Errorf("namespaceLabelHeader(%q) = %q", podSecurityLabel, got)
+	}
+	if got := namespaceLabelHeader("environment"); got != "ENVIRONMENT" {
+		t.Errorf("namespaceLabelHeader(environment) = %q", got)
+	}
+}
@@ -1,154 +1,890 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"text/tabwriter"
+	"time"
 )
 
-type resultRow struct {
-	name, typ, ns, replicas, cpu, memory string
+// resultTable is the rendered report: header cells, one row of cells per
+// workload and the TOTAL row, shared by the table and markdown printers
+type resultTable struct {
+	headers   []string
+	rows      [][]string
+	subtotals [][]string       // e.g. per-OS totals, shown just above total
+	groupEnds map[int][]string // subtotal shown after row i, e.g. per namespace
+	total     []string
+	colors    []string // ANSI color of each row, table format only
 }
 
-func printResults(deployments []DeploymentMetrics, outputType string, usePorter bool, totalOnly bool, format string) {
+// resultPrinter receives workloads as they are collected. Table and markdown
+// output need every row before they can align columns, so they buffer; JSON
+// is written out as each workload arrives, keeping only running totals.
+type resultPrinter interface {
+	Add(dm DeploymentMetrics)
+	Flush()
+}
+
+// tableOptions are the layout choices of table and markdown output
+type tableOptions struct {
+	groupBy    string           // GroupByNamespace or GroupByPriorityClass for a subtotal per group
+	breakdown  string           // BreakdownPod for a row per pod under each workload
+	wide       bool             // -o wide: replica, HPA, QoS and placement columns
+	perReplica bool             // --per-replica: the figures divided by the replicas
+	labels     []string         // --label-columns: workload labels shown as a column each
+	showLabels bool             // --show-labels: all workload labels in a LABELS column
+	columns    []string         // --columns: the selectable columns to keep, all if nil
+	promBy     string           // --prom-granularity: what prom series are summed by, PromByWorkload if empty
+	colors     *colorThresholds // row colors for table format, none if nil
+}
+
+func newResultPrinter(out io.Writer, outputType string, usePorter bool, totalOnly bool, format string, opts tableOptions, meta *runMetadata) resultPrinter {
+	if format == FormatJSON {
+		return &jsonPrinter{out: out, totalOnly: totalOnly, groupBy: opts.groupBy, meta: meta}
+	}
+	if format == FormatProm {
+		return &promPrinter{out: out, by: opts.promBy}
+	}
+	if format == FormatJSONL {
+		return &jsonlPrinter{out: out, totalOnly: totalOnly}
+	}
+	if format == FormatDOT {
+		return &dotPrinter{out: out, outputType: outputType}
+	}
+	return &bufferedPrinter{out: out, outputType: outputType, usePorter: usePorter, totalOnly: totalOnly, format: format, opts: opts}
+}
+
+type bufferedPrinter struct {
+	out         io.Writer
+	deployments []DeploymentMetrics
+	outputType  string
+	usePorter   bool
+	totalOnly   bool
+	format      string
+	opts        tableOptions
+}
+
+func (p *bufferedPrinter) Add(dm DeploymentMetrics) {
+	p.deployments = append(p.deployments, dm)
+}
+
+func (p *bufferedPrinter) Flush() {
+	printResults(p.out, p.deployments, p.outputType, p.usePorter, p.totalOnly, p.format, p.opts)
+}
+
+type jsonTotals struct {
+	Usage       ResourceMetrics `json:"usage"`
+	Requests    ResourceMetrics `json:"requests"`
+	Limits      ResourceMetrics `json:"limits"`
+	MaxRequests ResourceMetrics `json:"max_requests"`
+	CarbonGrams float64         `json:"carbon_gco2e,omitempty"`
+}
+
+// add adds the workload's figures, max-requests at their effective value
+func (t *jsonTotals) add(dm DeploymentMetrics) {
+	t.Usage.add(dm.Usage)
+	t.Requests.add(dm.Requests)
+	t.Limits.add(dm.Limits)
+	t.MaxRequests.add(dm.EffectiveMaxRequests())
+	t.CarbonGrams += dm.CarbonGrams
+}
+
+// addGroupTotals adds the workload to the totals of its group, e.g. its OS
+// or type. Workloads without a group, like Porter applications for OS, are
+// left out.
+func addGroupTotals(groups map[string]*jsonTotals, group string, dm DeploymentMetrics) {
+	if group == "" {
+		return
+	}
+	totals, ok := groups[group]
+	if !ok {
+		totals = &jsonTotals{}
+		groups[group] = totals
+	}
+	totals.add(dm)
+}
+
+// jsonPrinter streams {"$schema":...,"metadata":{...},"items":[...],"total":{...}}
+// one item at a time, in the shape of reportSchema. max_requests is always
+// the effective value, matching the max-requests output. metadata is left out
+// when meta is nil.
+type jsonPrinter struct {
+	out         io.Writer
+	totalOnly   bool
+	groupBy     string
+	meta        *runMetadata
+	count       int
+	totals      jsonTotals
+	byOS        map[string]*jsonTotals
+	byType      map[string]*jsonTotals
+	byNamespace map[string]*jsonTotals
+	byPriority  map[string]*jsonTotals
+}
+
+func (p *jsonPrinter) Add(dm DeploymentMetrics) {
+	dm.MaxRequests = dm.EffectiveMaxRequests()
+
+	p.totals.add(dm)
+	if p.byOS == nil {
+		p.byOS = make(map[string]*jsonTotals)
+		p.byType = make(map[string]*jsonTotals)
+		p.byNamespace = make(map[string]*jsonTotals)
+		p.byPriority = make(map[string]*jsonTotals)
+	}
+	addGroupTotals(p.byOS, dm.OS, dm)
+	addGroupTotals(p.byType, dm.Type, dm)
+	switch p.groupBy {
+	case GroupByNamespace:
+		addGroupTotals(p.byNamespace, dm.Namespace, dm)
+	case GroupByPriorityClass:
+		addGroupTotals(p.byPriority, groupKey(GroupByPriorityClass, dm), dm)
+	}
+
+	if p.totalOnly {
+		return
+	}
+
+	data, err := json.Marshal(dm)
+	if err != nil {
+		partialf("Error encoding %s: %v", dm.Name, err)
+		return
+	}
+
+	if p.count == 0 {
+		p.writeHeader()
+		fmt.Fprint(p.out, "\n")
+	} else {
+		fmt.Fprint(p.out, ",\n")
+	}
+	p.out.Write(data)
+	p.count++
+}
+
+// writeHeader opens the document and the items array
+func (p *jsonPrinter) writeHeader() {
+	fmt.Fprintf(p.out, "{\"$schema\":%q,\n", reportSchemaURL)
+	if p.meta != nil {
+		fmt.Fprint(p.out, "\"metadata\":")
+		if data, err := json.Marshal(p.meta); err == nil {
+			p.out.Write(data)
+		} else {
+			fmt.Fprint(p.out, "null")
+		}
+		fmt.Fprint(p.out, ",\n")
+	}
+	fmt.Fprint(p.out, "\"items\":[")
+}
+
+func (p *jsonPrinter) Flush() {
+	if p.count == 0 {
+		p.writeHeader()
+	}
+	fmt.Fprint(p.out, "\n],\"total\":")
+	json.NewEncoder(p.out).Encode(p.totals)
+	if len(p.byOS) > 1 {
+		fmt.Fprint(p.out, ",\"total_by_os\":")
+		json.NewEncoder(p.out).Encode(p.byOS)
+	}
+	if len(p.byType) > 1 {
+		fmt.Fprint(p.out, ",\"total_by_type\":")
+		json.NewEncoder(p.out).Encode(p.byType)
+	}
+	switch p.groupBy {
+	case GroupByNamespace:
+		fmt.Fprint(p.out, ",\"total_by_namespace\":")
+		json.NewEncoder(p.out).Encode(p.byNamespace)
+	case GroupByPriorityClass:
+		fmt.Fprint(p.out, ",\"total_by_priority_class\":")
+		json.NewEncoder(p.out).Encode(p.byPriority)
+	}
+	fmt.Fprint(p.out, "}\n")
+}
+
+// jsonlPrinter writes each workload as a JSON line the moment it arrives,
+// holding nothing back, for log pipelines on large clusters. With totalOnly
+// only a single {"total":{...}} line is written at the end, with a total per
+// workload type when there is more than one.
+type jsonlPrinter struct {
+	out       io.Writer
+	totalOnly bool
+	totals    jsonTotals
+	byType    map[string]*jsonTotals
+}
+
+func (p *jsonlPrinter) Add(dm DeploymentMetrics) {
+	dm.MaxRequests = dm.EffectiveMaxRequests()
+	if p.totalOnly {
+		p.totals.add(dm)
+		if p.byType == nil {
+			p.byType = make(map[string]*jsonTotals)
+		}
+		addGroupTotals(p.byType, dm.Type, dm)
+		return
+	}
+	if err := json.NewEncoder(p.out).Encode(dm); err != nil {
+		partialf("Error encoding %s: %v", dm.Name, err)
+	}
+}
+
+func (p *jsonlPrinter) Flush() {
+	if p.totalOnly {
+		line := struct {
+			Total  jsonTotals             `json:"total"`
+			ByType map[string]*jsonTotals `json:"total_by_type,omitempty"`
+		}{Total: p.totals}
+		if len(p.byType) > 1 {
+			line.ByType = p.byType
+		}
+		json.NewEncoder(p.out).Encode(line)
+	}
+}
+
+func printResults(out io.Writer, deployments []DeploymentMetrics, outputType string, usePorter bool, totalOnly bool, format string, opts tableOptions) {
 	if len(deployments) == 0 {
-		fmt.Println("No deployments found")
+		fmt.Fprintln(out, "No deployments found")
 		return
 	}
+	if opts.groupBy != "" {
+		// Stable, so rows keep their --sort-by order within a group
+		deployments = slices.Clone(deployments)
+		slices.SortStableFunc(deployments, func(a, b DeploymentMetrics) int {
+			return strings.Compare(groupKey(opts.groupBy, a), groupKey(opts.groupBy, b))
+		})
+	}
 
-	hasCronJobs := false
+	hasOtherTypes := false
+	hasMixedSources := false
+	hasTargets := false
+	hasMachineTypes := false
+	hasRuns := false
+	hasGPUs := false
+	hasImageSizes := false
+	hasCarbon := false
+	hasUsage := false
+	hasDegraded := false
 	for _, dm := range deployments {
-		if dm.Type == "CronJob" {
-			hasCronJobs = true
-			break
+		if dm.Type != "Deployment" {
+			hasOtherTypes = true
+		}
+		if dm.TargetCPU > 0 || dm.TargetMemory > 0 {
+			hasTargets = true
+		}
+		if dm.MachineType != "" {
+			hasMachineTypes = true
+		}
+		if dm.NextRun != nil || dm.LastSuccessfulRun != nil {
+			hasRuns = true
+		}
+		if dm.GPUs > 0 {
+			hasGPUs = true
+		}
+		if dm.ImageBytes > 0 {
+			hasImageSizes = true
+		}
+		if dm.CarbonGrams > 0 {
+			hasCarbon = true
+		}
+		if dm.Usage.CPU > 0 || dm.Usage.Memory > 0 {
+			hasUsage = true
+		}
+		if dm.Degraded() {
+			hasDegraded = true
+		}
+		if dm.Source != deployments[0].Source {
+			hasMixedSources = true
 		}
 	}
 
 	namespaceHeader := "NAMESPACE"
-	if usePorter {
+	if usePorter && !hasMixedSources {
 		namespaceHeader = "TARGET"
 	}
 
-	var rows []resultRow
-	var totalUsageCPU, totalUsageMemory int64
-	var totalRequestsCPU, totalRequestsMemory int64
-	var totalMaxCPU, totalMaxMemory int64
+	var table resultTable
+	if hasOtherTypes {
+		table.headers = append(table.headers, "NAME", "TYPE")
+	} else {
+		table.headers = append(table.headers, "DEPLOYMENT")
+	}
+	if hasMixedSources {
+		table.headers = append(table.headers, "SOURCE")
+	}
+	table.headers = append(table.headers, namespaceHeader)
+	namespaceColumns := namespaceLabelKeys(deployments)
+	namespaceColumn := len(table.headers)
+	for _, key := range namespaceColumns {
+		table.headers = append(table.headers, namespaceLabelHeader(key))
+	}
+	table.headers = append(table.headers, "REPLICAS")
+	if opts.wide {
+		table.headers = append(table.headers, "DESIRED", "AVAILABLE", "HPA MIN/MAX", "QOS", "CONTAINERS", "NODES", "RESTARTS")
+	}
+	// Grouped by priority class, rows say which they belong to like they
+	// say their namespace
+	showPriority := opts.wide || opts.groupBy == GroupByPriorityClass
+	if showPriority {
+		table.headers = append(table.headers, "PRIORITY CLASS")
+	}
+	if hasTargets {
+		table.headers = append(table.headers, "TARGETS")
+	}
+	if hasRuns {
+		table.headers = append(table.headers, "NEXT RUN", "LAST SUCCESS")
+	}
+	if hasMachineTypes {
+		table.headers = append(table.headers, "MACHINE TYPE")
+	}
+	gpuColumn := -1
+	if hasGPUs {
+		gpuColumn = len(table.headers)
+		table.headers = append(table.headers, "GPUS")
+	}
+	if hasImageSizes {
+		table.headers = append(table.headers, "IMAGE SIZE")
+	}
+	// Usage as a share of requests shows overprovisioning at a glance, for
+	// the output types that show usage and when there is usage to show
+	figures := resourceColumns{
+		cpu:         len(table.headers),
+		utilization: hasUsage && (outputType == OutputTypeUsage || outputType == OutputTypeCombined || outputType == OutputTypeIdle || outputType == OutputTypeAll),
+	}
+	table.headers = append(table.headers, resourceHeaders(outputType)...)
+	if figures.utilization {
+		table.headers = append(table.headers, "CPU%", "MEM%")
+	}
+	carbonColumn := -1
+	if hasCarbon {
+		carbonColumn = len(table.headers)
+		table.headers = append(table.headers, "CO2E")
+	}
+	if opts.perReplica {
+		table.headers = append(table.headers, "CPU/REPLICA", "MEM/REPLICA")
+	}
+	table.headers = append(table.headers, labelHeaders(opts.labels, opts.showLabels)...)
 
-	for _, dm := range deployments {
-		var cpu, memory, replicas string
+	var totals jsonTotals
+	var totalGPUs int64
+	byOS := make(map[string]*jsonTotals)
+	byType := make(map[string]*jsonTotals)
+	var groupTotals jsonTotals
 
+	for i, dm := range deployments {
+		addGroupTotals(byOS, dm.OS, dm)
+		addGroupTotals(byType, dm.Type, dm)
+
+		var replicas string
 		switch outputType {
-		case OutputTypeUsage, OutputTypeRequests, OutputTypeCombined:
+		case OutputTypeUsage, OutputTypeRequests, OutputTypeCombined, OutputTypeIdle, OutputTypeAll:
 			replicas = fmt.Sprintf("%d/%d", dm.CurrentReplicas, dm.MaxReplicas)
+			// Once some workload is degraded every row leads with its ready
+			// replicas, so the column reads the same way throughout
+			if hasDegraded {
+				replicas = fmt.Sprintf("%d/%s", dm.ReadyReplicas, replicas)
+			}
 		case OutputTypeMaxRequests:
 			replicas = fmt.Sprintf("%d", dm.MaxReplicas)
 		}
 
-		switch outputType {
-		case OutputTypeUsage:
-			cpu = formatCPU(dm.Usage.CPU)
-			memory = formatMemory(dm.Usage.Memory)
-		case OutputTypeRequests:
-			cpu = formatCPU(dm.Requests.CPU)
-			memory = formatMemory(dm.Requests.Memory)
-		case OutputTypeMaxRequests:
-			if dm.MaxReplicas > dm.DesiredReplicas {
-				cpu = formatCPU(dm.MaxRequests.CPU)
-				memory = formatMemory(dm.MaxRequests.Memory)
-			} else {
-				cpu = formatCPU(dm.Requests.CPU)
-				memory = formatMemory(dm.Requests.Memory)
+		var figuresOfRow jsonTotals
+		figuresOfRow.add(dm)
+		totals.add(dm)
+
+		row := []string{displayName(dm)}
+		if hasOtherTypes {
+			typ := dm.Type
+			if dm.Suspended {
+				typ += " (suspended)"
+			}
+			row = append(row, typ)
+		}
+		if hasMixedSources {
+			row = append(row, dm.Source)
+		}
+		row = append(row, dm.Namespace)
+		for _, key := range namespaceColumns {
+			row = append(row, valueOrDash(dm.NamespaceLabels[key]))
+		}
+		row = append(row, replicas)
+		if opts.wide {
+			hpa := "-"
+			if dm.MinReplicas > 0 {
+				hpa = fmt.Sprintf("%d/%d", dm.MinReplicas, dm.MaxReplicas)
+			}
+			row = append(row, fmt.Sprintf("%d", dm.DesiredReplicas), fmt.Sprintf("%d", dm.AvailableReplicas), hpa, valueOrDash(dm.QOSClass), fmt.Sprintf("%d", dm.Containers), fmt.Sprintf("%d", dm.Nodes), fmt.Sprintf("%d", dm.Restarts))
+		}
+		if showPriority {
+			row = append(row, valueOrDash(dm.PriorityClass))
+		}
+		if hasTargets {
+			row = append(row, formatTargets(dm))
+		}
+		if hasRuns {
+			row = append(row, formatRunTime(dm.NextRun), formatRunTime(dm.LastSuccessfulRun))
+		}
+		if hasMachineTypes {
+			row = append(row, valueOrDash(dm.MachineType))
+		}
+		if hasGPUs {
+			gpus := gpuCount(outputType, dm)
+			totalGPUs += gpus
+			row = append(row, fmt.Sprintf("%d", gpus))
+		}
+		if hasImageSizes {
+			row = append(row, formatMemory(dm.ImageBytes))
+		}
+		row = append(row, resourceCells(outputType, figuresOfRow)...)
+		if figures.utilization {
+			row = append(row, formatUtilization(dm.Usage.CPU, dm.Requests.CPU), formatUtilization(dm.Usage.Memory, dm.Requests.Memory))
+		}
+		if hasCarbon {
+			row = append(row, formatCarbon(dm.CarbonGrams))
+		}
+		if opts.perReplica {
+			row = append(row, perReplicaCells(outputType, dm)...)
+		}
+		row = append(row, labelCells(dm, opts.labels, opts.showLabels)...)
+		table.rows = append(table.rows, row)
+		if opts.colors != nil {
+			table.colors = append(table.colors, opts.colors.rowColor(dm))
+		}
+		if opts.breakdown == BreakdownPod {
+			// Pod rows only fill the name and resource columns, so a hot
+			// replica stands out against its siblings. The columns after
+			// them are left blank.
+			for _, pod := range ownerPods(dm.Owners) {
+				podMetrics := DeploymentMetrics{Usage: pod.Usage, Requests: pod.Requests, MaxRequests: pod.Requests}
+				var podFigures jsonTotals
+				podFigures.add(podMetrics)
+				podRow := make([]string, figures.cpu)
+				podRow[0] = "  └ " + pod.Name
+				podRow = append(podRow, resourceCells(outputType, podFigures)...)
+				if figures.utilization {
+					podRow = append(podRow, formatUtilization(pod.Usage.CPU, pod.Requests.CPU), formatUtilization(pod.Usage.Memory, pod.Requests.Memory))
+				}
+				podRow = append(podRow, make([]string, len(table.headers)-len(podRow))...)
+				table.rows = append(table.rows, podRow)
+				if opts.colors != nil {
+					table.colors = append(table.colors, opts.colors.rowColor(podMetrics))
+				}
 			}
-		case OutputTypeCombined:
-			cpu = formatCPUPair(dm.Usage.CPU, dm.Requests.CPU)
-			memory = formatMemoryPair(dm.Usage.Memory, dm.Requests.Memory)
-		}
-
-		totalUsageCPU += dm.Usage.CPU
-		totalUsageMemory += dm.Usage.Memory
-		totalRequestsCPU += dm.Requests.CPU
-		totalRequestsMemory += dm.Requests.Memory
-		if dm.MaxReplicas > dm.DesiredReplicas {
-			totalMaxCPU += dm.MaxRequests.CPU
-			totalMaxMemory += dm.MaxRequests.Memory
-		} else {
-			totalMaxCPU += dm.Requests.CPU
-			totalMaxMemory += dm.Requests.Memory
 		}
 
-		rows = append(rows, resultRow{dm.Name, dm.Type, dm.Namespace, replicas, cpu, memory})
+		if opts.groupBy != "" {
+			groupTotals.add(dm)
+			group := groupKey(opts.groupBy, dm)
+			if i == len(deployments)-1 || groupKey(opts.groupBy, deployments[i+1]) != group {
+				if table.groupEnds == nil {
+					table.groupEnds = make(map[int][]string)
+				}
+				subtotal := make([]string, len(table.headers))
+				subtotal[0] = "TOTAL (" + group + ")"
+				if opts.groupBy == GroupByNamespace {
+					for c, key := range namespaceColumns {
+						subtotal[namespaceColumn+c] = valueOrDash(dm.NamespaceLabels[key])
+					}
+				}
+				figures.fill(subtotal, outputType, groupTotals)
+				table.groupEnds[len(table.rows)-1] = subtotal
+				groupTotals = jsonTotals{}
+			}
+		}
 	}
 
-	var totalCPUStr, totalMemoryStr string
-	switch outputType {
-	case OutputTypeUsage:
-		totalCPUStr = formatCPU(totalUsageCPU)
-		totalMemoryStr = formatMemory(totalUsageMemory)
-	case OutputTypeRequests:
-		totalCPUStr = formatCPU(totalRequestsCPU)
-		totalMemoryStr = formatMemory(totalRequestsMemory)
-	case OutputTypeMaxRequests:
-		totalCPUStr = formatCPU(totalMaxCPU)
-		totalMemoryStr = formatMemory(totalMaxMemory)
-	case OutputTypeCombined:
-		totalCPUStr = formatCPUPair(totalUsageCPU, totalRequestsCPU)
-		totalMemoryStr = formatMemoryPair(totalUsageMemory, totalRequestsMemory)
+	table.total = make([]string, len(table.headers))
+	table.total[0] = "TOTAL"
+	figures.fill(table.total, outputType, totals)
+	if gpuColumn >= 0 {
+		table.total[gpuColumn] = fmt.Sprintf("%d", totalGPUs)
+	}
+	if carbonColumn >= 0 {
+		table.total[carbonColumn] = formatCarbon(totals.CarbonGrams)
+	}
+
+	// Batch and serving capacity are budgeted separately, so reports with
+	// more than one workload type get a total per type. Mixed Linux/Windows
+	// clusters get a total per OS, since one figure across both misleads sizing.
+	table.subtotals = append(table.subtotals, subtotalRows(byType, outputType, len(table.headers), figures)...)
+	table.subtotals = append(table.subtotals, subtotalRows(byOS, outputType, len(table.headers), figures)...)
+
+	if opts.columns != nil {
+		table = table.withColumns(opts.columns)
 	}
 
 	if format == FormatMarkdown {
-		printMarkdownResults(rows, namespaceHeader, hasCronJobs, totalOnly, totalCPUStr, totalMemoryStr)
+		printMarkdownResults(out, table, totalOnly)
 	} else {
-		printTableResults(rows, namespaceHeader, hasCronJobs, totalOnly, totalCPUStr, totalMemoryStr)
+		printTableResults(out, table, totalOnly)
 	}
 }
 
-func printTableResults(rows []resultRow, namespaceHeader string, hasCronJobs bool, totalOnly bool, totalCPUStr, totalMemoryStr string) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+// noPriorityClass is the priority class group of workloads without a
+// priorityClassName, which run at the cluster's global default priority
+const noPriorityClass = "no priority class"
 
-	if !totalOnly {
-		if hasCronJobs {
-			fmt.Fprintf(w, "NAME\tTYPE\t%s\tREPLICAS\tCPU\tMEMORY\n", namespaceHeader)
-		} else {
-			fmt.Fprintf(w, "DEPLOYMENT\t%s\tREPLICAS\tCPU\tMEMORY\n", namespaceHeader)
+// groupKey is the --group-by group a workload belongs to
+func groupKey(groupBy string, dm DeploymentMetrics) string {
+	if groupBy == GroupByPriorityClass {
+		if dm.PriorityClass == "" {
+			return noPriorityClass
+		}
+		return dm.PriorityClass
+	}
+	return dm.Namespace
+}
+
+// displayName is the workload's name as table rows show it, marked when it
+// is a canary, scales with the cluster or can't be scheduled
+func displayName(dm DeploymentMetrics) string {
+	name := dm.Name
+	if dm.Canary {
+		name += " (canary)"
+	}
+	if dm.Proportional {
+		name += " (cluster-proportional)"
+	}
+	if dm.Unschedulable {
+		name += " (unschedulable)"
+	}
+	if dm.Paused {
+		name += " (paused)"
+	}
+	return name
+}
+
+// subtotalRows renders a "TOTAL (group)" row per group, or none when there
+// is only one group and the subtotal would repeat the total
+func subtotalRows(groups map[string]*jsonTotals, outputType string, width int, figures resourceColumns) [][]string {
+	if len(groups) < 2 {
+		return nil
+	}
+	var rows [][]string
+	for _, name := range sortedKeys(groups) {
+		subtotal := make([]string, width)
+		subtotal[0] = "TOTAL (" + name + ")"
+		figures.fill(subtotal, outputType, *groups[name])
+		rows = append(rows, subtotal)
+	}
+	return rows
+}
+
+// resourceColumns locates the first CPU and memory column of a result table
+// and says whether the CPU% and MEM% columns follow them
+type resourceColumns struct {
+	cpu         int
+	utilization bool
+}
+
+// fill writes totals into the resource columns of a total or subtotal row
+func (c resourceColumns) fill(row []string, outputType string, totals jsonTotals) {
+	cells := resourceCells(outputType, totals)
+	copy(row[c.cpu:], cells)
+	if c.utilization {
+		row[c.cpu+len(cells)] = formatUtilization(totals.Usage.CPU, totals.Requests.CPU)
+		row[c.cpu+len(cells)+1] = formatUtilization(totals.Usage.Memory, totals.Requests.Memory)
+	}
+}
+
+// resourceHeaders are the headers of the CPU and memory columns: one each,
+// or with -o all one per figure
+func resourceHeaders(outputType string) []string {
+	if outputType == OutputTypeAll {
+		return []string{"CPU USAGE", "CPU REQUESTS", "CPU LIMITS", "CPU MAX", "MEMORY USAGE", "MEMORY REQUESTS", "MEMORY LIMITS", "MEMORY MAX"}
+	}
+	return []string{"CPU", "MEMORY"}
+}
+
+// resourceCells formats totals for the resourceHeaders columns
+func resourceCells(outputType string, totals jsonTotals) []string {
+	if outputType == OutputTypeAll {
+		return []string{
+			formatCPU(totals.Usage.CPU), formatCPU(totals.Requests.CPU), formatCPU(totals.Limits.CPU), formatCPU(totals.MaxRequests.CPU),
+			formatMemory(totals.Usage.Memory), formatMemory(totals.Requests.Memory), formatMemory(totals.Limits.Memory), formatMemory(totals.MaxRequests.Memory),
 		}
-		for _, r := range rows {
-			if hasCronJobs {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.name, r.typ, r.ns, r.replicas, r.cpu, r.memory)
-			} else {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.name, r.ns, r.replicas, r.cpu, r.memory)
+	}
+	cpu, memory := formatForOutput(outputType, totals.Usage, totals.Requests, totals.MaxRequests)
+	return []string{cpu, memory}
+}
+
+// perReplicaCells formats the figures of the output type divided by the
+// replicas they cover: max replicas for max-requests, current replicas
+// otherwise. Workloads scaled to zero show "-".
+func perReplicaCells(outputType string, dm DeploymentMetrics) []string {
+	replicas := int64(dm.CurrentReplicas)
+	if outputType == OutputTypeMaxRequests {
+		replicas = int64(dm.MaxReplicas)
+	}
+	if replicas <= 0 {
+		return []string{"-", "-"}
+	}
+	perReplica := func(rm ResourceMetrics) ResourceMetrics {
+		return ResourceMetrics{CPU: rm.CPU / replicas, Memory: rm.Memory / replicas}
+	}
+	cpu, memory := formatForOutput(outputType, perReplica(dm.Usage), perReplica(dm.Requests), perReplica(dm.EffectiveMaxRequests()))
+	return []string{cpu, memory}
+}
+
+// labelHeaders are the headers of the workload label columns: one per
+// --label-columns key, in upper case, then LABELS with --show-labels
+func labelHeaders(keys []string, showLabels bool) []string {
+	var headers []string
+	for _, key := range keys {
+		headers = append(headers, strings.ToUpper(key))
+	}
+	if showLabels {
+		headers = append(headers, "LABELS")
+	}
+	return headers
+}
+
+// labelCells fills the labelHeaders columns for a workload, "-" for labels
+// it doesn't carry
+func labelCells(dm DeploymentMetrics, keys []string, showLabels bool) []string {
+	var cells []string
+	for _, key := range keys {
+		cells = append(cells, valueOrDash(dm.Labels[key]))
+	}
+	if showLabels {
+		cells = append(cells, formatLabels(dm.Labels))
+	}
+	return cells
+}
+
+// formatLabels lists labels as sorted key=value pairs, the way kubectl
+// --show-labels does, or "-" when there are none
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range sortedKeys(labels) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return valueOrDash(strings.Join(pairs, ","))
+}
+
+// formatUtilization shows usage as a percentage of requests, or "-" when
+// nothing is requested
+func formatUtilization(usage, requests int64) string {
+	if requests <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(usage)/float64(requests)*100)
+}
+
+// gpuCount returns the GPUs the workload requests at the scale the output
+// type reports: max replicas for max-requests, current replicas otherwise
+func gpuCount(outputType string, dm DeploymentMetrics) int64 {
+	if outputType == OutputTypeMaxRequests {
+		return dm.GPUs * int64(dm.MaxReplicas)
+	}
+	return dm.GPUs * int64(dm.CurrentReplicas)
+}
+
+// formatTargets shows the autoscaling utilization targets, e.g. "cpu 70%, memory 80%"
+func formatTargets(dm DeploymentMetrics) string {
+	var targets []string
+	if dm.TargetCPU > 0 {
+		targets = append(targets, fmt.Sprintf("cpu %d%%", dm.TargetCPU))
+	}
+	if dm.TargetMemory > 0 {
+		targets = append(targets, fmt.Sprintf("memory %d%%", dm.TargetMemory))
+	}
+	if len(targets) == 0 {
+		return "-"
+	}
+	return strings.Join(targets, ", ")
+}
+
+// formatRunTime shows a CronJob run time to the minute, in the time zone
+// of its schedule, or "-" for none
+func formatRunTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04 MST")
+}
+
+// selectableColumns maps the names --columns takes to the headers of the
+// columns they keep. The name, type, source and namespace columns that say
+// which workload a row is, and --namespace-labels and --label-columns
+// columns, always stay.
+var selectableColumns = map[string]string{
+	"replicas":           "REPLICAS",
+	"desired":            "DESIRED",
+	"available":          "AVAILABLE",
+	"hpa":                "HPA MIN/MAX",
+	"qos":                "QOS",
+	"priority-class":     "PRIORITY CLASS",
+	"containers":         "CONTAINERS",
+	"nodes":              "NODES",
+	"restarts":           "RESTARTS",
+	"targets":            "TARGETS",
+	"next-run":           "NEXT RUN",
+	"last-success":       "LAST SUCCESS",
+	"machine-type":       "MACHINE TYPE",
+	"gpus":               "GPUS",
+	"image-size":         "IMAGE SIZE",
+	"cpu":                "CPU",
+	"memory":             "MEMORY",
+	"cpu-percent":        "CPU%",
+	"memory-percent":     "MEM%",
+	"carbon":             "CO2E",
+	"cpu-per-replica":    "CPU/REPLICA",
+	"memory-per-replica": "MEM/REPLICA",
+}
+
+// parseColumns validates a comma-separated --columns value
+func parseColumns(value string) ([]string, error) {
+	columns := parseLabelChain(value)
+	for _, name := range columns {
+		if _, ok := selectableColumns[name]; !ok {
+			return nil, fmt.Errorf("invalid --columns name '%s'. Must be one of %s", name, strings.Join(sortedKeys(selectableColumns), ", "))
+		}
+	}
+	return columns, nil
+}
+
+// withColumns returns a copy of the table without the selectable columns
+// not named in keep, leaving the order as it was
+func (t resultTable) withColumns(keep []string) resultTable {
+	dropped := make(map[string]bool)
+	for _, header := range selectableColumns {
+		dropped[header] = true
+	}
+	for _, name := range keep {
+		delete(dropped, selectableColumns[name])
+	}
+
+	var indexes []int
+	for i, header := range t.headers {
+		if !dropped[header] {
+			indexes = append(indexes, i)
+		}
+	}
+	pick := func(cells []string) []string {
+		picked := make([]string, len(indexes))
+		for i, index := range indexes {
+			// Rows shorter than the headers keep the missing cells blank
+			if index < len(cells) {
+				picked[i] = cells[index]
 			}
 		}
+		return picked
 	}
 
-	if hasCronJobs {
-		fmt.Fprintf(w, "TOTAL\t\t\t\t%s\t%s\n", totalCPUStr, totalMemoryStr)
-	} else {
-		fmt.Fprintf(w, "TOTAL\t\t\t%s\t%s\n", totalCPUStr, totalMemoryStr)
+	selected := resultTable{headers: pick(t.headers), total: pick(t.total), colors: t.colors}
+	for _, row := range t.rows {
+		selected.rows = append(selected.rows, pick(row))
+	}
+	for _, subtotal := range t.subtotals {
+		selected.subtotals = append(selected.subtotals, pick(subtotal))
 	}
+	if t.groupEnds != nil {
+		selected.groupEnds = make(map[int][]string, len(t.groupEnds))
+		for i, subtotal := range t.groupEnds {
+			selected.groupEnds[i] = pick(subtotal)
+		}
+	}
+	return selected
+}
 
-	w.Flush()
+// localized returns a copy of the table with configured header labels applied
+func (t resultTable) localized() resultTable {
+	headers := make([]string, len(t.headers))
+	for i, name := range t.headers {
+		headers[i] = header(name)
+	}
+	total := append([]string(nil), t.total...)
+	if len(total) > 0 {
+		total[0] = header(total[0])
+	}
+	return resultTable{headers: headers, rows: t.rows, subtotals: t.subtotals, groupEnds: t.groupEnds, total: total, colors: t.colors}
 }
 
-func printMarkdownResults(rows []resultRow, namespaceHeader string, hasCronJobs bool, totalOnly bool, totalCPUStr, totalMemoryStr string) {
-	if hasCronJobs {
-		fmt.Printf("| NAME | TYPE | %s | REPLICAS | CPU | MEMORY |\n", namespaceHeader)
-		fmt.Println("| --- | --- | --- | --- | --- | --- |")
+func printTableResults(out io.Writer, table resultTable, totalOnly bool) {
+	table = table.localized()
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+
+	// With colors every line gets one, so the escape codes widen every first
+	// column alike
+	line := func(cells []string, color string) string {
+		if table.colors != nil {
+			cells = colorLine(cells, color)
+		}
+		return strings.Join(cells, "\t")
+	}
+	if !totalOnly {
+		fmt.Fprintln(w, line(table.headers, colorDefault))
+	}
+	for i, row := range table.rows {
 		if !totalOnly {
-			for _, r := range rows {
-				fmt.Printf("| %s | %s | %s | %s | %s | %s |\n", r.name, r.typ, r.ns, r.replicas, r.cpu, r.memory)
+			color := colorDefault
+			if table.colors != nil {
+				color = table.colors[i]
 			}
+			fmt.Fprintln(w, line(row, color))
 		}
-		fmt.Printf("| **TOTAL** | | | | **%s** | **%s** |\n", totalCPUStr, totalMemoryStr)
-	} else {
-		fmt.Printf("| DEPLOYMENT | %s | REPLICAS | CPU | MEMORY |\n", namespaceHeader)
-		fmt.Println("| --- | --- | --- | --- | --- |")
+		if subtotal, ok := table.groupEnds[i]; ok {
+			fmt.Fprintln(w, line(subtotal, colorDefault))
+		}
+	}
+	for _, subtotal := range table.subtotals {
+		fmt.Fprintln(w, line(subtotal, colorDefault))
+	}
+	fmt.Fprintln(w, line(table.total, colorDefault))
+
+	w.Flush()
+}
+
+func printMarkdownResults(out io.Writer, table resultTable, totalOnly bool) {
+	table = table.localized()
+	fmt.Fprintf(out, "| %s |\n", strings.Join(table.headers, " | "))
+	fmt.Fprintf(out, "|%s\n", strings.Repeat(" --- |", len(table.headers)))
+	for i, row := range table.rows {
 		if !totalOnly {
-			for _, r := range rows {
-				fmt.Printf("| %s | %s | %s | %s | %s |\n", r.name, r.ns, r.replicas, r.cpu, r.memory)
-			}
+			fmt.Fprintf(out, "| %s |\n", strings.Join(row, " | "))
+		}
+		if subtotal, ok := table.groupEnds[i]; ok {
+			fmt.Fprintf(out, "%s|\n", markdownTotalRow(subtotal))
 		}
-		fmt.Printf("| **TOTAL** | | | **%s** | **%s** |\n", totalCPUStr, totalMemoryStr)
+	}
+
+	for _, subtotal := range table.subtotals {
+		fmt.Fprintf(out, "%s|\n", markdownTotalRow(subtotal))
+	}
+	fmt.Fprintf(out, "%s|\n", markdownTotalRow(table.total))
+}
+
+// markdownTotalRow bolds the non-empty cells, leaving blank ones as "| "
+func markdownTotalRow(cells []string) string {
+	var row strings.Builder
+	for _, cell := range cells {
+		if cell == "" {
+			row.WriteString("| ")
+		} else {
+			fmt.Fprintf(&row, "| **%s** ", cell)
+		}
+	}
+	return row.String()
+}
+
+// formatForOutput picks the CPU and memory strings the output type shows
+func formatForOutput(outputType string, usage, requests, maxRequests ResourceMetrics) (string, string) {
+	switch outputType {
+	case OutputTypeUsage:
+		return formatCPU(usage.CPU), formatMemory(usage.Memory)
+	case OutputTypeMaxRequests:
+		return formatCPU(maxRequests.CPU), formatMemory(maxRequests.Memory)
+	case OutputTypeCombined:
+		return formatCPUPair(usage.CPU, requests.CPU), formatMemoryPair(usage.Memory, requests.Memory)
+	case OutputTypeIdle:
+		idle := idleMetrics(usage, requests)
+		return formatSignedCPU(idle.CPU), formatSignedMemory(idle.Memory)
+	default:
+		return formatCPU(requests.CPU), formatMemory(requests.Memory)
 	}
 }
 
@@ -246,48 +982,160 @@ func parseResourceValue(value string, isCPU bool) (int64, error) {
 }
 
 func formatCPUPair(usage, requests int64) string {
-	if requests >= 1000 || usage >= 1000 {
+	if displayUnits != UnitsRaw && (requests >= 1000 || usage >= 1000) {
 		return fmt.Sprintf("%.2f / %.2f cores", float64(usage)/1000.0, float64(requests)/1000.0)
 	}
 	return fmt.Sprintf("%dm / %dm", usage, requests)
 }
 
 func formatMemoryPair(usage, requests int64) string {
-	const (
-		KB = 1024
-		MB = 1024 * KB
-		GB = 1024 * MB
-	)
-	if requests >= GB || usage >= GB {
-		return fmt.Sprintf("%.2f / %.2f GB", float64(usage)/float64(GB), float64(requests)/float64(GB))
-	} else if requests >= MB || usage >= MB {
-		return fmt.Sprintf("%.2f / %.2f MB", float64(usage)/float64(MB), float64(requests)/float64(MB))
-	} else if requests >= KB || usage >= KB {
-		return fmt.Sprintf("%.2f / %.2f KB", float64(usage)/float64(KB), float64(requests)/float64(KB))
+	if displayUnits == UnitsRaw {
+		return fmt.Sprintf("%d / %d", usage, requests)
+	}
+	scale := memoryScale()
+	for i := len(scale.labels) - 1; i >= 0; i-- {
+		if unit := scale.unit(i); requests >= unit || usage >= unit {
+			return fmt.Sprintf("%.2f / %.2f %s", float64(usage)/float64(unit), float64(requests)/float64(unit), scale.labels[i])
+		}
 	}
 	return fmt.Sprintf("%d / %d B", usage, requests)
 }
 
 func formatCPU(milliCores int64) string {
-	if milliCores >= 1000 {
+	if displayUnits != UnitsRaw && milliCores >= 1000 {
 		return fmt.Sprintf("%.2f cores", float64(milliCores)/1000.0)
 	}
 	return fmt.Sprintf("%dm", milliCores)
 }
 
 func formatMemory(bytes int64) string {
-	const (
-		KB = 1024
-		MB = 1024 * KB
-		GB = 1024 * MB
-	)
-
-	if bytes >= GB {
-		return fmt.Sprintf("%.2f GB", float64(bytes)/float64(GB))
-	} else if bytes >= MB {
-		return fmt.Sprintf("%.2f MB", float64(bytes)/float64(MB))
-	} else if bytes >= KB {
-		return fmt.Sprintf("%.2f KB", float64(bytes)/float64(KB))
+	if displayUnits == UnitsRaw {
+		return fmt.Sprintf("%d", bytes)
+	}
+	scale := memoryScale()
+	for i := len(scale.labels) - 1; i >= 0; i-- {
+		if unit := scale.unit(i); bytes >= unit {
+			return fmt.Sprintf("%.2f %s", float64(bytes)/float64(unit), scale.labels[i])
+		}
 	}
 	return fmt.Sprintf("%d B", bytes)
 }
+
+// customColumn is one HEADER:.Field.Path column of -o custom-columns
+type customColumn struct {
+	header string
+	path   []string
+}
+
+// outputColumns parses a -o custom-columns=SPEC value. Other values are
+// output types and give nil.
+func outputColumns(value string) ([]customColumn, error) {
+	if spec, ok := strings.CutPrefix(value, "custom-columns="); ok {
+		return parseCustomColumns(spec)
+	}
+	return nil, nil
+}
+
+// parseCustomColumns parses a kubectl-style column list such as
+// "NAME:.Name,CPU:.Requests.CPU,APP:.Labels.app". Paths are checked against
+// DeploymentMetrics up front so a typo fails before any API calls.
+func parseCustomColumns(spec string) ([]customColumn, error) {
+	var columns []customColumn
+	for _, part := range strings.Split(spec, ",") {
+		header, expr, ok := strings.Cut(part, ":")
+		header = strings.TrimSpace(header)
+		expr = strings.TrimSpace(expr)
+		if !ok || header == "" || !strings.HasPrefix(expr, ".") || len(expr) < 2 {
+			return nil, fmt.Errorf("invalid custom column %q, expected HEADER:.Field", part)
+		}
+		path := strings.Split(expr[1:], ".")
+		if err := checkColumnPath(reflect.TypeOf(DeploymentMetrics{}), path); err != nil {
+			return nil, fmt.Errorf("invalid custom column %q: %w", part, err)
+		}
+		columns = append(columns, customColumn{header: header, path: path})
+	}
+	return columns, nil
+}
+
+// checkColumnPath reports whether the field path exists in t. Map fields
+// take any key.
+func checkColumnPath(t reflect.Type, path []string) error {
+	for i, name := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := t.FieldByName(name)
+			if !ok || !field.IsExported() {
+				return fmt.Errorf("no field %s in %s", name, t.Name())
+			}
+			t = field.Type
+		case reflect.Map:
+			if i != len(path)-1 {
+				return fmt.Errorf("%s is a map key and can't have fields", name)
+			}
+			return nil
+		default:
+			return fmt.Errorf("%s has no fields", strings.Join(path[:i], "."))
+		}
+	}
+	return nil
+}
+
+// value renders the column for one workload, "<none>" for nil or missing values
+func (c customColumn) value(dm DeploymentMetrics) string {
+	v := reflect.ValueOf(dm)
+	for _, name := range c.path {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return "<none>"
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			v = v.FieldByName(name)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(name))
+			if !v.IsValid() {
+				return "<none>"
+			}
+		default:
+			return "<none>"
+		}
+	}
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil() {
+		return "<none>"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// customColumnsPrinter prints just the chosen columns, without a total row
+type customColumnsPrinter struct {
+	out     io.Writer
+	columns []customColumn
+	rows    [][]string
+}
+
+func (p *customColumnsPrinter) Add(dm DeploymentMetrics) {
+	dm.MaxRequests = dm.EffectiveMaxRequests()
+	row := make([]string, len(p.columns))
+	for i, column := range p.columns {
+		row[i] = column.value(dm)
+	}
+	p.rows = append(p.rows, row)
+}
+
+func (p *customColumnsPrinter) Flush() {
+	w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+	headers := make([]string, len(p.columns))
+	for i, column := range p.columns {
+		headers[i] = column.header
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range p.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseResourceValue(t *testing.T) {
@@ -125,3 +130,734 @@ func TestGetEnvDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web", Namespace: "prod", Type: "Deployment",
+		CurrentReplicas: 2, DesiredReplicas: 2, MaxReplicas: 4,
+		Requests:    ResourceMetrics{CPU: 200, Memory: 1024},
+		MaxRequests: ResourceMetrics{CPU: 400, Memory: 2048},
+	})
+	p.Add(DeploymentMetrics{
+		Name: "worker", Namespace: "prod", Type: "Deployment",
+		CurrentReplicas: 1, DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 100, Memory: 512},
+	})
+	p.Flush()
+
+	var got struct {
+		Items []DeploymentMetrics `json:"items"`
+		Total jsonTotals          `json:"total"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if len(got.Items) != 2 {
+		t.Fatalf("len(items) = %d, want 2", len(got.Items))
+	}
+	if got.Items[1].MaxRequests.CPU != 100 {
+		t.Errorf("worker max_requests cpu = %d, want effective value 100", got.Items[1].MaxRequests.CPU)
+	}
+	if got.Total.Requests.CPU != 300 {
+		t.Errorf("total requests cpu = %d, want 300", got.Total.Requests.CPU)
+	}
+	if got.Total.MaxRequests.Memory != 2560 {
+		t.Errorf("total max_requests memory = %d, want 2560", got.Total.MaxRequests.Memory)
+	}
+}
+
+func TestJSONPrinterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{}, nil)
+	p.Flush()
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+}
+
+func TestJSONLPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSONL, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web", Namespace: "prod", Type: "Deployment",
+		CurrentReplicas: 2, DesiredReplicas: 2, MaxReplicas: 4,
+		Requests:    ResourceMetrics{CPU: 200, Memory: 1024},
+		MaxRequests: ResourceMetrics{CPU: 400, Memory: 2048},
+	})
+	if !strings.HasSuffix(buf.String(), "\n") || strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("first workload should be written before Flush, got %q", buf.String())
+	}
+	p.Add(DeploymentMetrics{
+		Name: "worker", Namespace: "prod", Type: "Deployment",
+		CurrentReplicas: 1, DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 100, Memory: 512},
+	})
+	p.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	var worker DeploymentMetrics
+	if err := json.Unmarshal([]byte(lines[1]), &worker); err != nil {
+		t.Fatalf("line is not valid JSON: %v\n%s", err, lines[1])
+	}
+	if worker.Name != "worker" || worker.MaxRequests.CPU != 100 {
+		t.Errorf("worker = %+v, want effective max_requests cpu 100", worker)
+	}
+
+	buf.Reset()
+	p = newResultPrinter(&buf, OutputTypeRequests, false, true, FormatJSONL, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Requests: ResourceMetrics{CPU: 200}})
+	p.Add(DeploymentMetrics{Name: "worker", Requests: ResourceMetrics{CPU: 100}})
+	p.Flush()
+	var total struct {
+		Total jsonTotals `json:"total"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &total); err != nil || total.Total.Requests.CPU != 300 {
+		t.Errorf("total-only output = %q, want a single total line with 300m requests", buf.String())
+	}
+	if strings.Contains(buf.String(), "total_by_type") {
+		t.Errorf("total-only output = %q, want no per-type totals for a single type", buf.String())
+	}
+
+	buf.Reset()
+	p = newResultPrinter(&buf, OutputTypeRequests, false, true, FormatJSONL, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Type: "Deployment", Requests: ResourceMetrics{CPU: 200}})
+	p.Add(DeploymentMetrics{Name: "report", Type: "CronJob", Requests: ResourceMetrics{CPU: 100}})
+	p.Flush()
+	var byType struct {
+		ByType map[string]jsonTotals `json:"total_by_type"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &byType); err != nil || byType.ByType["CronJob"].Requests.CPU != 100 || byType.ByType["Deployment"].Requests.CPU != 200 {
+		t.Errorf("total-only output = %q, want a total per type", buf.String())
+	}
+}
+
+func TestTablePrinterMixedSources(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, true, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web-app-web", Namespace: "prod-cluster-default", Type: "Deployment", Source: SourcePorter,
+		CurrentReplicas: 1, MaxReplicas: 3, Requests: ResourceMetrics{CPU: 500, Memory: 1048576},
+	})
+	p.Add(DeploymentMetrics{
+		Name: "ingress", Namespace: "ingress-nginx", Type: "Deployment", Source: SourceKubernetes,
+		CurrentReplicas: 2, ReadyReplicas: 2, MaxReplicas: 2, Requests: ResourceMetrics{CPU: 700, Memory: 1048576},
+	})
+	p.Flush()
+
+	want := "DEPLOYMENT    SOURCE       NAMESPACE              REPLICAS   CPU          MEMORY\n" +
+		"web-app-web   porter       prod-cluster-default   1/3        500m         1.00 MB\n" +
+		"ingress       kubernetes   ingress-nginx          2/2        700m         1.00 MB\n" +
+		"TOTAL                                                        1.20 cores   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterTargets(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, true, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "api-web", Namespace: "prod", Type: "Deployment", Source: SourcePorter,
+		CurrentReplicas: 2, MaxReplicas: 10, TargetCPU: 70, TargetMemory: 80, Requests: ResourceMetrics{CPU: 500, Memory: 1048576},
+	})
+	p.Add(DeploymentMetrics{
+		Name: "api-worker", Namespace: "prod", Type: "Deployment", Source: SourcePorter,
+		CurrentReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 250, Memory: 1048576},
+	})
+	p.Flush()
+
+	want := "DEPLOYMENT   TARGET   REPLICAS   TARGETS               CPU    MEMORY\n" +
+		"api-web      prod     2/10       cpu 70%, memory 80%   500m   1.00 MB\n" +
+		"api-worker   prod     1/1        -                     250m   1.00 MB\n" +
+		"TOTAL                                                  750m   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterGPUs(t *testing.T) {
+	deployments := []DeploymentMetrics{
+		{Name: "ml-inference", Namespace: "prod", Type: "Deployment", Source: SourcePorter, CurrentReplicas: 2, MaxReplicas: 4,
+			GPUs: 1, MachineType: "g4dn.xlarge", Requests: ResourceMetrics{CPU: 2000, Memory: 1048576}},
+		{Name: "api", Namespace: "prod", Type: "Deployment", Source: SourcePorter, CurrentReplicas: 1, MaxReplicas: 1,
+			Requests: ResourceMetrics{CPU: 250, Memory: 1048576}},
+	}
+
+	var buf bytes.Buffer
+	printResults(&buf, deployments, OutputTypeRequests, true, false, FormatTable, tableOptions{})
+	want := "DEPLOYMENT     TARGET   REPLICAS   MACHINE TYPE   GPUS   CPU          MEMORY\n" +
+		"ml-inference   prod     2/4        g4dn.xlarge    2      2.00 cores   1.00 MB\n" +
+		"api            prod     1/1        -              0      250m         1.00 MB\n" +
+		"TOTAL                                             2      2.25 cores   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printResults(&buf, deployments, OutputTypeMaxRequests, true, true, FormatTable, tableOptions{})
+	if !strings.Contains(buf.String(), "   4   ") {
+		t.Errorf("max-requests total should count 4 GPUs at max replicas:\n%s", buf.String())
+	}
+}
+
+func TestMarkdownPrinterTotalRow(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, true, FormatMarkdown, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web", Namespace: "prod", Type: "Deployment", Source: SourceKubernetes,
+		Requests: ResourceMetrics{CPU: 200, Memory: 1024},
+	})
+	p.Flush()
+
+	want := "| DEPLOYMENT | NAMESPACE | REPLICAS | CPU | MEMORY |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| **TOTAL** | | | **200m** | **1.00 KB** |\n"
+	if buf.String() != want {
+		t.Errorf("markdown output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestJSONPrinterMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	meta := &runMetadata{
+		GeneratedAt: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		Version:     "v1.2.3",
+		Context:     "prod",
+		Cluster:     "prod-cluster",
+		Flags:       map[string]string{"all-namespaces": "true"},
+	}
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{}, meta)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "prod", Type: "Deployment"})
+	p.Flush()
+
+	var got struct {
+		Metadata runMetadata         `json:"metadata"`
+		Items    []DeploymentMetrics `json:"items"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Metadata.Version != "v1.2.3" || got.Metadata.Cluster != "prod-cluster" {
+		t.Errorf("metadata = %+v, want version v1.2.3 and cluster prod-cluster", got.Metadata)
+	}
+	if !got.Metadata.GeneratedAt.Equal(meta.GeneratedAt) {
+		t.Errorf("generated_at = %v, want %v", got.Metadata.GeneratedAt, meta.GeneratedAt)
+	}
+	if len(got.Items) != 1 {
+		t.Errorf("len(items) = %d, want 1", len(got.Items))
+	}
+}
+
+func TestTablePrinterOSTotals(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web", Namespace: "prod", Type: "Deployment", Source: SourceKubernetes, OS: "linux",
+		CurrentReplicas: 1, ReadyReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 500, Memory: 1048576},
+	})
+	p.Add(DeploymentMetrics{
+		Name: "iis", Namespace: "prod", Type: "Deployment", Source: SourceKubernetes, OS: "windows",
+		CurrentReplicas: 1, ReadyReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 700, Memory: 1048576},
+	})
+	p.Flush()
+
+	want := "DEPLOYMENT        NAMESPACE   REPLICAS   CPU          MEMORY\n" +
+		"web               prod        1/1        500m         1.00 MB\n" +
+		"iis               prod        1/1        700m         1.00 MB\n" +
+		"TOTAL (linux)                            500m         1.00 MB\n" +
+		"TOTAL (windows)                          700m         1.00 MB\n" +
+		"TOTAL                                    1.20 cores   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestJSONPrinterOSTotals(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "prod", Type: "Deployment", OS: "linux", Requests: ResourceMetrics{CPU: 500}})
+	p.Add(DeploymentMetrics{Name: "iis", Namespace: "prod", Type: "Deployment", OS: "windows", Requests: ResourceMetrics{CPU: 700}})
+	p.Flush()
+
+	var got struct {
+		TotalByOS map[string]jsonTotals `json:"total_by_os"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.TotalByOS["linux"].Requests.CPU != 500 || got.TotalByOS["windows"].Requests.CPU != 700 {
+		t.Errorf("total_by_os = %+v", got.TotalByOS)
+	}
+
+	buf.Reset()
+	p = newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "prod", Type: "Deployment", OS: "linux"})
+	p.Flush()
+	if strings.Contains(buf.String(), "total_by_os") {
+		t.Errorf("single-OS output should not have total_by_os:\n%s", buf.String())
+	}
+}
+
+func TestTablePrinterTypeTotals(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web", Namespace: "prod", Type: "Deployment", Source: SourceKubernetes,
+		CurrentReplicas: 2, ReadyReplicas: 2, MaxReplicas: 2, Requests: ResourceMetrics{CPU: 1000, Memory: 1048576},
+	})
+	p.Add(DeploymentMetrics{
+		Name: "backup", Namespace: "prod", Type: "CronJob", Source: SourceKubernetes,
+		CurrentReplicas: 0, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 250, Memory: 1048576},
+	})
+	p.Flush()
+
+	want := "NAME                 TYPE         NAMESPACE   REPLICAS   CPU          MEMORY\n" +
+		"web                  Deployment   prod        2/2        1.00 cores   1.00 MB\n" +
+		"backup               CronJob      prod        0/1        250m         1.00 MB\n" +
+		"TOTAL (CronJob)                                          250m         1.00 MB\n" +
+		"TOTAL (Deployment)                                       1.00 cores   1.00 MB\n" +
+		"TOTAL                                                    1.25 cores   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestJSONPrinterTypeTotals(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "prod", Type: "Deployment", Requests: ResourceMetrics{CPU: 500}})
+	p.Add(DeploymentMetrics{Name: "backup", Namespace: "prod", Type: "CronJob", Requests: ResourceMetrics{CPU: 700}})
+	p.Flush()
+
+	var got struct {
+		TotalByType map[string]jsonTotals `json:"total_by_type"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.TotalByType["Deployment"].Requests.CPU != 500 || got.TotalByType["CronJob"].Requests.CPU != 700 {
+		t.Errorf("total_by_type = %+v", got.TotalByType)
+	}
+}
+
+func TestParseCustomColumns(t *testing.T) {
+	columns, err := outputColumns("custom-columns=NAME:.Name, CPU:.Requests.CPU,APP:.Labels.app,SCALED:.ScaledRequests.CPU")
+	if err != nil {
+		t.Fatalf("outputColumns() error = %v", err)
+	}
+	if len(columns) != 4 || columns[1].header != "CPU" || !reflect.DeepEqual(columns[1].path, []string{"Requests", "CPU"}) {
+		t.Errorf("outputColumns() = %+v", columns)
+	}
+
+	if columns, err := outputColumns(OutputTypeRequests); columns != nil || err != nil {
+		t.Errorf("outputColumns(requests) = %v, %v, want nil, nil", columns, err)
+	}
+
+	for _, bad := range []string{"NAME", "NAME:Name", ":.Name", "CPU:.Requests.Cores", "X:.Name.Length", "X:.Labels.app.name", "X:.name"} {
+		if _, err := parseCustomColumns(bad); err == nil {
+			t.Errorf("parseCustomColumns(%q) should return an error", bad)
+		}
+	}
+}
+
+func TestCustomColumnsPrinter(t *testing.T) {
+	columns, err := parseCustomColumns("NAME:.Name,MAX CPU:.MaxRequests.CPU,APP:.Labels.app,SCALED:.ScaledRequests.CPU")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	p := &customColumnsPrinter{out: &buf, columns: columns}
+	p.Add(DeploymentMetrics{
+		Name: "web", DesiredReplicas: 2, MaxReplicas: 4, Labels: map[string]string{"app": "shop"},
+		Requests: ResourceMetrics{CPU: 500}, MaxRequests: ResourceMetrics{CPU: 1000},
+		ScaledRequests: &ResourceMetrics{CPU: 200},
+	})
+	p.Add(DeploymentMetrics{Name: "worker", DesiredReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 250}})
+	p.Flush()
+
+	want := "NAME     MAX CPU   APP      SCALED\n" +
+		"web      1000      shop     200\n" +
+		"worker   250       <none>   <none>\n"
+	if buf.String() != want {
+		t.Errorf("custom-columns output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterGroupByNamespace(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{groupBy: GroupByNamespace}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 500, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "search", Namespace: "docs", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 250, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "cart", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 250, Memory: 1048576}})
+	p.Flush()
+
+	want := "DEPLOYMENT     NAMESPACE   REPLICAS   CPU          MEMORY\n" +
+		"search         docs        1/1        250m         1.00 MB\n" +
+		"TOTAL (docs)                          250m         1.00 MB\n" +
+		"web            shop        1/1        500m         1.00 MB\n" +
+		"cart           shop        1/1        250m         1.00 MB\n" +
+		"TOTAL (shop)                          750m         2.00 MB\n" +
+		"TOTAL                                 1.00 cores   3.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	p = newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{groupBy: GroupByNamespace}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", Requests: ResourceMetrics{CPU: 500}})
+	p.Flush()
+	var got struct {
+		TotalByNamespace map[string]jsonTotals `json:"total_by_namespace"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.TotalByNamespace["shop"].Requests.CPU != 500 {
+		t.Errorf("total_by_namespace = %+v", got.TotalByNamespace)
+	}
+}
+
+func TestTablePrinterMarkers(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "batch", Namespace: "etl", Type: "Deployment", Unschedulable: true, Requests: ResourceMetrics{CPU: 3000}})
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", Canary: true})
+	p.Add(DeploymentMetrics{Name: "coredns", Namespace: "kube-system", Type: "Deployment", Proportional: true})
+	p.Add(DeploymentMetrics{Name: "legacy", Namespace: "shop", Type: "Deployment", Paused: true})
+	p.Flush()
+
+	if !strings.Contains(buf.String(), "batch (unschedulable) ") {
+		t.Errorf("table output should mark the unschedulable workload:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "web (canary) ") {
+		t.Errorf("table output should mark the canary:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "coredns (cluster-proportional)") {
+		t.Errorf("table output should mark the cluster-proportional workload:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "legacy (paused) ") {
+		t.Errorf("table output should mark the paused Deployment:\n%s", buf.String())
+	}
+}
+
+func TestTablePrinterNamespaceLabels(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{groupBy: GroupByNamespace}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		NamespaceLabels: map[string]string{podSecurityLabel: "restricted"}, Requests: ResourceMetrics{CPU: 500, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "legacy", Namespace: "old", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		NamespaceLabels: map[string]string{podSecurityLabel: ""}, Requests: ResourceMetrics{CPU: 250, Memory: 1048576}})
+	p.Flush()
+
+	want := "DEPLOYMENT     NAMESPACE   POD SECURITY   REPLICAS   CPU    MEMORY\n" +
+		"legacy         old         -              1/1        250m   1.00 MB\n" +
+		"TOTAL (old)                -                         250m   1.00 MB\n" +
+		"web            shop        restricted     1/1        500m   1.00 MB\n" +
+		"TOTAL (shop)               restricted                500m   1.00 MB\n" +
+		"TOTAL                                                750m   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterWide(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{wide: true}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 3, DesiredReplicas: 3, AvailableReplicas: 3, MinReplicas: 2, MaxReplicas: 6,
+		QOSClass: "Burstable", PriorityClass: "critical", Containers: 2, Nodes: 3, Restarts: 14, Requests: ResourceMetrics{CPU: 750, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "worker", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, DesiredReplicas: 1, AvailableReplicas: 1, MaxReplicas: 1,
+		QOSClass: "Guaranteed", Containers: 1, Nodes: 1, Requests: ResourceMetrics{CPU: 250, Memory: 1048576}})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   DESIRED   AVAILABLE   HPA MIN/MAX   QOS          CONTAINERS   NODES   RESTARTS   PRIORITY CLASS   CPU          MEMORY\n" +
+		"web          shop        3/6        3         3           2/6           Burstable    2            3       14         critical         750m         1.00 MB\n" +
+		"worker       shop        1/1        1         1           -             Guaranteed   1            1       0          -                250m         1.00 MB\n" +
+		"TOTAL                                                                                                                                 1.00 cores   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterGroupByPriorityClass(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{groupBy: GroupByPriorityClass}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1, PriorityClass: "critical",
+		Requests: ResourceMetrics{CPU: 500, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "crawler", Namespace: "batch", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1, PriorityClass: "preemptible",
+		Requests: ResourceMetrics{CPU: 250, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "cart", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 250, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "api", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1, PriorityClass: "critical",
+		Requests: ResourceMetrics{CPU: 250, Memory: 1048576}})
+	p.Flush()
+
+	want := "DEPLOYMENT                  NAMESPACE   REPLICAS   PRIORITY CLASS   CPU          MEMORY\n" +
+		"web                         shop        1/1        critical         500m         1.00 MB\n" +
+		"api                         shop        1/1        critical         250m         1.00 MB\n" +
+		"TOTAL (critical)                                                    750m         2.00 MB\n" +
+		"cart                        shop        1/1        -                250m         1.00 MB\n" +
+		"TOTAL (no priority class)                                           250m         1.00 MB\n" +
+		"crawler                     batch       1/1        preemptible      250m         1.00 MB\n" +
+		"TOTAL (preemptible)                                                 250m         1.00 MB\n" +
+		"TOTAL                                                               1.25 cores   4.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	got, err := parseColumns("cpu, memory,replicas")
+	if err != nil || !reflect.DeepEqual(got, []string{"cpu", "memory", "replicas"}) {
+		t.Errorf("parseColumns() = %v, %v", got, err)
+	}
+	if _, err := parseColumns("cpu,namespace"); err == nil {
+		t.Error("parseColumns() should reject columns that always show")
+	}
+}
+
+func TestTablePrinterColumns(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{groupBy: GroupByNamespace, columns: []string{"cpu"}}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 2, MaxReplicas: 2, Requests: ResourceMetrics{CPU: 500, Memory: 1048576}})
+	p.Flush()
+
+	want := "DEPLOYMENT     NAMESPACE   CPU\n" +
+		"web            shop        500m\n" +
+		"TOTAL (shop)               500m\n" +
+		"TOTAL                      500m\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterUtilization(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeUsage, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 2, MaxReplicas: 2,
+		Usage: ResourceMetrics{CPU: 100, Memory: 524288}, Requests: ResourceMetrics{CPU: 500, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "worker", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Usage: ResourceMetrics{CPU: 300, Memory: 524288}})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU    MEMORY      CPU%   MEM%\n" +
+		"web          shop        2/2        100m   512.00 KB   20%    50%\n" +
+		"worker       shop        1/1        300m   512.00 KB   -      -\n" +
+		"TOTAL                               400m   1.00 MB     80%    100%\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	p = newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", Usage: ResourceMetrics{CPU: 100}, Requests: ResourceMetrics{CPU: 500}})
+	p.Flush()
+	if strings.Contains(buf.String(), "CPU%") {
+		t.Errorf("requests output should not show utilization columns:\n%s", buf.String())
+	}
+}
+
+func TestTablePrinterIdle(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeIdle, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 2, MaxReplicas: 2,
+		Usage: ResourceMetrics{CPU: 100, Memory: 1048576}, Requests: ResourceMetrics{CPU: 1500, Memory: 2097152}})
+	p.Add(DeploymentMetrics{Name: "worker", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Usage: ResourceMetrics{CPU: 300, Memory: 2097152}, Requests: ResourceMetrics{CPU: 200, Memory: 1048576}})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU          MEMORY     CPU%   MEM%\n" +
+		"web          shop        2/2        1.40 cores   1.00 MB    7%     50%\n" +
+		"worker       shop        1/1        -100m        -1.00 MB   150%   200%\n" +
+		"TOTAL                               1.30 cores   0 B        24%    100%\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterAll(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeAll, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 2, MaxReplicas: 4,
+		Usage: ResourceMetrics{CPU: 100, Memory: 1048576}, Requests: ResourceMetrics{CPU: 200, Memory: 2097152},
+		Limits: ResourceMetrics{CPU: 400, Memory: 4194304}, MaxRequests: ResourceMetrics{CPU: 400, Memory: 4194304}})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU USAGE   CPU REQUESTS   CPU LIMITS   CPU MAX   MEMORY USAGE   MEMORY REQUESTS   MEMORY LIMITS   MEMORY MAX   CPU%   MEM%\n" +
+		"web          shop        2/4        100m        200m           400m         400m      1.00 MB        2.00 MB           4.00 MB         4.00 MB      50%    50%\n" +
+		"TOTAL                               100m        200m           400m         400m      1.00 MB        2.00 MB           4.00 MB         4.00 MB      50%    50%\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterBreakdownPod(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeUsage, false, false, FormatTable, tableOptions{breakdown: BreakdownPod, groupBy: GroupByNamespace}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 2, MaxReplicas: 2,
+		Usage: ResourceMetrics{CPU: 500, Memory: 2097152}, Requests: ResourceMetrics{CPU: 400, Memory: 4194304},
+		Owners: []*ownerNode{{Kind: "ReplicaSet", Name: "web-7d4b", Children: []*ownerNode{
+			{Kind: "Pod", Name: "web-7d4b-a", Usage: ResourceMetrics{CPU: 50, Memory: 1048576}, Requests: ResourceMetrics{CPU: 200, Memory: 2097152}},
+			{Kind: "Pod", Name: "web-7d4b-b", Usage: ResourceMetrics{CPU: 450, Memory: 1048576}, Requests: ResourceMetrics{CPU: 200, Memory: 2097152}},
+		}}}})
+	p.Flush()
+
+	want := "DEPLOYMENT       NAMESPACE   REPLICAS   CPU    MEMORY    CPU%   MEM%\n" +
+		"web              shop        2/2        500m   2.00 MB   125%   50%\n" +
+		"  └ web-7d4b-a                          50m    1.00 MB   25%    50%\n" +
+		"  └ web-7d4b-b                          450m   1.00 MB   225%   50%\n" +
+		"TOTAL (shop)                            500m   2.00 MB   125%   50%\n" +
+		"TOTAL                                   500m   2.00 MB   125%   50%\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterBreakdownPodColumns(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeUsage, false, false, FormatTable, tableOptions{breakdown: BreakdownPod, perReplica: true,
+		labels: []string{"team"}, columns: []string{"cpu", "carbon", "cpu-per-replica"}}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 2, MaxReplicas: 2,
+		Usage: ResourceMetrics{CPU: 500, Memory: 2097152}, Requests: ResourceMetrics{CPU: 400, Memory: 4194304},
+		Labels: map[string]string{"team": "checkout"}, CarbonGrams: 1500,
+		Owners: []*ownerNode{{Kind: "ReplicaSet", Name: "web-7d4b", Children: []*ownerNode{
+			{Kind: "Pod", Name: "web-7d4b-a", Usage: ResourceMetrics{CPU: 50, Memory: 1048576}, Requests: ResourceMetrics{CPU: 200, Memory: 2097152}},
+			{Kind: "Pod", Name: "web-7d4b-b", Usage: ResourceMetrics{CPU: 450, Memory: 1048576}, Requests: ResourceMetrics{CPU: 200, Memory: 2097152}},
+		}}}})
+	p.Flush()
+
+	want := "DEPLOYMENT       NAMESPACE   CPU    CO2E      CPU/REPLICA   TEAM\n" +
+		"web              shop        500m   1.50 kg   250m          checkout\n" +
+		"  └ web-7d4b-a               50m                            \n" +
+		"  └ web-7d4b-b               450m                           \n" +
+		"TOTAL                        500m   1.50 kg                 \n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterPerReplica(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{perReplica: true}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 4, MaxReplicas: 4,
+		Requests: ResourceMetrics{CPU: 800, Memory: 8388608}})
+	p.Add(DeploymentMetrics{Name: "api", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 200, Memory: 2097152}})
+	p.Add(DeploymentMetrics{Name: "batch", Namespace: "shop", Type: "Deployment", CurrentReplicas: 0, MaxReplicas: 0})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU          MEMORY     CPU/REPLICA   MEM/REPLICA\n" +
+		"web          shop        4/4        800m         8.00 MB    200m          2.00 MB\n" +
+		"api          shop        1/1        200m         2.00 MB    200m          2.00 MB\n" +
+		"batch        shop        0/0        0m           0 B        -             -\n" +
+		"TOTAL                               1.00 cores   10.00 MB                 \n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterDegraded(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", Source: SourceKubernetes,
+		CurrentReplicas: 3, ReadyReplicas: 2, MaxReplicas: 5, Requests: ResourceMetrics{CPU: 600, Memory: 3145728}})
+	p.Add(DeploymentMetrics{Name: "api", Namespace: "shop", Type: "Deployment", Source: SourceKubernetes,
+		CurrentReplicas: 1, ReadyReplicas: 1, MaxReplicas: 1, Requests: ResourceMetrics{CPU: 200, Memory: 1048576}})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU    MEMORY\n" +
+		"web          shop        2/3/5      600m   3.00 MB\n" +
+		"api          shop        1/1/1      200m   1.00 MB\n" +
+		"TOTAL                               800m   4.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterCronRuns(t *testing.T) {
+	next := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)
+	last := time.Date(2026, 10, 16, 2, 0, 41, 0, time.UTC)
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 200, Memory: 1048576}})
+	p.Add(DeploymentMetrics{Name: "backup", Namespace: "shop", Type: "CronJob", DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 500, Memory: 1048576}, NextRun: &next, LastSuccessfulRun: &last})
+	p.Flush()
+
+	want := "NAME                 TYPE         NAMESPACE   REPLICAS   NEXT RUN               LAST SUCCESS           CPU    MEMORY\n" +
+		"web                  Deployment   shop        1/1        -                      -                      200m   1.00 MB\n" +
+		"backup               CronJob      shop        0/1        2026-10-17 02:00 UTC   2026-10-16 02:00 UTC   500m   1.00 MB\n" +
+		"TOTAL (CronJob)                                                                                        500m   1.00 MB\n" +
+		"TOTAL (Deployment)                                                                                     200m   1.00 MB\n" +
+		"TOTAL                                                                                                  700m   2.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterCarbon(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 200, Memory: 2097152}, CarbonGrams: 794.5})
+	p.Add(DeploymentMetrics{Name: "api", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 200, Memory: 2097152}, CarbonGrams: 1500})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU    MEMORY    CO2E\n" +
+		"web          shop        1/1        200m   2.00 MB   794 g\n" +
+		"api          shop        1/1        200m   2.00 MB   1.50 kg\n" +
+		"TOTAL                               400m   4.00 MB   2.29 kg\n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestTablePrinterLabels(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatTable, tableOptions{labels: []string{"team"}, showLabels: true}, nil)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 200, Memory: 2097152}, Labels: map[string]string{"team": "checkout", "app": "web"}})
+	p.Add(DeploymentMetrics{Name: "api", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 200, Memory: 2097152}})
+	p.Flush()
+
+	want := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU    MEMORY    TEAM       LABELS\n" +
+		"web          shop        1/1        200m   2.00 MB   checkout   app=web,team=checkout\n" +
+		"api          shop        1/1        200m   2.00 MB   -          -\n" +
+		"TOTAL                               400m   4.00 MB              \n"
+	if buf.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPerReplicaCells(t *testing.T) {
+	dm := DeploymentMetrics{CurrentReplicas: 2, DesiredReplicas: 2, MaxReplicas: 5,
+		Usage:       ResourceMetrics{CPU: 300, Memory: 2097152},
+		Requests:    ResourceMetrics{CPU: 400, Memory: 4194304},
+		MaxRequests: ResourceMetrics{CPU: 1000, Memory: 10485760}}
+	tests := []struct {
+		outputType string
+		want       []string
+	}{
+		{OutputTypeUsage, []string{"150m", "1.00 MB"}},
+		{OutputTypeRequests, []string{"200m", "2.00 MB"}},
+		{OutputTypeMaxRequests, []string{"200m", "2.00 MB"}},
+		{OutputTypeIdle, []string{"50m", "1.00 MB"}},
+	}
+	for _, tt := range tests {
+		got := perReplicaCells(tt.outputType, dm)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("perReplicaCells(%s) = %v, want %v", tt.outputType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.txt")
+	if err := os.WriteFile(path, []byte("old report\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new report\n")); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new report\n" {
+		t.Errorf("report = %q, %v, want the new report", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "report.txt"), nil); err == nil {
+		t.Error("writeFileAtomic() into a missing directory should fail")
+	}
+}
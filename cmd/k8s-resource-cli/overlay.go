@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// overlayHour is the projected max-requests for one hour of the day
+type overlayHour struct {
+	Hour      int             `json:"hour"`
+	Serving   ResourceMetrics `json:"serving"`
+	Batch     ResourceMetrics `json:"batch"`
+	Total     ResourceMetrics `json:"total"`
+	BatchPeak bool            `json:"batch_peak,omitempty"`
+	Over      []string        `json:"over_capacity,omitempty"` // resources where total exceeds node allocatable
+}
+
+// overlayPrinter lays CronJob max-requests over the hours of the day their
+// schedules fire, on top of the constant serving (Deployment) max-requests.
+// A CronJob counts in every hour it starts a run in; run duration is unknown.
+type overlayPrinter struct {
+	out      io.Writer
+	format   string
+	meta     *runMetadata
+	serving  ResourceMetrics
+	batch    [hoursPerDay]ResourceMetrics
+	capacity ResourceMetrics // node allocatable, zero if unknown
+}
+
+func newOverlayPrinter(out io.Writer, format string, meta *runMetadata) *overlayPrinter {
+	return &overlayPrinter{out: out, format: format, meta: meta}
+}
+
+// SetCapacity sets the node allocatable the hourly totals are checked against
+func (p *overlayPrinter) SetCapacity(capacity ResourceMetrics) {
+	p.capacity = capacity
+}
+
+func (p *overlayPrinter) Add(dm DeploymentMetrics) {
+	if dm.Schedule == "" {
+		p.serving.add(dm.EffectiveMaxRequests())
+		return
+	}
+
+	schedule, err := parseCronSchedule(dm.Schedule)
+	if err != nil {
+		warnf("Leaving cronjob %s in namespace %s out of the overlay: %v", dm.Name, dm.Namespace, err)
+		return
+	}
+	for hour := 0; hour < hoursPerDay; hour++ {
+		if schedule.hours&(1<<uint(hour)) != 0 {
+			p.batch[hour].add(dm.EffectiveMaxRequests())
+		}
+	}
+}
+
+// hours builds the per-hour rows, marking the hours with the highest batch
+// requests and any hour whose total exceeds capacity
+func (p *overlayPrinter) hours() []overlayHour {
+	var peak ResourceMetrics
+	for _, batch := range p.batch {
+		peak.CPU = max(peak.CPU, batch.CPU)
+		peak.Memory = max(peak.Memory, batch.Memory)
+	}
+
+	hours := make([]overlayHour, hoursPerDay)
+	for hour, batch := range p.batch {
+		h := overlayHour{Hour: hour, Serving: p.serving, Batch: batch, Total: p.serving}
+		h.Total.add(batch)
+		h.BatchPeak = (batch.CPU > 0 && batch.CPU == peak.CPU) || (batch.Memory > 0 && batch.Memory == peak.Memory)
+		if p.capacity.CPU > 0 && h.Total.CPU > p.capacity.CPU {
+			h.Over = append(h.Over, "cpu")
+		}
+		if p.capacity.Memory > 0 && h.Total.Memory > p.capacity.Memory {
+			h.Over = append(h.Over, "memory")
+		}
+		hours[hour] = h
+	}
+	return hours
+}
+
+func (h overlayHour) status() string {
+	var status []string
+	if h.BatchPeak {
+		status = append(status, "batch peak")
+	}
+	if len(h.Over) > 0 {
+		status = append(status, "over capacity ("+strings.Join(h.Over, ", ")+")")
+	}
+	if len(status) == 0 {
+		return "-"
+	}
+	return strings.Join(status, ", ")
+}
+
+func (p *overlayPrinter) Flush() {
+	hours := p.hours()
+
+	if p.format == FormatJSON {
+		output := struct {
+			Metadata *runMetadata    `json:"metadata,omitempty"`
+			Hours    []overlayHour   `json:"hours"`
+			Capacity ResourceMetrics `json:"capacity"`
+		}{Metadata: p.meta, Hours: hours, Capacity: p.capacity}
+		if err := json.NewEncoder(p.out).Encode(output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON output: %v\n", err)
+		}
+		return
+	}
+
+	table := resultTable{headers: []string{"HOUR", "SERVING CPU", "SERVING MEMORY", "BATCH CPU", "BATCH MEMORY", "TOTAL CPU", "TOTAL MEMORY", "STATUS"}}
+	peak := hours[0]
+	for _, h := range hours {
+		table.rows = append(table.rows, []string{
+			fmt.Sprintf("%02d:00", h.Hour),
+			formatCPU(h.Serving.CPU), formatMemory(h.Serving.Memory),
+			formatCPU(h.Batch.CPU), formatMemory(h.Batch.Memory),
+			formatCPU(h.Total.CPU), formatMemory(h.Total.Memory),
+			h.status(),
+		})
+		if h.Total.CPU > peak.Total.CPU || (h.Total.CPU == peak.Total.CPU && h.Total.Memory > peak.Total.Memory) {
+			peak = h
+		}
+	}
+	capacity := "-"
+	if p.capacity.CPU > 0 || p.capacity.Memory > 0 {
+		capacity = fmt.Sprintf("capacity %s / %s", formatCPU(p.capacity.CPU), formatMemory(p.capacity.Memory))
+	}
+	table.total = []string{
+		fmt.Sprintf("PEAK %02d:00", peak.Hour),
+		formatCPU(peak.Serving.CPU), formatMemory(peak.Serving.Memory),
+		formatCPU(peak.Batch.CPU), formatMemory(peak.Batch.Memory),
+		formatCPU(peak.Total.CPU), formatMemory(peak.Total.Memory),
+		capacity,
+	}
+
+	if p.format == FormatMarkdown {
+		printMarkdownResults(p.out, table, false)
+	} else {
+		printTableResults(p.out, table, false)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOverlayPrinterHours(t *testing.T) {
+	p := newOverlayPrinter(&bytes.Buffer{}, FormatTable, nil)
+	p.SetCapacity(ResourceMetrics{CPU: 2000, Memory: 8 << 30})
+
+	p.Add(DeploymentMetrics{Name: "web", Type: "Deployment", DesiredReplicas: 2, MaxReplicas: 4,
+		Requests: ResourceMetrics{CPU: 500, Memory: 1 << 30}, MaxRequests: ResourceMetrics{CPU: 1000, Memory: 2 << 30}})
+	p.Add(DeploymentMetrics{Name: "backup", Type: "CronJob", Schedule: "0 2 * * *", DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 1500, Memory: 1 << 30}, MaxRequests: ResourceMetrics{CPU: 1500, Memory: 1 << 30}})
+	p.Add(DeploymentMetrics{Name: "report", Type: "CronJob", Schedule: "*/30 2,14 * * *", DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 100, Memory: 1 << 28}, MaxRequests: ResourceMetrics{CPU: 100, Memory: 1 << 28}})
+
+	hours := p.hours()
+	if len(hours) != 24 {
+		t.Fatalf("hours() returned %d rows, want 24", len(hours))
+	}
+
+	if got := hours[0].Total; got.CPU != 1000 {
+		t.Errorf("hour 0 total CPU = %d, want serving only (1000)", got.CPU)
+	}
+	if got := hours[2].Batch; got.CPU != 1600 {
+		t.Errorf("hour 2 batch CPU = %d, want 1600", got.CPU)
+	}
+	if got := hours[14].Batch; got.CPU != 100 {
+		t.Errorf("hour 14 batch CPU = %d, want 100", got.CPU)
+	}
+	if !hours[2].BatchPeak || hours[14].BatchPeak {
+		t.Errorf("batch peak should be hour 2 only, got 2=%v 14=%v", hours[2].BatchPeak, hours[14].BatchPeak)
+	}
+	if len(hours[2].Over) != 1 || hours[2].Over[0] != "cpu" {
+		t.Errorf("hour 2 over capacity = %v, want [cpu]", hours[2].Over)
+	}
+	if len(hours[14].Over) != 0 {
+		t.Errorf("hour 14 over capacity = %v, want none", hours[14].Over)
+	}
+}
+
+func TestOverlayPrinterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	p := newOverlayPrinter(&buf, FormatTable, nil)
+	p.Add(DeploymentMetrics{Name: "web", Type: "Deployment", DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 500}, MaxRequests: ResourceMetrics{CPU: 500}})
+	p.Add(DeploymentMetrics{Name: "nightly", Type: "CronJob", Schedule: "@daily", DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 250}, MaxRequests: ResourceMetrics{CPU: 250}})
+	p.Flush()
+
+	output := buf.String()
+	for _, want := range []string{"HOUR", "00:00", "batch peak", "PEAK 00:00", "750m"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Flush() output missing %q:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ownerNode is one level of a workload's owner graph, e.g. a ReplicaSet of a
+// Deployment or a Pod of a Job, with the requests and usage of the pods
+// beneath it. Downstream tools can re-aggregate at any level from it.
+type ownerNode struct {
+	Kind     string          `json:"kind"`
+	Name     string          `json:"name"`
+	UID      string          `json:"uid,omitempty"`
+	Requests ResourceMetrics `json:"requests"`
+	Usage    ResourceMetrics `json:"usage"`
+	Children []*ownerNode    `json:"children,omitempty"`
+}
+
+// buildOwnerTree groups pods under their controlling owner of the given kind
+// (ReplicaSet for Deployments, Job for CronJobs). Pods without such an owner
+// are attached directly. Owners and pods are sorted by name.
+func buildOwnerTree(ownerKind string, pods []corev1.Pod, usage *podUsageIndex) []*ownerNode {
+	owners := make(map[string]*ownerNode)
+	var roots []*ownerNode
+
+	for _, pod := range pods {
+		podNode := &ownerNode{Kind: "Pod", Name: pod.Name, UID: string(pod.UID), Requests: podRequests(pod)}
+		if podUsage, ok := usage.Pod(pod.Namespace, pod.Name); ok {
+			podNode.Usage = podUsage
+		}
+
+		ref := metav1.GetControllerOf(&pod)
+		if ref == nil || ref.Kind != ownerKind {
+			roots = append(roots, podNode)
+			continue
+		}
+		owner, ok := owners[ref.Name]
+		if !ok {
+			owner = &ownerNode{Kind: ref.Kind, Name: ref.Name, UID: string(ref.UID)}
+			owners[ref.Name] = owner
+			roots = append(roots, owner)
+		}
+		owner.Children = append(owner.Children, podNode)
+		owner.Requests.add(podNode.Requests)
+		owner.Usage.add(podNode.Usage)
+	}
+
+	sortOwnerNodes(roots)
+	for _, owner := range owners {
+		sortOwnerNodes(owner.Children)
+	}
+	return roots
+}
+
+// ownerPods returns the pods of an owner graph in graph order
+func ownerPods(nodes []*ownerNode) []*ownerNode {
+	var pods []*ownerNode
+	for _, node := range nodes {
+		if node.Kind == "Pod" {
+			pods = append(pods, node)
+		}
+		pods = append(pods, ownerPods(node.Children)...)
+	}
+	return pods
+}
+
+func sortOwnerNodes(nodes []*ownerNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Kind != nodes[j].Kind {
+			return nodes[i].Kind < nodes[j].Kind
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func ownedPod(name, ownerKind, ownerName, cpu string) corev1.Pod {
+	controller := true
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:      "app",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}}},
+	}
+	if ownerKind != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, UID: types.UID("uid-" + ownerName), Controller: &controller}}
+	}
+	return pod
+}
+
+func TestBuildOwnerTree(t *testing.T) {
+	pods := []corev1.Pod{
+		ownedPod("web-new-b", "ReplicaSet", "web-new", "200m"),
+		ownedPod("web-old-a", "ReplicaSet", "web-old", "100m"),
+		ownedPod("web-new-a", "ReplicaSet", "web-new", "200m"),
+		ownedPod("stray", "", "", "50m"),
+	}
+	usage := &podUsageIndex{byNamespace: map[string]map[string]ResourceMetrics{
+		"shop": {"web-new-a": {CPU: 150}, "web-new-b": {CPU: 50}},
+	}}
+
+	tree := buildOwnerTree("ReplicaSet", pods, usage)
+	if len(tree) != 3 {
+		t.Fatalf("buildOwnerTree() returned %d roots, want 3", len(tree))
+	}
+
+	// Sorted by kind, then name: the ownerless pod comes first
+	if tree[0].Kind != "Pod" || tree[0].Name != "stray" {
+		t.Errorf("tree[0] = %s/%s, want Pod/stray", tree[0].Kind, tree[0].Name)
+	}
+
+	rs := tree[1]
+	if rs.Kind != "ReplicaSet" || rs.Name != "web-new" || rs.UID != "uid-web-new" {
+		t.Errorf("tree[1] = %s/%s (%s), want ReplicaSet/web-new", rs.Kind, rs.Name, rs.UID)
+	}
+	if rs.Requests.CPU != 400 || rs.Usage.CPU != 200 {
+		t.Errorf("web-new requests/usage = %d/%d, want 400/200", rs.Requests.CPU, rs.Usage.CPU)
+	}
+	if len(rs.Children) != 2 || rs.Children[0].Name != "web-new-a" {
+		t.Errorf("web-new children = %+v, want web-new-a then web-new-b", rs.Children)
+	}
+
+	if tree[2].Name != "web-old" || tree[2].Requests.CPU != 100 {
+		t.Errorf("tree[2] = %+v, want web-old with 100m", tree[2])
+	}
+}
+
+func TestOwnerTreeJSON(t *testing.T) {
+	tree := buildOwnerTree("Job", []corev1.Pod{ownedPod("backup-1-x", "Job", "backup-1", "1")}, nil)
+	data, err := json.Marshal(DeploymentMetrics{Name: "backup", Type: "CronJob", Owners: tree})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"owners":[{"kind":"Job","name":"backup-1","uid":"uid-backup-1","requests":{"cpu_millicores":1000,"memory_bytes":0},"usage":{"cpu_millicores":0,"memory_bytes":0},"children":[{"kind":"Pod","name":"backup-1-x"`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("JSON = %s\nwant it to contain %s", data, want)
+	}
+
+	data, _ = json.Marshal(DeploymentMetrics{Name: "web"})
+	if strings.Contains(string(data), "owners") {
+		t.Errorf("JSON without an owner graph should omit owners: %s", data)
+	}
+}
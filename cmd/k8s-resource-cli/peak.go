@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Annotations a team can put on a Deployment or CronJob to record the peak
+// the whole workload is expected to reach, in Kubernetes quantities
+const (
+	expectedPeakCPUAnnotation    = "capacity.k8s-resource-cli/expected-peak-cpu"
+	expectedPeakMemoryAnnotation = "capacity.k8s-resource-cli/expected-peak-memory"
+)
+
+// expectedPeak reads the expected-peak annotations, returning nil when
+// neither is set. A resource without an annotation is left at zero.
+func expectedPeak(annotations map[string]string) (*ResourceMetrics, error) {
+	cpu, hasCPU := annotations[expectedPeakCPUAnnotation]
+	memory, hasMemory := annotations[expectedPeakMemoryAnnotation]
+	if !hasCPU && !hasMemory {
+		return nil, nil
+	}
+
+	var peak ResourceMetrics
+	var err error
+	if hasCPU {
+		if peak.CPU, err = parseResourceValue(cpu, true); err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': %w", expectedPeakCPUAnnotation, cpu, err)
+		}
+	}
+	if hasMemory {
+		if peak.Memory, err = parseResourceValue(memory, false); err != nil {
+			return nil, fmt.Errorf("invalid %s '%s': %w", expectedPeakMemoryAnnotation, memory, err)
+		}
+	}
+	return &peak, nil
+}
+
+// checkExpectedPeak compares a workload's expected peak with what was
+// measured and what it can request at max scale. Usage more than tolerance
+// percent above the expectation means the expectation is out of date; an
+// expectation above max-requests means the workload isn't sized to reach it.
+// Usage is only measured at report time, so runs at peak hours are the
+// ones that catch an underestimate.
+func checkExpectedPeak(dm DeploymentMetrics, tolerance int) []string {
+	if dm.ExpectedPeak == nil {
+		return nil
+	}
+
+	var problems []string
+	check := func(resource string, expected, usage, maxRequests int64, format func(int64) string) {
+		if expected <= 0 {
+			return
+		}
+		if usage > expected*int64(100+tolerance)/100 {
+			problems = append(problems, fmt.Sprintf(
+				"%s usage %s is %d%% above the expected peak of %s",
+				resource, format(usage), (usage-expected)*100/expected, format(expected)))
+		}
+		if maxRequests > 0 && expected > maxRequests {
+			problems = append(problems, fmt.Sprintf(
+				"expected %s peak %s is more than the %s requested at max replicas",
+				resource, format(expected), format(maxRequests)))
+		}
+	}
+	maxRequests := dm.EffectiveMaxRequests()
+	check("CPU", dm.ExpectedPeak.CPU, dm.Usage.CPU, maxRequests.CPU, formatCPU)
+	check("memory", dm.ExpectedPeak.Memory, dm.Usage.Memory, maxRequests.Memory, formatMemory)
+	return problems
+}
+
+// peakChecker collects expected-peak deviations for printing after the report
+type peakChecker struct {
+	tolerance int
+	findings  []string
+}
+
+func (c *peakChecker) Add(dm DeploymentMetrics) {
+	for _, problem := range checkExpectedPeak(dm, c.tolerance) {
+		c.findings = append(c.findings, fmt.Sprintf("%s/%s: %s", dm.Namespace, dm.Name, problem))
+	}
+}
+
+func (c *peakChecker) Print(out io.Writer) {
+	for _, finding := range c.findings {
+		fmt.Fprintf(out, "Warning: expected peak %s\n", finding)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExpectedPeak(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        *ResourceMetrics
+		wantErr     bool
+	}{
+		{name: "no annotations"},
+		{
+			name:        "both",
+			annotations: map[string]string{expectedPeakCPUAnnotation: "1500m", expectedPeakMemoryAnnotation: "2Gi"},
+			want:        &ResourceMetrics{CPU: 1500, Memory: 2 << 30},
+		},
+		{
+			name:        "cpu only",
+			annotations: map[string]string{expectedPeakCPUAnnotation: "2"},
+			want:        &ResourceMetrics{CPU: 2000},
+		},
+		{
+			name:        "invalid cpu",
+			annotations: map[string]string{expectedPeakCPUAnnotation: "lots"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expectedPeak(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expectedPeak() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("expectedPeak() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckExpectedPeak(t *testing.T) {
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want []string
+	}{
+		{
+			name: "no expectation",
+			dm:   DeploymentMetrics{Usage: ResourceMetrics{CPU: 5000}},
+		},
+		{
+			name: "within tolerance",
+			dm: DeploymentMetrics{ExpectedPeak: &ResourceMetrics{CPU: 1000}, Usage: ResourceMetrics{CPU: 1050},
+				DesiredReplicas: 2, MaxReplicas: 2, Requests: ResourceMetrics{CPU: 2000}},
+		},
+		{
+			name: "usage above expectation",
+			dm: DeploymentMetrics{ExpectedPeak: &ResourceMetrics{Memory: 1 << 30}, Usage: ResourceMetrics{Memory: 3 << 29},
+				DesiredReplicas: 2, MaxReplicas: 2, Requests: ResourceMetrics{Memory: 4 << 30}},
+			want: []string{"50% above"},
+		},
+		{
+			name: "expectation above max requests",
+			dm: DeploymentMetrics{ExpectedPeak: &ResourceMetrics{CPU: 4000},
+				DesiredReplicas: 2, MaxReplicas: 4, Requests: ResourceMetrics{CPU: 1000}, MaxRequests: ResourceMetrics{CPU: 2000}},
+			want: []string{"max replicas"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkExpectedPeak(tt.dm, 10)
+			if len(got) != len(tt.want) {
+				t.Fatalf("checkExpectedPeak() = %v, want %d problems", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("checkExpectedPeak()[%d] = %q, want it to mention %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPeakCheckerPrint(t *testing.T) {
+	c := &peakChecker{}
+	c.Add(DeploymentMetrics{Name: "api", Namespace: "shop", ExpectedPeak: &ResourceMetrics{CPU: 100}, Usage: ResourceMetrics{CPU: 200}})
+
+	var buf bytes.Buffer
+	c.Print(&buf)
+	if !strings.HasPrefix(buf.String(), "Warning: expected peak shop/api: CPU usage 200m is 100% above") {
+		t.Errorf("Print() = %q", buf.String())
+	}
+}
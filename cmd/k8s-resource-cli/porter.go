@@ -2,25 +2,32 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Labels Porter puts on the pods it runs for an application's services
+const (
+	porterAppNameLabel     = "porter.run/app-name"
+	porterServiceNameLabel = "porter.run/service-name"
 )
 
-func getPorterApplicationMetrics(ctx context.Context, client *PorterClient, appName string) ([]DeploymentMetrics, error) {
+func getPorterApplicationMetrics(ctx context.Context, client *PorterClient, appName string, withK8s bool, emit func(DeploymentMetrics)) error {
 	// List all applications
 	apps, err := client.ListApplications(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list applications: %w", err)
+		return fmt.Errorf("failed to list applications: %w", err)
 	}
 
-	var deployments []DeploymentMetrics
-
 	totalApps := len(apps)
-	spinnerChars := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	// Clusters whose metrics client couldn't be built, warned about once
+	failedClusters := make(map[int]bool)
 
 	for i, app := range apps {
 		// Skip if filtering by name
@@ -28,22 +35,26 @@ func getPorterApplicationMetrics(ctx context.Context, client *PorterClient, appN
 			continue
 		}
 
-		// Show progress indicator with spinner
-		spinner := spinnerChars[i%len(spinnerChars)]
-		showProgress(os.Stderr, spinner, i+1, totalApps, app.Name)
+		progress.Show("application", i+1, totalApps, app.Name)
+
+		// Past the deadline every remaining request would fail the same way
+		if err := ctx.Err(); err != nil {
+			progress.Clear()
+			return fmt.Errorf("stopped at application %d/%d: %w", i+1, totalApps, err)
+		}
 
 		// Get application details
 		detail, err := client.GetApplication(ctx, app.ID)
 		if err != nil {
-			// Clear spinner line before showing warning
-			clearProgress(os.Stderr)
-			fmt.Fprintf(os.Stderr, "Warning: Error getting application %s: %v\n", app.Name, err)
+			partialf("Error getting application %s: %v", app.Name, err)
 			continue
 		}
 
 		// Get deployment target info for cluster name
 		clusterName := detail.DeploymentTargetID // fallback to ID
+		clusterID := 0
 		if target, err := client.GetDeploymentTarget(ctx, detail.DeploymentTargetID); err == nil {
+			clusterID = target.ClusterID
 			if target.Name != "" {
 				clusterName = target.Name
 
@@ -57,10 +68,8 @@ func getPorterApplicationMetrics(ctx context.Context, client *PorterClient, appN
 					}
 				}
 			}
-		} else if client.Debug {
-			// Clear spinner line before showing debug message
-			clearProgress(os.Stderr)
-			fmt.Fprintf(os.Stderr, "DEBUG - Error getting deployment target %s: %v\n", detail.DeploymentTargetID, err)
+		} else {
+			debugf(verbosityDecisions, "Error getting deployment target %s: %v", detail.DeploymentTargetID, err)
 		}
 
 		// Process each service in the application
@@ -68,18 +77,34 @@ func getPorterApplicationMetrics(ctx context.Context, client *PorterClient, appN
 			// Determine min and max replicas
 			minReplicas := service.Instances
 			maxReplicas := service.Instances
+			var targetCPU, targetMemory int32
 			if service.Autoscaling != nil && service.Autoscaling.Enabled {
 				minReplicas = service.Autoscaling.MinInstances
 				maxReplicas = service.Autoscaling.MaxInstances
+				targetCPU = service.Autoscaling.CPUThresholdPercent
+				targetMemory = service.Autoscaling.MemoryThresholdPercent
 			}
 
 			dm := DeploymentMetrics{
 				Name:            fmt.Sprintf("%s-%s", app.Name, service.Name),
 				Namespace:       clusterName,
 				Type:            "Deployment",
+				Source:          SourcePorter,
 				CurrentReplicas: service.Instances,
-				DesiredReplicas: minReplicas,
-				MaxReplicas:     maxReplicas,
+				// Porter doesn't report readiness, so instances count as ready
+				ReadyReplicas:     service.Instances,
+				AvailableReplicas: service.Instances,
+				DesiredReplicas:   minReplicas,
+				MaxReplicas:       maxReplicas,
+				TargetCPU:         targetCPU,
+				TargetMemory:      targetMemory,
+				MachineType:       service.MachineType,
+			}
+			if service.Autoscaling != nil && service.Autoscaling.Enabled {
+				dm.MinReplicas = minReplicas
+			}
+			if service.GPU != nil && service.GPU.Enabled {
+				dm.GPUs = service.GPU.GPUCoresNvidia
 			}
 
 			// Convert CPU cores to millicores and memory MB to bytes
@@ -94,14 +119,59 @@ func getPorterApplicationMetrics(ctx context.Context, client *PorterClient, appN
 			dm.MaxRequests.CPU = cpuMillis * int64(maxReplicas)
 			dm.MaxRequests.Memory = memoryBytes * int64(maxReplicas)
 
-			deployments = append(deployments, dm)
+			// Enrich with live usage from the target cluster's metrics-server
+			if withK8s && clusterID != 0 {
+				usage, err := getPorterServiceUsage(ctx, client, clusterID, app.Name, service.Name)
+				if _, clusterFailed := client.metricsClientErrors[clusterID]; clusterFailed {
+					if !failedClusters[clusterID] {
+						failedClusters[clusterID] = true
+						partialf("Error getting usage from cluster %d, its services have none: %v", clusterID, err)
+					}
+				} else if err != nil {
+					partialf("Error getting usage for %s: %v", dm.Name, err)
+				} else {
+					dm.Usage = usage
+				}
+			}
+
+			emit(dm)
 		}
 	}
 
-	// Clear the progress indicator
-	fmt.Fprintf(os.Stderr, "\r\033[K")
+	progress.Clear()
+
+	return nil
+}
+
+// getPorterServiceUsage sums live container usage for the pods of one
+// Porter service, using the cluster kubeconfig handed out by the Porter API
+func getPorterServiceUsage(ctx context.Context, client *PorterClient, clusterID int, appName, serviceName string) (ResourceMetrics, error) {
+	var usage ResourceMetrics
+
+	metricsClientset, err := client.GetClusterMetricsClient(ctx, clusterID)
+	if err != nil {
+		return usage, err
+	}
+
+	podMetricsList, err := metricsClientset.MetricsV1beta1().PodMetricses("").List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s", porterAppNameLabel, appName, porterServiceNameLabel, serviceName),
+	})
+	if err != nil {
+		return usage, fmt.Errorf("error getting pod metrics: %w", err)
+	}
+
+	for _, podMetrics := range podMetricsList.Items {
+		for _, container := range podMetrics.Containers {
+			if cpu := container.Usage.Cpu(); cpu != nil {
+				usage.CPU += cpu.MilliValue()
+			}
+			if memory := container.Usage.Memory(); memory != nil {
+				usage.Memory += memory.Value()
+			}
+		}
+	}
 
-	return deployments, nil
+	return usage, nil
 }
 
 func (c *PorterClient) ListApplications(ctx context.Context) ([]PorterApplication, error) {
@@ -196,6 +266,49 @@ func (c *PorterClient) loadClusters(ctx context.Context) error {
 	return nil
 }
 
+// GetClusterMetricsClient returns a metrics client for the cluster, built from
+// the temporary kubeconfig Porter issues for it (with caching). A cluster
+// whose client can't be built fails the same way for every later service.
+func (c *PorterClient) GetClusterMetricsClient(ctx context.Context, clusterID int) (*versioned.Clientset, error) {
+	if metricsClientset, ok := c.metricsClientCache[clusterID]; ok {
+		return metricsClientset, nil
+	}
+	if err, ok := c.metricsClientErrors[clusterID]; ok {
+		return nil, err
+	}
+
+	metricsClientset, err := c.newClusterMetricsClient(ctx, clusterID)
+	if err != nil {
+		c.metricsClientErrors[clusterID] = err
+		return nil, err
+	}
+	c.metricsClientCache[clusterID] = metricsClientset
+	return metricsClientset, nil
+}
+
+func (c *PorterClient) newClusterMetricsClient(ctx context.Context, clusterID int) (*versioned.Clientset, error) {
+
+	url := fmt.Sprintf("%s/api/projects/%s/clusters/%d/kubeconfig", c.BaseURL, c.ProjectID, clusterID)
+
+	var response PorterKubeconfigResponse
+	if err := c.doAPIRequest(ctx, "GET", url, &response); err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig for cluster %d: %w", clusterID, err)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(response.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig for cluster %d: %w", clusterID, err)
+	}
+	// A hung cluster gets as long as a Porter API request, --porter-timeout
+	config.Timeout = c.HTTPClient.Timeout
+
+	metricsClientset, err := versioned.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating metrics client for cluster %d: %w", clusterID, err)
+	}
+	return metricsClientset, nil
+}
+
 func (c *PorterClient) doAPIRequest(ctx context.Context, method, url string, result interface{}) error {
 	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
@@ -204,6 +317,12 @@ func (c *PorterClient) doAPIRequest(ctx context.Context, method, url string, res
 
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("Content-Type", "application/json")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	for name, values := range c.Headers {
+		req.Header[name] = values
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -220,17 +339,51 @@ func (c *PorterClient) doAPIRequest(ctx context.Context, method, url string, res
 		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	if c.Debug {
-		fmt.Fprintf(os.Stderr, "DEBUG - %s %s Raw Response:\n%s\n\n", method, url, string(body))
+	debugf(verbosityPayloads, "%s %s Response (redacted):\n%s\n", method, url, redactPorterBody(body, result))
+
+	report, err := decodePorterResponse(body, result)
+	if err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
+	if len(report.Missing) > 0 {
+		partialf("Porter API response for %s lacks %s, which will read as zero; the API may have changed",
+			url, strings.Join(report.Missing, ", "))
+	}
+	if len(report.Unknown) > 0 {
+		debugf(verbosityDecisions, "%s %s fields not understood: %s", method, url, strings.Join(report.Unknown, ", "))
+	}
+	return nil
+}
 
-	return json.Unmarshal(body, result)
+// porterUserAgent identifies the tool and its version to Porter and any proxy on the way
+func porterUserAgent() string {
+	return "k8s-resource-cli/" + version
 }
 
-func showProgress(stderr interface{ Write([]byte) (int, error) }, spinner string, current, total int, name string) {
-	fmt.Fprintf(stderr, "\r%s Loading application %d/%d: %s...\033[K", spinner, current, total, name)
+// headerFlag collects repeated "Name: value" flags into an http.Header
+type headerFlag struct {
+	header http.Header
 }
 
-func clearProgress(stderr interface{ Write([]byte) (int, error) }) {
-	fmt.Fprintf(stderr, "\r\033[K")
+func (f *headerFlag) String() string {
+	var pairs []string
+	for _, name := range sortedKeys(f.header) {
+		for _, value := range f.header[name] {
+			pairs = append(pairs, name+": "+value)
+		}
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (f *headerFlag) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("expected 'Name: value', got %q", value)
+	}
+	if f.header == nil {
+		f.header = make(http.Header)
+	}
+	f.header.Add(name, strings.TrimSpace(headerValue))
+	return nil
 }
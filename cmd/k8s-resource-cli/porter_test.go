@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+func TestHeaderFlag(t *testing.T) {
+	var f headerFlag
+	for _, value := range []string{"X-Proxy-Token: abc", "x-team:platform ", "X-Proxy-Token: def"} {
+		if err := f.Set(value); err != nil {
+			t.Fatalf("Set(%q) error = %v", value, err)
+		}
+	}
+	if got := f.header.Values("X-Proxy-Token"); len(got) != 2 || got[0] != "abc" || got[1] != "def" {
+		t.Errorf("X-Proxy-Token = %v, want [abc def]", got)
+	}
+	if got := f.header.Get("X-Team"); got != "platform" {
+		t.Errorf("X-Team = %q, want platform", got)
+	}
+	if got, want := f.String(), "X-Proxy-Token: abc, X-Proxy-Token: def, X-Team: platform"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"no-colon", ": value"} {
+		if err := f.Set(bad); err == nil {
+			t.Errorf("Set(%q) should return an error", bad)
+		}
+	}
+}
+
+func TestPorterClientHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte(`{"applications":[]}`))
+	}))
+	defer server.Close()
+
+	client := &PorterClient{
+		BaseURL:    server.URL,
+		Token:      "token",
+		ProjectID:  "1",
+		HTTPClient: server.Client(),
+		UserAgent:  "k8s-resource-cli/1.2.3",
+		Headers:    http.Header{"X-Proxy-Token": {"secret"}},
+	}
+	if _, err := client.ListApplications(context.Background()); err != nil {
+		t.Fatalf("ListApplications() error = %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"Authorization": "Bearer token",
+		"User-Agent":    "k8s-resource-cli/1.2.3",
+		"X-Proxy-Token": "secret",
+	} {
+		if got.Get(name) != want {
+			t.Errorf("%s header = %q, want %q", name, got.Get(name), want)
+		}
+	}
+}
+
+func TestPorterClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/applications") {
+			w.Write([]byte(`{"applications":[{"id":"1","name":"a"},{"id":"2","name":"b"}]}`))
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := &PorterClient{
+		BaseURL:               server.URL,
+		ProjectID:             "1",
+		HTTPClient:            &http.Client{Timeout: 20 * time.Millisecond},
+		deploymentTargetCache: make(map[string]*PorterDeploymentTarget),
+		clusterCache:          make(map[int]*PorterCluster),
+	}
+	if _, err := client.GetApplication(context.Background(), "1"); err == nil {
+		t.Error("GetApplication() on a hung endpoint should time out")
+	}
+
+	client.HTTPClient = server.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := getPorterApplicationMetrics(ctx, client, "", false, func(DeploymentMetrics) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("getPorterApplicationMetrics() past the deadline error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestPorterServiceUsage(t *testing.T) {
+	var kubeconfigRequests int
+	var selector string
+	hang := false
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/projects/1/clusters/7/kubeconfig":
+			kubeconfigRequests++
+			kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: porter
+clusters:
+- name: porter
+  cluster: {server: %q}
+contexts:
+- name: porter
+  context: {cluster: porter}
+`, serverURL)
+			json.NewEncoder(w).Encode(PorterKubeconfigResponse{Kubeconfig: []byte(kubeconfig)})
+		case r.URL.Path == "/api/projects/1/clusters/8/kubeconfig":
+			kubeconfigRequests++
+			http.Error(w, "cluster unreachable", http.StatusBadGateway)
+		case r.URL.Path == "/apis/metrics.k8s.io/v1beta1/pods":
+			if hang {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				return
+			}
+			selector = r.URL.Query().Get("labelSelector")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"kind":"PodMetricsList","apiVersion":"metrics.k8s.io/v1beta1","items":[
+				{"metadata":{"name":"web-1","namespace":"default"},"containers":[{"name":"web","usage":{"cpu":"120m","memory":"64Mi"}}]},
+				{"metadata":{"name":"web-2","namespace":"default"},"containers":[{"name":"web","usage":{"cpu":"80m","memory":"32Mi"}}]}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL = server.URL
+
+	client := &PorterClient{
+		BaseURL:             server.URL,
+		ProjectID:           "1",
+		HTTPClient:          &http.Client{Timeout: 50 * time.Millisecond},
+		metricsClientCache:  make(map[int]*versioned.Clientset),
+		metricsClientErrors: make(map[int]error),
+	}
+
+	usage, err := getPorterServiceUsage(context.Background(), client, 7, "shop", "web")
+	if err != nil {
+		t.Fatalf("getPorterServiceUsage() error = %v", err)
+	}
+	if usage != (ResourceMetrics{CPU: 200, Memory: 96 << 20}) {
+		t.Errorf("usage = %+v, want CPU 200, Memory 96Mi", usage)
+	}
+	if want := "porter.run/app-name=shop,porter.run/service-name=web"; selector != want {
+		t.Errorf("labelSelector = %q, want %q", selector, want)
+	}
+
+	// A hung cluster times out like a Porter API request
+	hang = true
+	start := time.Now()
+	if _, err := getPorterServiceUsage(context.Background(), client, 7, "shop", "worker"); err == nil {
+		t.Error("getPorterServiceUsage() on a hung cluster should time out")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("getPorterServiceUsage() on a hung cluster took %v, want the 50ms timeout", elapsed)
+	}
+
+	// A cluster whose kubeconfig can't be fetched is only asked for once
+	for range 3 {
+		if _, err := getPorterServiceUsage(context.Background(), client, 8, "shop", "web"); err == nil {
+			t.Error("getPorterServiceUsage() without a kubeconfig should fail")
+		}
+	}
+	if kubeconfigRequests != 2 {
+		t.Errorf("kubeconfig requests = %d, want one per cluster", kubeconfigRequests)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// The Porter API structs follow the v2 alpha API, which changes shape now
+// and then. Responses are decoded leniently: camelCase keys, as protobuf
+// JSON writes them, are accepted for snake_case fields, fields the structs
+// don't know are noted for -v, and fields tagged porter:"required"
+// that are missing are reported so they don't silently read as zero.
+// Fields tagged porter:"secret" are never dumped at -v=4.
+
+// porterSchemaReport lists where a response strayed from the structs, as
+// JSON paths like services[0].cpu_cores
+type porterSchemaReport struct {
+	Unknown []string
+	Missing []string
+}
+
+// decodePorterResponse decodes body into result, mapping renamed keys and
+// collecting unknown and missing fields
+func decodePorterResponse(body []byte, result interface{}) (porterSchemaReport, error) {
+	var report porterSchemaReport
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return report, err
+	}
+
+	normalized := normalizePorterJSON(raw, reflect.TypeOf(result), "", &report)
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return report, err
+	}
+	return report, json.Unmarshal(data, result)
+}
+
+// normalizePorterJSON walks a decoded JSON value alongside the Go type it
+// is meant for, renaming camelCase object keys to the snake_case field
+// they stand for
+func normalizePorterJSON(value interface{}, t reflect.Type, path string, report *porterSchemaReport) interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		fields := porterJSONFields(t)
+		out := make(map[string]interface{}, len(object))
+		for key, item := range object {
+			name := key
+			if _, known := fields[name]; !known {
+				snake := camelToSnake(key)
+				if _, known := fields[snake]; !known || object[snake] != nil {
+					report.Unknown = append(report.Unknown, joinJSONPath(path, key))
+					continue
+				}
+				name = snake
+			}
+			out[name] = normalizePorterJSON(item, fields[name].Type, joinJSONPath(path, name), report)
+		}
+		for _, name := range sortedKeys(fields) {
+			if _, present := out[name]; !present && fields[name].Tag.Get("porter") == "required" {
+				report.Missing = append(report.Missing, joinJSONPath(path, name))
+			}
+		}
+		return out
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok || t.Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+		for i, item := range items {
+			items[i] = normalizePorterJSON(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), report)
+		}
+		return items
+	}
+	return value
+}
+
+// porterJSONFields maps the JSON names of a struct's exported fields to the fields
+func porterJSONFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// camelToSnake turns deploymentTargetId into deployment_target_id
+func camelToSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sensitiveKeyParts mark fields whose values are redacted from dumped
+// responses whatever the structs say about them
+var sensitiveKeyParts = []string{"token", "secret", "password", "credential", "kubeconfig", "key", "cert"}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactPorterBody renders a response for -v=4. Dumping is allowlisted:
+// only values of the fields the structs for result know are shown, and not
+// those tagged porter:"secret", named like a credential, or holding a URL.
+// Everything else keeps its key, so the shape of the response is still
+// there to debug, but its value reads REDACTED.
+func redactPorterBody(body []byte, result interface{}) string {
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return fmt.Sprintf("(%d bytes that are not JSON, not shown)", len(body))
+	}
+	data, err := json.MarshalIndent(redactPorterJSON(raw, reflect.TypeOf(result)), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("(%d bytes, not shown: %v)", len(body), err)
+	}
+	return string(data)
+}
+
+func redactPorterJSON(value interface{}, t reflect.Type) interface{} {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		var fields map[string]reflect.StructField
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = porterJSONFields(t)
+		}
+		out := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			field, known := fields[key]
+			if !known {
+				field, known = fields[camelToSnake(key)]
+			}
+			if !known || field.Tag.Get("porter") == "secret" || isSensitiveKey(key) {
+				out[key] = redactedValue
+				continue
+			}
+			out[key] = redactPorterJSON(item, field.Type)
+		}
+		return out
+	case []interface{}:
+		var elem reflect.Type
+		if t != nil && t.Kind() == reflect.Slice {
+			elem = t.Elem()
+		}
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = redactPorterJSON(item, elem)
+		}
+		return out
+	case string:
+		if strings.Contains(value, "://") {
+			return redactedValue
+		}
+	}
+	return value
+}
+
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
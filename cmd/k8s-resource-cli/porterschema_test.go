@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDecodePorterResponse(t *testing.T) {
+	body := `{
+		"id": "app-1",
+		"name": "web",
+		"deploymentTargetId": "dt-1",
+		"createdAt": "2026-10-01",
+		"services": [
+			{"name": "api", "cpuCores": 0.5, "ramMegabytes": 512, "instances": 2,
+			 "autoscaling": {"enabled": true, "minInstances": 1, "max_instances": 5}},
+			{"name": "worker", "cpu": "250m", "ram_megabytes": 256}
+		]
+	}`
+
+	var app PorterApplicationDetail
+	report, err := decodePorterResponse([]byte(body), &app)
+	if err != nil {
+		t.Fatalf("decodePorterResponse() error = %v", err)
+	}
+
+	if app.DeploymentTargetID != "dt-1" {
+		t.Errorf("DeploymentTargetID = %q, want dt-1 from camelCase key", app.DeploymentTargetID)
+	}
+	api := app.Services[0]
+	if api.CPUCores != 0.5 || api.RAMMegabytes != 512 || api.Instances != 2 {
+		t.Errorf("api service = %+v, want camelCase fields decoded", api)
+	}
+	if api.Autoscaling == nil || api.Autoscaling.MinInstances != 1 || api.Autoscaling.MaxInstances != 5 {
+		t.Errorf("api autoscaling = %+v, want min 1 max 5", api.Autoscaling)
+	}
+
+	if want := []string{"createdAt", "services[1].cpu"}; !reflect.DeepEqual(slices.Sorted(slices.Values(report.Unknown)), want) {
+		t.Errorf("Unknown = %v, want %v", report.Unknown, want)
+	}
+	if want := []string{"services[1].cpu_cores"}; !reflect.DeepEqual(report.Missing, want) {
+		t.Errorf("Missing = %v, want %v", report.Missing, want)
+	}
+}
+
+func TestDecodePorterResponseInvalid(t *testing.T) {
+	var app PorterApplicationDetail
+	if _, err := decodePorterResponse([]byte(`{"services": [`), &app); err == nil {
+		t.Error("decodePorterResponse() on truncated JSON should return an error")
+	}
+}
+
+func TestCamelToSnake(t *testing.T) {
+	for in, want := range map[string]string{
+		"cpuCores":           "cpu_cores",
+		"deploymentTargetId": "deployment_target_id",
+		"name":               "name",
+		"ram_megabytes":      "ram_megabytes",
+	} {
+		if got := camelToSnake(in); got != want {
+			t.Errorf("camelToSnake(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRedactPorterBody(t *testing.T) {
+	body := []byte(`{"applications":[{"id":"app-1","name":"web","webhookUrl":"https://internal.example.com/hook","deployToken":"abc123"}],"nextPage":"2"}`)
+	dump := redactPorterBody(body, &PorterListApplicationsResponse{})
+	for _, secret := range []string{"internal.example.com", "abc123", `"2"`} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump contains %s:\n%s", secret, dump)
+		}
+	}
+	for _, kept := range []string{`"id": "app-1"`, `"name": "web"`, `"webhookUrl": "REDACTED"`, `"nextPage": "REDACTED"`} {
+		if !strings.Contains(dump, kept) {
+			t.Errorf("dump lacks %s:\n%s", kept, dump)
+		}
+	}
+
+	dump = redactPorterBody([]byte(`{"kubeconfig":"YXBpVmVyc2lvbjogdjE="}`), &PorterKubeconfigResponse{})
+	if strings.Contains(dump, "YXBp") {
+		t.Errorf("kubeconfig dumped:\n%s", dump)
+	}
+	if dump := redactPorterBody([]byte("<html>"), &PorterKubeconfigResponse{}); dump != "(6 bytes that are not JSON, not shown)" {
+		t.Errorf("non-JSON dump = %q", dump)
+	}
+}
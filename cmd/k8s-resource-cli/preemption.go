@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// preemptionRisk is a workload and the requests of its running pods that
+// higher-priority workloads scaling to max would preempt
+type preemptionRisk struct {
+	Workload  DeploymentMetrics
+	Priority  int32
+	Preempted ResourceMetrics
+}
+
+// priorityClasses maps PriorityClass names to their values. Pods without a
+// class get the globalDefault class's value, or zero without one.
+type priorityClasses struct {
+	values       map[string]int32
+	defaultValue int32
+}
+
+func (p priorityClasses) value(name string) int32 {
+	if name == "" {
+		return p.defaultValue
+	}
+	return p.values[name]
+}
+
+func loadPriorityClasses(ctx context.Context, clientset *kubernetes.Clientset) (priorityClasses, error) {
+	classes := priorityClasses{values: make(map[string]int32)}
+	list, err := clientset.SchedulingV1().PriorityClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return classes, fmt.Errorf("error listing priority classes: %w", err)
+	}
+	for _, class := range list.Items {
+		classes.values[class.Name] = class.Value
+		if class.GlobalDefault {
+			classes.defaultValue = class.Value
+		}
+	}
+	return classes, nil
+}
+
+// planPreemption works out what happens to free capacity if every workload
+// scales to its max replicas. Growth is served from free capacity first,
+// highest priority first; what doesn't fit preempts running pods of lower
+// priority, lowest first. Capacity is pooled across nodes, so this is the
+// risk at best fit: per-node packing can only make it worse.
+func planPreemption(workloads []DeploymentMetrics, classes priorityClasses, free ResourceMetrics) []preemptionRisk {
+	risks := make([]preemptionRisk, len(workloads))
+	for i, dm := range workloads {
+		risks[i] = preemptionRisk{Workload: dm, Priority: classes.value(dm.PriorityClass)}
+	}
+
+	// Victims are taken lowest priority first, growth is served highest first
+	victims := make([]int, len(risks))
+	for i := range victims {
+		victims[i] = i
+	}
+	sort.SliceStable(victims, func(a, b int) bool { return risks[victims[a]].Priority < risks[victims[b]].Priority })
+	growers := make([]int, len(victims))
+	for i, v := range victims {
+		growers[len(growers)-1-i] = v
+	}
+
+	take := func(need *ResourceMetrics) {
+		cpu, memory := min(need.CPU, free.CPU), min(need.Memory, free.Memory)
+		need.CPU -= cpu
+		need.Memory -= memory
+		free.CPU -= cpu
+		free.Memory -= memory
+	}
+
+	for _, g := range growers {
+		grower := risks[g]
+		maxRequests, current := grower.Workload.EffectiveMaxRequests(), grower.Workload.Requests
+		need := ResourceMetrics{CPU: max(maxRequests.CPU-current.CPU, 0), Memory: max(maxRequests.Memory-current.Memory, 0)}
+		take(&need)
+		for _, v := range victims {
+			victim := &risks[v]
+			if need.CPU == 0 && need.Memory == 0 || victim.Priority >= grower.Priority {
+				break
+			}
+			pool := ResourceMetrics{CPU: victim.Workload.Requests.CPU - victim.Preempted.CPU, Memory: victim.Workload.Requests.Memory - victim.Preempted.Memory}
+			// Preempting a pod frees all of its requests, so enough of the
+			// victim goes to cover the scarcer resource
+			var fraction float64
+			if pool.CPU > 0 {
+				fraction = float64(need.CPU) / float64(pool.CPU)
+			}
+			if pool.Memory > 0 {
+				fraction = max(fraction, float64(need.Memory)/float64(pool.Memory))
+			}
+			fraction = min(fraction, 1)
+			evicted := ResourceMetrics{CPU: int64(float64(pool.CPU) * fraction), Memory: int64(float64(pool.Memory) * fraction)}
+			victim.Preempted.add(evicted)
+			free.add(evicted)
+			take(&need)
+		}
+	}
+	return risks
+}
+
+// runPreemptionCommand implements the "preemption" subcommand, listing the
+// workloads whose pods would be preempted if higher-priority workloads
+// scaled to their HPA max replicas
+func runPreemptionCommand(args []string) {
+	var kubeconfig string
+	var kubeContext string
+	var showAll bool
+
+	fs := flag.NewFlagSet("preemption", flag.ContinueOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	fs.BoolVar(&showAll, "all", false, "Also list workloads that would not be preempted")
+	parseFlags(fs, args)
+
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	ctx := context.Background()
+	classes, err := loadPriorityClasses(ctx, clientset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+	allocatable, err := loadNodeAllocatable(ctx, clientset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+	requests, err := clusterRequests(ctx, clientset)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+
+	// Preemption crosses namespaces, so every Deployment is considered
+	caps, _ := detectCapabilities(clientset)
+	var workloads []DeploymentMetrics
+	getAllDeployments(ctx, clientset, nil, caps, "", "", "", true, func(dm DeploymentMetrics) {
+		workloads = append(workloads, dm)
+	})
+
+	free := ResourceMetrics{CPU: max(allocatable.CPU-requests.CPU, 0), Memory: max(allocatable.Memory-requests.Memory, 0)}
+	printPreemptionRisks(os.Stdout, planPreemption(workloads, classes, free), showAll)
+}
+
+func printPreemptionRisks(out io.Writer, risks []preemptionRisk, showAll bool) {
+	sort.SliceStable(risks, func(i, j int) bool { return risks[i].Priority < risks[j].Priority })
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "WORKLOAD\tPRIORITY CLASS\tPRIORITY\tPREEMPTED CPU\tPREEMPTED MEMORY\tRISK\n")
+	listed := 0
+	for _, risk := range risks {
+		atRisk := risk.Preempted.CPU > 0 || risk.Preempted.Memory > 0
+		if !atRisk && !showAll {
+			continue
+		}
+		listed++
+		status := "-"
+		if atRisk {
+			status = "partly preempted"
+			if risk.Preempted == risk.Workload.Requests {
+				status = "preempted"
+			}
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%d\t%s\t%s\t%s\n", risk.Workload.Namespace, risk.Workload.Name, valueOrDash(risk.Workload.PriorityClass),
+			risk.Priority, formatCPU(risk.Preempted.CPU), formatMemory(risk.Preempted.Memory), status)
+	}
+	if listed == 0 {
+		fmt.Fprintln(out, "No workloads would be preempted with every workload at its max replicas")
+		return
+	}
+	w.Flush()
+}
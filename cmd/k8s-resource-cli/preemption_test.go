@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPlanPreemption(t *testing.T) {
+	classes := priorityClasses{values: map[string]int32{"critical": 1000, "batch": -10}}
+	workloads := []DeploymentMetrics{
+		// Grows from 1 to 4 cores at max replicas
+		{Name: "api", Namespace: "shop", PriorityClass: "critical", DesiredReplicas: 1, MaxReplicas: 4,
+			Requests: ResourceMetrics{CPU: 1000, Memory: 1024}, MaxRequests: ResourceMetrics{CPU: 4000, Memory: 4096}},
+		{Name: "reports", Namespace: "etl", PriorityClass: "batch", DesiredReplicas: 1, MaxReplicas: 1,
+			Requests: ResourceMetrics{CPU: 1000, Memory: 1024}},
+		{Name: "web", Namespace: "shop", DesiredReplicas: 1, MaxReplicas: 1,
+			Requests: ResourceMetrics{CPU: 2000, Memory: 2048}},
+	}
+
+	risks := planPreemption(workloads, classes, ResourceMetrics{CPU: 1000, Memory: 1024})
+	byName := make(map[string]preemptionRisk)
+	for _, risk := range risks {
+		byName[risk.Workload.Name] = risk
+	}
+
+	// 3 cores of growth: 1 free, then all of the batch workload, then half
+	// of the default-priority one
+	if got := byName["reports"].Preempted; got != (ResourceMetrics{CPU: 1000, Memory: 1024}) {
+		t.Errorf("reports preempted = %+v, want all of it", got)
+	}
+	if got := byName["web"].Preempted; got != (ResourceMetrics{CPU: 1000, Memory: 1024}) {
+		t.Errorf("web preempted = %+v, want half of it", got)
+	}
+	if got := byName["api"].Preempted; got != (ResourceMetrics{}) {
+		t.Errorf("api preempted = %+v, the highest priority can't be preempted", got)
+	}
+
+	var buf bytes.Buffer
+	printPreemptionRisks(&buf, risks, false)
+	if !strings.Contains(buf.String(), "etl/reports") || !strings.Contains(buf.String(), "partly preempted") || strings.Contains(buf.String(), "shop/api") {
+		t.Errorf("printPreemptionRisks() =\n%s", buf.String())
+	}
+}
+
+func TestPlanPreemptionWithRoom(t *testing.T) {
+	workloads := []DeploymentMetrics{
+		{Name: "api", Namespace: "shop", DesiredReplicas: 1, MaxReplicas: 2,
+			Requests: ResourceMetrics{CPU: 1000}, MaxRequests: ResourceMetrics{CPU: 2000}},
+	}
+	var buf bytes.Buffer
+	printPreemptionRisks(&buf, planPreemption(workloads, priorityClasses{}, ResourceMetrics{CPU: 4000}), false)
+	if !strings.Contains(buf.String(), "No workloads would be preempted") {
+		t.Errorf("printPreemptionRisks() = %q", buf.String())
+	}
+}
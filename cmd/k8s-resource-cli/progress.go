@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Values for --progress
+const (
+	ProgressNone    = "none"
+	ProgressSpinner = "spinner"
+	ProgressBar     = "bar"
+)
+
+// progressBarWidth is how many cells the --progress bar has
+const progressBarWidth = 20
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// progressReporter keeps a single line on stderr up to date while workloads
+// are collected, overwriting it in place
+type progressReporter struct {
+	out   io.Writer
+	mode  string
+	frame int
+	shown bool // a line is on screen and must be cleared before other output
+}
+
+// progress is the progress line of this run, set once from --progress and --quiet
+var progress = &progressReporter{out: os.Stderr, mode: ProgressSpinner}
+
+// parseProgressMode validates --progress
+func parseProgressMode(mode string) (string, error) {
+	switch mode {
+	case ProgressNone, ProgressSpinner, ProgressBar:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid --progress '%s'. Must be 'none', 'spinner', or 'bar'", mode)
+}
+
+// Show reports that the current'th of total items, a kind of object named
+// name, is being loaded. total is 0 when it isn't known yet, as when the API
+// server doesn't say how many items later list pages hold.
+func (p *progressReporter) Show(kind string, current, total int, name string) {
+	if p.mode == ProgressNone {
+		return
+	}
+	count := fmt.Sprint(current)
+	if total > 0 {
+		count = fmt.Sprintf("%d/%d", current, total)
+	}
+	if p.mode == ProgressBar && total > 0 {
+		filled := min(current, total) * progressBarWidth / total
+		bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+		fmt.Fprintf(p.out, "\r[%s] %3d%% Loading %s %s: %s\033[K", bar, min(current, total)*100/total, kind, count, name)
+	} else {
+		fmt.Fprintf(p.out, "\r%s Loading %s %s: %s...\033[K", spinnerFrames[p.frame%len(spinnerFrames)], kind, count, name)
+		p.frame++
+	}
+	p.shown = true
+}
+
+// Clear erases the progress line, if one is shown
+func (p *progressReporter) Clear() {
+	if !p.shown {
+		return
+	}
+	fmt.Fprintf(p.out, "\r\033[K")
+	p.shown = false
+}
+
+// listTotal is how many items a paged list holds in all, given how many came
+// in earlier pages and the current page, or 0 when the API server doesn't
+// count the pages still to come
+func listTotal(loaded, page int, remaining *int64, more bool) int {
+	switch {
+	case !more:
+		return loaded + page
+	case remaining != nil:
+		return loaded + page + int(*remaining)
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProgressReporter(t *testing.T) {
+	tests := []struct {
+		mode    string
+		current int
+		total   int
+		want    string
+	}{
+		{ProgressNone, 3, 10, ""},
+		{ProgressSpinner, 3, 10, "\r⠋ Loading deployment 3/10: prod/web...\033[K\r\033[K"},
+		{ProgressSpinner, 3, 0, "\r⠋ Loading deployment 3: prod/web...\033[K\r\033[K"},
+		{ProgressBar, 3, 10, "\r[######--------------]  30% Loading deployment 3/10: prod/web\033[K\r\033[K"},
+		{ProgressBar, 3, 0, "\r⠋ Loading deployment 3: prod/web...\033[K\r\033[K"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		p := &progressReporter{out: &buf, mode: tt.mode}
+		p.Show("deployment", tt.current, tt.total, "prod/web")
+		p.Clear()
+		p.Clear()
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s progress %d/%d = %q, want %q", tt.mode, tt.current, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestListTotal(t *testing.T) {
+	remaining := int64(700)
+	tests := []struct {
+		loaded    int
+		page      int
+		remaining *int64
+		more      bool
+		want      int
+	}{
+		{0, 120, nil, false, 120},
+		{0, 500, &remaining, true, 1200},
+		{500, 500, nil, true, 0},
+		{1000, 200, nil, false, 1200},
+	}
+	for _, tt := range tests {
+		if got := listTotal(tt.loaded, tt.page, tt.remaining, tt.more); got != tt.want {
+			t.Errorf("listTotal(%d, %d, %v, %t) = %d, want %d", tt.loaded, tt.page, tt.remaining, tt.more, got, tt.want)
+		}
+	}
+}
+
+func TestParseProgressMode(t *testing.T) {
+	for _, mode := range []string{ProgressNone, ProgressSpinner, ProgressBar} {
+		if _, err := parseProgressMode(mode); err != nil {
+			t.Errorf("parseProgressMode(%q) error = %v", mode, err)
+		}
+	}
+	if _, err := parseProgressMode("dots"); err == nil {
+		t.Errorf("parseProgressMode(\"dots\") accepted an invalid mode")
+	}
+}
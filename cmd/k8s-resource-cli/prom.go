@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// promMetric is one gauge family of the Prometheus output, read from each workload
+type promMetric struct {
+	name  string
+	help  string
+	value func(dm DeploymentMetrics) float64
+}
+
+var promMetrics = []promMetric{
+	{"k8s_resource_cli_usage_cpu_cores", "CPU usage of the workload's pods in cores.",
+		func(dm DeploymentMetrics) float64 { return float64(dm.Usage.CPU) / 1000 }},
+	{"k8s_resource_cli_usage_memory_bytes", "Memory usage of the workload's pods in bytes.",
+		func(dm DeploymentMetrics) float64 { return float64(dm.Usage.Memory) }},
+	{"k8s_resource_cli_requests_cpu_cores", "CPU requests of the workload's current replicas in cores.",
+		func(dm DeploymentMetrics) float64 { return float64(dm.Requests.CPU) / 1000 }},
+	{"k8s_resource_cli_requests_memory_bytes", "Memory requests of the workload's current replicas in bytes.",
+		func(dm DeploymentMetrics) float64 { return float64(dm.Requests.Memory) }},
+	{"k8s_resource_cli_max_requests_cpu_cores", "CPU requests of the workload at max replicas in cores.",
+		func(dm DeploymentMetrics) float64 { return float64(dm.EffectiveMaxRequests().CPU) / 1000 }},
+	{"k8s_resource_cli_max_requests_memory_bytes", "Memory requests of the workload at max replicas in bytes.",
+		func(dm DeploymentMetrics) float64 { return float64(dm.EffectiveMaxRequests().Memory) }},
+}
+
+// promPrinter writes the workloads in the Prometheus text exposition format,
+// for a node_exporter textfile collector or a scrape of a one-shot run. The
+// samples of a metric family must be consecutive, so workloads are buffered.
+// by (--prom-granularity) sums workloads into coarser series, so large
+// clusters don't create a series per workload.
+type promPrinter struct {
+	out         io.Writer
+	by          string
+	deployments []DeploymentMetrics
+}
+
+func (p *promPrinter) Add(dm DeploymentMetrics) {
+	p.deployments = append(p.deployments, dm)
+}
+
+// promSeries is the label set of a series and the sum of its workloads
+type promSeries struct {
+	labels string
+	totals jsonTotals
+}
+
+// metrics returns the summed workloads as one, with no replicas so that
+// its max requests are taken as they are
+func (s *promSeries) metrics() DeploymentMetrics {
+	return DeploymentMetrics{Usage: s.totals.Usage, Requests: s.totals.Requests, Limits: s.totals.Limits, MaxRequests: s.totals.MaxRequests}
+}
+
+// series groups the workloads by the labels of --prom-granularity, in the
+// order the label sets first appear
+func (p *promPrinter) series() []*promSeries {
+	var series []*promSeries
+	byLabels := make(map[string]*promSeries)
+	for _, dm := range p.deployments {
+		var labels string
+		switch p.by {
+		case PromByCluster:
+		case PromByNamespace:
+			labels = fmt.Sprintf("{namespace=%s}", promLabelValue(dm.Namespace))
+		case PromByType:
+			labels = fmt.Sprintf("{namespace=%s,type=%s}", promLabelValue(dm.Namespace), promLabelValue(dm.Type))
+		default:
+			labels = fmt.Sprintf("{namespace=%s,workload=%s,type=%s}", promLabelValue(dm.Namespace), promLabelValue(dm.Name), promLabelValue(dm.Type))
+		}
+		s, ok := byLabels[labels]
+		if !ok {
+			s = &promSeries{labels: labels}
+			byLabels[labels] = s
+			series = append(series, s)
+		}
+		s.totals.add(dm)
+	}
+	return series
+}
+
+func (p *promPrinter) Flush() {
+	series := p.series()
+	for _, metric := range promMetrics {
+		fmt.Fprintf(p.out, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(p.out, "# TYPE %s gauge\n", metric.name)
+		for _, s := range series {
+			fmt.Fprintf(p.out, "%s%s %s\n", metric.name, s.labels, strconv.FormatFloat(metric.value(s.metrics()), 'f', -1, 64))
+		}
+	}
+}
+
+// promLabelValue quotes a label value, escaping backslashes, double quotes and newlines
+func promLabelValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
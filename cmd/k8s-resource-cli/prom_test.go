@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPromPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatProm, tableOptions{}, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web", Namespace: "default", Type: "Deployment",
+		CurrentReplicas: 2, DesiredReplicas: 2, MaxReplicas: 4,
+		Usage:       ResourceMetrics{CPU: 150, Memory: 1048576},
+		Requests:    ResourceMetrics{CPU: 500, Memory: 2097152},
+		MaxRequests: ResourceMetrics{CPU: 1000, Memory: 4194304},
+	})
+	p.Add(DeploymentMetrics{Name: "backup", Namespace: "ops", Type: "CronJob", MaxReplicas: 1, Requests: ResourceMetrics{CPU: 2000}})
+	p.Flush()
+	output := buf.String()
+
+	for _, want := range []string{
+		"# HELP k8s_resource_cli_requests_cpu_cores CPU requests of the workload's current replicas in cores.\n" +
+			"# TYPE k8s_resource_cli_requests_cpu_cores gauge\n" +
+			"k8s_resource_cli_requests_cpu_cores{namespace=\"default\",workload=\"web\",type=\"Deployment\"} 0.5\n" +
+			"k8s_resource_cli_requests_cpu_cores{namespace=\"ops\",workload=\"backup\",type=\"CronJob\"} 2\n",
+		"k8s_resource_cli_usage_memory_bytes{namespace=\"default\",workload=\"web\",type=\"Deployment\"} 1048576\n",
+		"k8s_resource_cli_max_requests_cpu_cores{namespace=\"default\",workload=\"web\",type=\"Deployment\"} 1\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("prom output missing %q:\n%s", want, output)
+		}
+	}
+	if got := strings.Count(output, "# TYPE "); got != len(promMetrics) {
+		t.Errorf("prom output has %d metric families, want %d", got, len(promMetrics))
+	}
+}
+
+func TestPromLabelValue(t *testing.T) {
+	if got, want := promLabelValue("a\"b\\c\nd"), `"a\"b\\c\nd"`; got != want {
+		t.Errorf("promLabelValue() = %s, want %s", got, want)
+	}
+}
+
+func TestPromPrinterGranularity(t *testing.T) {
+	deployments := []DeploymentMetrics{
+		{Name: "web", Namespace: "shop", Type: "Deployment", CurrentReplicas: 2, DesiredReplicas: 2, MaxReplicas: 4,
+			Requests: ResourceMetrics{CPU: 100}, MaxRequests: ResourceMetrics{CPU: 200}},
+		{Name: "api", Namespace: "shop", Type: "Deployment", CurrentReplicas: 1, DesiredReplicas: 1, MaxReplicas: 1,
+			Requests: ResourceMetrics{CPU: 200}, MaxRequests: ResourceMetrics{CPU: 200}},
+		{Name: "backup", Namespace: "ops", Type: "CronJob", DesiredReplicas: 1, MaxReplicas: 1,
+			Requests: ResourceMetrics{CPU: 2000}, MaxRequests: ResourceMetrics{CPU: 2000}},
+	}
+	tests := []struct {
+		by   string
+		want string
+	}{
+		{PromByWorkload, "k8s_resource_cli_max_requests_cpu_cores{namespace=\"shop\",workload=\"web\",type=\"Deployment\"} 0.2\n" +
+			"k8s_resource_cli_max_requests_cpu_cores{namespace=\"shop\",workload=\"api\",type=\"Deployment\"} 0.2\n" +
+			"k8s_resource_cli_max_requests_cpu_cores{namespace=\"ops\",workload=\"backup\",type=\"CronJob\"} 2\n"},
+		{PromByType, "k8s_resource_cli_max_requests_cpu_cores{namespace=\"shop\",type=\"Deployment\"} 0.4\n" +
+			"k8s_resource_cli_max_requests_cpu_cores{namespace=\"ops\",type=\"CronJob\"} 2\n"},
+		{PromByNamespace, "k8s_resource_cli_max_requests_cpu_cores{namespace=\"shop\"} 0.4\n" +
+			"k8s_resource_cli_max_requests_cpu_cores{namespace=\"ops\"} 2\n"},
+		{PromByCluster, "k8s_resource_cli_max_requests_cpu_cores 2.4\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatProm, tableOptions{promBy: tt.by}, nil)
+		for _, dm := range deployments {
+			p.Add(dm)
+		}
+		p.Flush()
+		output := buf.String()
+		if !strings.Contains(output, "# TYPE k8s_resource_cli_max_requests_cpu_cores gauge\n"+tt.want+"#") {
+			t.Errorf("prom output by %s missing %q:\n%s", tt.by, tt.want, output)
+		}
+	}
+}
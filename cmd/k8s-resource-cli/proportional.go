@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// cpaImage identifies cluster-proportional-autoscaler containers, e.g.
+// registry.k8s.io/cpa/cluster-proportional-autoscaler. The dns-autoscaler
+// that scales CoreDNS with the node count is the best known one.
+const cpaImage = "cluster-proportional-autoscaler"
+
+// cpaTargets returns the namespace/name of the Deployments a
+// cluster-proportional-autoscaler Deployment scales. Targets come from its
+// --target flag, e.g. deployment/coredns, in the namespace of its
+// --namespace flag, or its own namespace when that isn't set.
+func cpaTargets(d appsv1.Deployment) []string {
+	var targets []string
+	for _, container := range d.Spec.Template.Spec.Containers {
+		if !strings.Contains(container.Image, cpaImage) {
+			continue
+		}
+		namespace := d.Namespace
+		var targetList string
+		args := append(append([]string(nil), container.Command...), container.Args...)
+		for i, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				continue
+			}
+			name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if !hasValue && i+1 < len(args) {
+				value = args[i+1]
+			}
+			switch name {
+			case "target":
+				targetList = value
+			case "namespace":
+				namespace = value
+			}
+		}
+		for _, target := range strings.Split(targetList, ",") {
+			kind, name, ok := strings.Cut(strings.TrimSpace(target), "/")
+			if ok && strings.EqualFold(kind, "deployment") && name != "" {
+				targets = append(targets, namespace+"/"+name)
+			}
+		}
+	}
+	return targets
+}
+
+// deploymentIndex is what one extra list of Deployments tells about the
+// workloads being reported: the names in scope, which pair Flagger canaries
+// with their primaries, and the targets of cluster-proportional-autoscalers
+type deploymentIndex struct {
+	names        map[string]bool
+	proportional map[string]bool
+}
+
+// listDeploymentIndex lists the Deployments in the namespace, or in all
+// namespaces if empty
+func listDeploymentIndex(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (deploymentIndex, error) {
+	index := deploymentIndex{names: make(map[string]bool), proportional: make(map[string]bool)}
+	listOptions := metav1.ListOptions{Limit: listPageSize}
+	for {
+		deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+		if err != nil {
+			return index, fmt.Errorf("error listing deployments: %w", err)
+		}
+		for _, deployment := range deployments.Items {
+			index.names[deployment.Namespace+"/"+deployment.Name] = true
+			for _, target := range cpaTargets(deployment) {
+				index.proportional[target] = true
+			}
+		}
+		if deployments.Continue == "" {
+			return index, nil
+		}
+		listOptions.Continue = deployments.Continue
+	}
+}
+
+// deploymentMarker marks canaries and cluster-proportional workloads, both
+// of which depend on other Deployments, listing them once on first use
+type deploymentMarker struct {
+	ctx       context.Context
+	clientset *kubernetes.Clientset
+	namespace string // empty for all namespaces
+	index     deploymentIndex
+	loaded    bool
+}
+
+func (m *deploymentMarker) Mark(dm *DeploymentMetrics) {
+	if !m.loaded {
+		m.loaded = true
+		var err error
+		if m.index, err = listDeploymentIndex(m.ctx, m.clientset, m.namespace); err != nil {
+			debugf(verbosityDecisions, "%v, only canary labels are checked", err)
+		}
+	}
+	dm.Canary = isCanary(*dm, m.index.names)
+	dm.Proportional = dm.Type == "Deployment" && m.index.proportional[dm.Namespace+"/"+dm.Name]
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func cpaDeployment(namespace, image string, command, args []string) appsv1.Deployment {
+	d := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "dns-autoscaler", Namespace: namespace}}
+	d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "autoscaler", Image: image, Command: command, Args: args}}
+	return d
+}
+
+func TestCPATargets(t *testing.T) {
+	const image = "registry.k8s.io/cpa/cluster-proportional-autoscaler:v1.8.9"
+
+	tests := []struct {
+		name       string
+		deployment appsv1.Deployment
+		want       []string
+	}{
+		{"dns-autoscaler command", cpaDeployment("kube-system", image,
+			[]string{"/cluster-proportional-autoscaler", "--namespace=kube-system", "--configmap=dns-autoscaler", "--target=Deployment/coredns"}, nil),
+			[]string{"kube-system/coredns"}},
+		{"own namespace and separate value", cpaDeployment("infra", image, nil, []string{"--target", "deployment/ingress,replicaset/legacy"}),
+			[]string{"infra/ingress"}},
+		{"other namespace", cpaDeployment("infra", image, nil, []string{"--target=deployment/metrics", "--namespace=monitoring"}),
+			[]string{"monitoring/metrics"}},
+		{"not an autoscaler", cpaDeployment("infra", "nginx:1.27", nil, []string{"--target=deployment/web"}), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cpaTargets(tt.deployment); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cpaTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
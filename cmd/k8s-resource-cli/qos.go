@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// qosChange is one edit needed to bring a container to Guaranteed QoS
+type qosChange struct {
+	Container string
+	Resource  corev1.ResourceName
+	Current   string
+	Change    string
+}
+
+// qosPlan is the set of changes for one workload. Close is false when some
+// container lacks both request and limit for a resource, or its request is
+// further below the limit than the tolerance allows.
+type qosPlan struct {
+	Workload string
+	Changes  []qosChange
+	Close    bool
+}
+
+// planGuaranteed works out the changes that make every container of the pod
+// spec, init containers included, have equal CPU and memory requests and
+// limits. Requests are raised to the limit rather than limits lowered, so no
+// container loses headroom it has today.
+func planGuaranteed(spec corev1.PodSpec, tolerance float64) ([]qosChange, bool) {
+	var changes []qosChange
+	near := true
+
+	containers := append(append([]corev1.Container(nil), spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			request, hasRequest := container.Resources.Requests[name]
+			limit, hasLimit := container.Resources.Limits[name]
+
+			switch {
+			case !hasRequest && !hasLimit:
+				near = false
+				changes = append(changes, qosChange{container.Name, name, "none", "set a request and an equal limit"})
+			case !hasLimit:
+				changes = append(changes, qosChange{container.Name, name, "request " + request.String(),
+					fmt.Sprintf("set limits.%s: %s", name, request.String())})
+			case !hasRequest:
+				// Kubernetes defaults the request to the limit, already equal
+			case request.Cmp(limit) != 0:
+				if limitValue := limit.MilliValue(); limitValue > 0 && float64(limitValue-request.MilliValue())/float64(limitValue) > tolerance {
+					near = false
+				}
+				changes = append(changes, qosChange{container.Name, name, requestLimitLabel(request, limit),
+					fmt.Sprintf("set requests.%s: %s", name, limit.String())})
+			}
+		}
+	}
+
+	return changes, near
+}
+
+// podQOSClass works out the QoS class Kubernetes gives pods of this spec:
+// Guaranteed when every container has CPU and memory limits with equal
+// requests, BestEffort when none has any, Burstable otherwise. A request
+// left unset defaults to its limit.
+func podQOSClass(spec corev1.PodSpec) corev1.PodQOSClass {
+	guaranteed, bestEffort := true, true
+	containers := append(append([]corev1.Container(nil), spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			request, hasRequest := container.Resources.Requests[name]
+			limit, hasLimit := container.Resources.Limits[name]
+			if hasRequest || hasLimit {
+				bestEffort = false
+			}
+			if !hasLimit || (hasRequest && request.Cmp(limit) != 0) {
+				guaranteed = false
+			}
+		}
+	}
+	switch {
+	case bestEffort:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	}
+	return corev1.PodQOSBurstable
+}
+
+func requestLimitLabel(request, limit resource.Quantity) string {
+	return fmt.Sprintf("request %s / limit %s", request.String(), limit.String())
+}
+
+// runQoSPlanCommand implements the "qos-plan" subcommand, listing workloads
+// that are close to Guaranteed QoS and the exact edits that get them there
+func runQoSPlanCommand(args []string) {
+	var namespace string
+	var allNamespaces bool
+	var kubeconfig string
+	var kubeContext string
+	var includeCronJobs bool
+	var tolerance float64
+	var showAll bool
+
+	fs := flag.NewFlagSet("qos-plan", flag.ContinueOnError)
+	fs.StringVar(&namespace, "namespace", "", "Namespace (defaults to current context or 'default')")
+	fs.BoolVar(&allNamespaces, "A", false, "Plan across all namespaces")
+	fs.BoolVar(&allNamespaces, "all-namespaces", false, "Plan across all namespaces")
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	fs.BoolVar(&includeCronJobs, "include-cronjobs", false, "Include CronJob job templates")
+	fs.Float64Var(&tolerance, "tolerance", 0.25, "How far below its limit a request may be, as a fraction of the limit, to count as close")
+	fs.BoolVar(&showAll, "all", false, "Also list workloads that are not close to Guaranteed")
+	parseFlags(fs, args)
+
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	if allNamespaces {
+		namespace = ""
+	} else if namespace == "" {
+		if namespace, err = getNamespaceFromKubeconfig(kubeconfig, kubeContext); err != nil {
+			namespace = "default"
+		}
+	}
+
+	plans, err := loadQoSPlans(context.Background(), clientset, namespace, includeCronJobs, tolerance)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(apiExitCode(err))
+	}
+	printQoSPlans(os.Stdout, plans, showAll)
+}
+
+// loadQoSPlans plans every Deployment, and optionally CronJob, that isn't
+// Guaranteed already
+func loadQoSPlans(ctx context.Context, clientset *kubernetes.Clientset, namespace string, includeCronJobs bool, tolerance float64) ([]qosPlan, error) {
+	var plans []qosPlan
+	add := func(workload string, spec corev1.PodSpec) {
+		if changes, near := planGuaranteed(spec, tolerance); len(changes) > 0 {
+			plans = append(plans, qosPlan{Workload: workload, Changes: changes, Close: near})
+		}
+	}
+
+	listOptions := metav1.ListOptions{Limit: listPageSize}
+	for {
+		deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, fmt.Errorf("error listing deployments: %w", err)
+		}
+		for _, d := range deployments.Items {
+			add("Deployment/"+d.Namespace+"/"+d.Name, d.Spec.Template.Spec)
+		}
+		if deployments.Continue == "" {
+			break
+		}
+		listOptions.Continue = deployments.Continue
+	}
+
+	if includeCronJobs {
+		listOptions = metav1.ListOptions{Limit: listPageSize}
+		for {
+			cronJobs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, listOptions)
+			if err != nil {
+				return nil, fmt.Errorf("error listing cronjobs: %w", err)
+			}
+			for _, c := range cronJobs.Items {
+				add("CronJob/"+c.Namespace+"/"+c.Name, c.Spec.JobTemplate.Spec.Template.Spec)
+			}
+			if cronJobs.Continue == "" {
+				break
+			}
+			listOptions.Continue = cronJobs.Continue
+		}
+	}
+
+	return plans, nil
+}
+
+func printQoSPlans(out io.Writer, plans []qosPlan, showAll bool) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "WORKLOAD\tCONTAINER\tRESOURCE\tCURRENT\tCHANGE\n")
+	listed := 0
+	for _, plan := range plans {
+		if !plan.Close && !showAll {
+			continue
+		}
+		listed++
+		for _, change := range plan.Changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", plan.Workload, change.Container, change.Resource, change.Current, change.Change)
+		}
+	}
+	if listed == 0 {
+		fmt.Fprintln(out, "No workloads close to Guaranteed QoS found")
+		return
+	}
+	w.Flush()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func qosContainer(name string, requests, limits map[corev1.ResourceName]string) corev1.Container {
+	c := corev1.Container{Name: name, Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}}
+	for resourceName, value := range requests {
+		c.Resources.Requests[resourceName] = resource.MustParse(value)
+	}
+	for resourceName, value := range limits {
+		c.Resources.Limits[resourceName] = resource.MustParse(value)
+	}
+	return c
+}
+
+func TestPlanGuaranteed(t *testing.T) {
+	tests := []struct {
+		name        string
+		containers  []corev1.Container
+		wantChanges []string
+		wantClose   bool
+	}{
+		{
+			name: "already guaranteed",
+			containers: []corev1.Container{qosContainer("app",
+				map[corev1.ResourceName]string{"cpu": "500m", "memory": "1Gi"},
+				map[corev1.ResourceName]string{"cpu": "500m", "memory": "1Gi"})},
+			wantClose: true,
+		},
+		{
+			name: "limits only",
+			containers: []corev1.Container{qosContainer("app", nil,
+				map[corev1.ResourceName]string{"cpu": "1", "memory": "1Gi"})},
+			wantClose: true,
+		},
+		{
+			name: "missing limits",
+			containers: []corev1.Container{qosContainer("app",
+				map[corev1.ResourceName]string{"cpu": "250m", "memory": "512Mi"}, nil)},
+			wantChanges: []string{"set limits.cpu: 250m", "set limits.memory: 512Mi"},
+			wantClose:   true,
+		},
+		{
+			name: "request within tolerance",
+			containers: []corev1.Container{qosContainer("app",
+				map[corev1.ResourceName]string{"cpu": "800m", "memory": "1Gi"},
+				map[corev1.ResourceName]string{"cpu": "1", "memory": "1Gi"})},
+			wantChanges: []string{"set requests.cpu: 1"},
+			wantClose:   true,
+		},
+		{
+			name: "request far below limit",
+			containers: []corev1.Container{qosContainer("app",
+				map[corev1.ResourceName]string{"cpu": "100m", "memory": "1Gi"},
+				map[corev1.ResourceName]string{"cpu": "1", "memory": "1Gi"})},
+			wantChanges: []string{"set requests.cpu: 1"},
+			wantClose:   false,
+		},
+		{
+			name: "sidecar without resources",
+			containers: []corev1.Container{
+				qosContainer("app",
+					map[corev1.ResourceName]string{"cpu": "1", "memory": "1Gi"},
+					map[corev1.ResourceName]string{"cpu": "1", "memory": "1Gi"}),
+				qosContainer("proxy", nil, nil),
+			},
+			wantChanges: []string{"set a request and an equal limit", "set a request and an equal limit"},
+			wantClose:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, near := planGuaranteed(corev1.PodSpec{Containers: tt.containers}, 0.25)
+			if near != tt.wantClose {
+				t.Errorf("planGuaranteed() close = %v, want %v", near, tt.wantClose)
+			}
+			if len(changes) != len(tt.wantChanges) {
+				t.Fatalf("planGuaranteed() = %+v, want %d changes", changes, len(tt.wantChanges))
+			}
+			for i, want := range tt.wantChanges {
+				if changes[i].Change != want {
+					t.Errorf("change[%d] = %q, want %q", i, changes[i].Change, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanGuaranteedInitContainers(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{qosContainer("migrate",
+			map[corev1.ResourceName]string{"cpu": "100m", "memory": "128Mi"}, nil)},
+		Containers: []corev1.Container{qosContainer("app",
+			map[corev1.ResourceName]string{"cpu": "1", "memory": "1Gi"},
+			map[corev1.ResourceName]string{"cpu": "1", "memory": "1Gi"})},
+	}
+	changes, _ := planGuaranteed(spec, 0.25)
+	if len(changes) != 2 || changes[0].Container != "migrate" {
+		t.Errorf("planGuaranteed() = %+v, want the init container's limits", changes)
+	}
+}
+
+func TestPrintQoSPlans(t *testing.T) {
+	plans := []qosPlan{
+		{Workload: "Deployment/shop/api", Close: true, Changes: []qosChange{{"app", corev1.ResourceCPU, "request 250m", "set limits.cpu: 250m"}}},
+		{Workload: "Deployment/shop/worker", Close: false, Changes: []qosChange{{"app", corev1.ResourceMemory, "none", "set a request and an equal limit"}}},
+	}
+
+	var buf bytes.Buffer
+	printQoSPlans(&buf, plans, false)
+	if !strings.Contains(buf.String(), "shop/api") || strings.Contains(buf.String(), "shop/worker") {
+		t.Errorf("printQoSPlans() should list only close workloads:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printQoSPlans(&buf, plans, true)
+	if !strings.Contains(buf.String(), "shop/worker") {
+		t.Errorf("printQoSPlans() with showAll should list every workload:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printQoSPlans(&buf, nil, false)
+	if !strings.Contains(buf.String(), "No workloads") {
+		t.Errorf("printQoSPlans() with no plans = %q", buf.String())
+	}
+}
+
+func TestPodQOSClass(t *testing.T) {
+	both := map[corev1.ResourceName]string{corev1.ResourceCPU: "500m", corev1.ResourceMemory: "1Gi"}
+
+	tests := []struct {
+		name           string
+		initContainers []corev1.Container
+		containers     []corev1.Container
+		want           corev1.PodQOSClass
+	}{
+		{"no resources", nil, []corev1.Container{qosContainer("app", nil, nil)}, corev1.PodQOSBestEffort},
+		{"equal requests and limits", nil, []corev1.Container{qosContainer("app", both, both)}, corev1.PodQOSGuaranteed},
+		{"limits only", nil, []corev1.Container{qosContainer("app", nil, both)}, corev1.PodQOSGuaranteed},
+		{"requests only", nil, []corev1.Container{qosContainer("app", both, nil)}, corev1.PodQOSBurstable},
+		{"init container without limits", []corev1.Container{qosContainer("init", nil, nil)}, []corev1.Container{qosContainer("app", both, both)}, corev1.PodQOSBurstable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := corev1.PodSpec{InitContainers: tt.initContainers, Containers: tt.containers}
+			if got := podQOSClass(spec); got != tt.want {
+				t.Errorf("podQOSClass() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
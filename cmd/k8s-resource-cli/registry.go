@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Manifest media types image sizes are read from. Lists and indexes point
+// at one manifest per platform.
+const (
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// dockerHubRegistry serves images without a registry host, e.g. nginx:1.25
+const dockerHubRegistry = "registry-1.docker.io"
+
+// imageArchitecture picks the manifest of multi-platform images. Nodes
+// aren't asked for theirs, so sizes are those of the amd64 build.
+const imageArchitecture = "amd64"
+
+// imageReference is a container image split into where its manifest lives
+type imageReference struct {
+	registry   string
+	repository string
+	reference  string // digest, or else tag
+}
+
+// parseImageReference splits an image the way the container runtime does:
+// the first path component is a registry host only if it looks like one,
+// Docker Hub images without an organization live under library/, and a
+// missing tag means latest
+func parseImageReference(image string) imageReference {
+	ref := imageReference{registry: dockerHubRegistry, reference: "latest"}
+	name, digest, hasDigest := strings.Cut(image, "@")
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		ref.reference = name[colon+1:]
+		name = name[:colon]
+	}
+	if hasDigest {
+		ref.reference = digest
+	}
+	if host, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		if host != "docker.io" && host != "index.docker.io" {
+			ref.registry = host
+		}
+		name = rest
+	}
+	if ref.registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	ref.repository = name
+	return ref
+}
+
+// registryManifest is the part of an image manifest, or of a list or index
+// of them, that sizes come from
+type registryManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Size int64 `json:"size"`
+	} `json:"config"`
+	Layers []struct {
+		Size int64 `json:"size"`
+	} `json:"layers"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// size is the compressed size of the image: what a node pulls
+func (m registryManifest) size() int64 {
+	size := m.Config.Size
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	return size
+}
+
+// platformDigest picks the manifest for the OS from a list or index,
+// preferring imageArchitecture, and reports false if none matches
+func (m registryManifest) platformDigest(imageOS string) (string, bool) {
+	digest := ""
+	for _, manifest := range m.Manifests {
+		if manifest.Platform.OS != imageOS {
+			continue
+		}
+		if manifest.Platform.Architecture == imageArchitecture {
+			return manifest.Digest, true
+		}
+		if digest == "" {
+			digest = manifest.Digest
+		}
+	}
+	return digest, digest != ""
+}
+
+// registryClient reads image sizes from the registries' v2 HTTP API.
+// Registries that ask for a token get an anonymous one, so only public
+// images, or registries that let the network in, can be sized.
+type registryClient struct {
+	httpClient *http.Client
+	tokens     map[string]string // bearer token per registry and repository
+	sizes      map[string]int64  // sizes read so far per image and OS
+}
+
+func newRegistryClient(httpClient *http.Client) *registryClient {
+	return &registryClient{httpClient: httpClient, tokens: make(map[string]string), sizes: make(map[string]int64)}
+}
+
+// ImageSize returns the compressed size of the image's build for the OS
+func (c *registryClient) ImageSize(ctx context.Context, image, imageOS string) (int64, error) {
+	if imageOS == "" {
+		imageOS = "linux"
+	}
+	key := image + "|" + imageOS
+	if size, ok := c.sizes[key]; ok {
+		return size, nil
+	}
+
+	ref := parseImageReference(image)
+	manifest, err := c.manifest(ctx, ref, ref.reference)
+	if err != nil {
+		return 0, err
+	}
+	if manifest.MediaType == mediaTypeDockerManifestList || manifest.MediaType == mediaTypeOCIIndex || len(manifest.Manifests) > 0 {
+		digest, ok := manifest.platformDigest(imageOS)
+		if !ok {
+			return 0, fmt.Errorf("image %s has no %s build", image, imageOS)
+		}
+		if manifest, err = c.manifest(ctx, ref, digest); err != nil {
+			return 0, err
+		}
+	}
+	c.sizes[key] = manifest.size()
+	return c.sizes[key], nil
+}
+
+// manifest fetches a manifest, getting a token first if the registry asks
+func (c *registryClient) manifest(ctx context.Context, ref imageReference, reference string) (registryManifest, error) {
+	var manifest registryManifest
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.registry, ref.repository, reference)
+	tokenKey := ref.registry + "/" + ref.repository
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+		if err != nil {
+			return manifest, err
+		}
+		req.Header.Set("Accept", strings.Join([]string{mediaTypeDockerManifest, mediaTypeDockerManifestList, mediaTypeOCIManifest, mediaTypeOCIIndex}, ", "))
+		if token := c.tokens[tokenKey]; token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return manifest, fmt.Errorf("error fetching manifest %s: %w", manifestURL, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if c.tokens[tokenKey], err = c.token(ctx, challenge); err != nil {
+				return manifest, err
+			}
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return manifest, fmt.Errorf("error fetching manifest %s: %s", manifestURL, resp.Status)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+			return manifest, fmt.Errorf("invalid manifest %s: %w", manifestURL, err)
+		}
+		if manifest.MediaType == "" {
+			manifest.MediaType = resp.Header.Get("Content-Type")
+		}
+		return manifest, nil
+	}
+}
+
+// token gets an anonymous pull token from the realm of a
+// `Bearer realm="...",service="...",scope="..."` challenge
+func (c *registryClient) token(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry requires %s authentication, only anonymous bearer tokens are supported", valueOrDash(scheme))
+	}
+	values := url.Values{}
+	var realm string
+	for _, param := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(value, `"`)
+		switch name {
+		case "realm":
+			realm = value
+		case "service", "scope":
+			values.Set(name, value)
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("registry token challenge has no realm: %s", challenge)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error getting registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error getting registry token: %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid registry token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// imageSizer sets the total size of each workload's images, reporting each
+// image it can't size once
+type imageSizer struct {
+	ctx    context.Context
+	client *registryClient
+	failed map[string]bool
+}
+
+func newImageSizer(ctx context.Context, client *registryClient) *imageSizer {
+	return &imageSizer{ctx: ctx, client: client, failed: make(map[string]bool)}
+}
+
+func (s *imageSizer) Mark(dm *DeploymentMetrics) {
+	for _, image := range dm.Images {
+		size, err := s.client.ImageSize(s.ctx, image, dm.OS)
+		if err != nil {
+			if !s.failed[image] {
+				debugf(verbosityDecisions, "%v", err)
+			}
+			s.failed[image] = true
+			continue
+		}
+		dm.ImageBytes += size
+	}
+}
+
+// Print warns about images whose size is missing from the report
+func (s *imageSizer) Print(w io.Writer) {
+	if len(s.failed) > 0 {
+		fmt.Fprintf(w, "Warning: couldn't read the size of %d images from their registries, so image sizes are incomplete; use -v to see why\n", len(s.failed))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image string
+		want  imageReference
+	}{
+		{"nginx", imageReference{dockerHubRegistry, "library/nginx", "latest"}},
+		{"nginx:1.25", imageReference{dockerHubRegistry, "library/nginx", "1.25"}},
+		{"docker.io/bitnami/redis:7.2", imageReference{dockerHubRegistry, "bitnami/redis", "7.2"}},
+		{"ghcr.io/org/app:v1", imageReference{"ghcr.io", "org/app", "v1"}},
+		{"localhost:5000/app", imageReference{"localhost:5000", "app", "latest"}},
+		{"registry.k8s.io/pause:3.9@sha256:abc", imageReference{"registry.k8s.io", "pause", "sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := parseImageReference(tt.image); got != tt.want {
+				t.Errorf("parseImageReference(%q) = %+v, want %+v", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryClientImageSize(t *testing.T) {
+	var server *httptest.Server
+	manifestRequests := 0
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:org/app:pull" {
+				t.Errorf("token scope = %q", r.URL.Query().Get("scope"))
+			}
+			fmt.Fprint(w, `{"token":"anonymous"}`)
+		case r.Header.Get("Authorization") != "Bearer anonymous":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:org/app:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/org/app/manifests/v1":
+			manifestRequests++
+			w.Header().Set("Content-Type", mediaTypeOCIIndex)
+			fmt.Fprint(w, `{"manifests":[
+				{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64"}},
+				{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}}]}`)
+		case r.URL.Path == "/v2/org/app/manifests/sha256:amd":
+			fmt.Fprint(w, `{"mediaType":"`+mediaTypeOCIManifest+`","config":{"size":1000},"layers":[{"size":20000},{"size":300000}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newRegistryClient(server.Client())
+	image := strings.TrimPrefix(server.URL, "https://") + "/org/app:v1"
+	for i := 0; i < 2; i++ {
+		size, err := client.ImageSize(context.Background(), image, "linux")
+		if err != nil {
+			t.Fatalf("ImageSize() error = %v", err)
+		}
+		if size != 321000 {
+			t.Errorf("ImageSize() = %d, want 321000", size)
+		}
+	}
+	if manifestRequests != 1 {
+		t.Errorf("index fetched %d times, want once with the size cached", manifestRequests)
+	}
+
+	if _, err := client.ImageSize(context.Background(), image, "windows"); err == nil {
+		t.Error("ImageSize() for an OS without a build succeeded, want an error")
+	}
+}
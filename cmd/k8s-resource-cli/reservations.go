@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// reservationDateLayout is the format of a reservation's launch date
+const reservationDateLayout = "2006-01-02"
+
+// reservationFile is the --reservations file, YAML or JSON
+type reservationFile struct {
+	Reservations []struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		CPU       string `json:"cpu"`    // per replica, e.g. 500m
+		Memory    string `json:"memory"` // per replica, e.g. 1Gi
+		Replicas  int32  `json:"replicas"`
+		Launch    string `json:"launch"` // YYYY-MM-DD
+	} `json:"reservations"`
+}
+
+// reservation is a planned workload whose capacity is committed before it
+// is deployed. Requests covers all replicas.
+type reservation struct {
+	Name      string
+	Namespace string
+	Replicas  int32
+	Requests  ResourceMetrics
+	Launch    time.Time
+}
+
+// loadReservations reads and validates the reservations file, sorted by launch date
+func loadReservations(path string) ([]reservation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file reservationFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid reservations file %s: %w", path, err)
+	}
+
+	reservations := make([]reservation, 0, len(file.Reservations))
+	for i, r := range file.Reservations {
+		if r.Name == "" {
+			return nil, fmt.Errorf("reservation %d in %s has no name", i+1, path)
+		}
+		cpu, err := parseResourceValue(r.CPU, true)
+		if err != nil {
+			return nil, fmt.Errorf("reservation %s: %w", r.Name, err)
+		}
+		memory, err := parseResourceValue(r.Memory, false)
+		if err != nil {
+			return nil, fmt.Errorf("reservation %s: %w", r.Name, err)
+		}
+		launch, err := time.Parse(reservationDateLayout, r.Launch)
+		if err != nil {
+			return nil, fmt.Errorf("reservation %s: invalid launch date %q, expected YYYY-MM-DD", r.Name, r.Launch)
+		}
+		replicas := max(r.Replicas, 1)
+		reservations = append(reservations, reservation{
+			Name:      r.Name,
+			Namespace: r.Namespace,
+			Replicas:  replicas,
+			Requests:  ResourceMetrics{CPU: cpu * int64(replicas), Memory: memory * int64(replicas)},
+			Launch:    launch,
+		})
+	}
+
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].Launch.Before(reservations[j].Launch)
+	})
+	return reservations, nil
+}
+
+// reservationTracker notes which reserved workloads already show up in the
+// report, since their capacity is then counted in the cluster's requests
+type reservationTracker struct {
+	reservations []reservation
+	deployed     map[string]bool
+}
+
+func newReservationTracker(reservations []reservation) *reservationTracker {
+	return &reservationTracker{reservations: reservations, deployed: make(map[string]bool)}
+}
+
+func (t *reservationTracker) Add(dm DeploymentMetrics) {
+	t.deployed[dm.Namespace+"/"+dm.Name] = true
+}
+
+// status is "deployed" once a workload of that name is in the report, and
+// "overdue" when its launch date has passed without one
+func (t *reservationTracker) status(r reservation, now time.Time) string {
+	switch {
+	case t.deployed[r.Namespace+"/"+r.Name]:
+		return "deployed"
+	case r.Launch.Before(now.Truncate(24 * time.Hour)):
+		return "overdue"
+	}
+	return "planned"
+}
+
+// Print lists the reservations by launch date with the free headroom left
+// after each one not yet deployed, given the cluster's free headroom today
+func (t *reservationTracker) Print(out io.Writer, format string, free ResourceMetrics, now time.Time) {
+	fmt.Fprintln(out)
+	if len(t.reservations) == 0 {
+		fmt.Fprintln(out, "No reservations found")
+		return
+	}
+
+	table := resultTable{headers: []string{"RESERVATION", "NAMESPACE", "LAUNCH", "REPLICAS", "CPU", "MEMORY", "STATUS", "HEADROOM AFTER"}}
+	var committed ResourceMetrics
+	headroom := free
+	for _, r := range t.reservations {
+		status := t.status(r, now)
+		after := "-"
+		if status != "deployed" {
+			committed.add(r.Requests)
+			headroom.add(ResourceMetrics{CPU: -r.Requests.CPU, Memory: -r.Requests.Memory})
+			after = formatHeadroom(headroom)
+		}
+		table.rows = append(table.rows, []string{
+			r.Name, r.Namespace, r.Launch.Format(reservationDateLayout), strconv.Itoa(int(r.Replicas)),
+			formatCPU(r.Requests.CPU), formatMemory(r.Requests.Memory), status, after,
+		})
+	}
+	table.subtotals = [][]string{{"FREE HEADROOM", "", "", "", formatSignedCPU(free.CPU), formatSignedMemory(free.Memory), "", ""}}
+	table.total = []string{"COMMITTED", "", "", "", formatCPU(committed.CPU), formatMemory(committed.Memory), "", formatHeadroom(headroom)}
+
+	if format == FormatMarkdown {
+		printMarkdownResults(out, table, false)
+	} else {
+		printTableResults(out, table, false)
+	}
+}
+
+// formatHeadroom shows CPU and memory headroom, which goes negative once
+// reservations outgrow the cluster
+func formatHeadroom(headroom ResourceMetrics) string {
+	return formatSignedCPU(headroom.CPU) + ", " + formatSignedMemory(headroom.Memory)
+}
+
+func formatSignedCPU(milliCores int64) string {
+	if milliCores < 0 {
+		return "-" + formatCPU(-milliCores)
+	}
+	return formatCPU(milliCores)
+}
+
+func formatSignedMemory(bytes int64) string {
+	if bytes < 0 {
+		return "-" + formatMemory(-bytes)
+	}
+	return formatMemory(bytes)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadReservations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reservations.yaml")
+	content := `reservations:
+  - name: search-v2
+    namespace: search
+    cpu: 2
+    memory: 4Gi
+    replicas: 3
+    launch: 2026-12-01
+  - name: checkout
+    namespace: shop
+    cpu: 500m
+    memory: 512Mi
+    launch: 2026-11-01
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reservations, err := loadReservations(path)
+	if err != nil {
+		t.Fatalf("loadReservations() error = %v", err)
+	}
+	if len(reservations) != 2 {
+		t.Fatalf("loadReservations() returned %d reservations, want 2", len(reservations))
+	}
+	if reservations[0].Name != "checkout" {
+		t.Errorf("reservations[0] = %s, want checkout (earliest launch first)", reservations[0].Name)
+	}
+	if reservations[0].Replicas != 1 {
+		t.Errorf("checkout replicas = %d, want default of 1", reservations[0].Replicas)
+	}
+	want := ResourceMetrics{CPU: 6000, Memory: 12 << 30}
+	if reservations[1].Requests != want {
+		t.Errorf("search-v2 requests = %+v, want %+v", reservations[1].Requests, want)
+	}
+
+	for name, bad := range map[string]string{
+		"no name":     "reservations:\n  - cpu: 1\n    launch: 2026-11-01\n",
+		"bad date":    "reservations:\n  - name: x\n    launch: 11/01/2026\n",
+		"bad cpu":     "reservations:\n  - name: x\n    cpu: lots\n    launch: 2026-11-01\n",
+		"not a list":  "reservations: 3\n",
+		"empty date":  "reservations:\n  - name: x\n",
+		"wrong types": "reservations:\n  - name: x\n    replicas: many\n    launch: 2026-11-01\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadReservations(path); err == nil {
+				t.Error("loadReservations() should return an error")
+			}
+		})
+	}
+}
+
+func TestReservationTrackerPrint(t *testing.T) {
+	now := time.Date(2026, 11, 15, 12, 0, 0, 0, time.UTC)
+	day := func(s string) time.Time {
+		d, _ := time.Parse(reservationDateLayout, s)
+		return d
+	}
+	tracker := newReservationTracker([]reservation{
+		{Name: "late", Namespace: "a", Replicas: 1, Requests: ResourceMetrics{CPU: 1000, Memory: 1 << 30}, Launch: day("2026-11-01")},
+		{Name: "live", Namespace: "a", Replicas: 2, Requests: ResourceMetrics{CPU: 4000, Memory: 4 << 30}, Launch: day("2026-11-10")},
+		{Name: "next", Namespace: "b", Replicas: 3, Requests: ResourceMetrics{CPU: 3000, Memory: 2 << 30}, Launch: day("2026-12-01")},
+	})
+	tracker.Add(DeploymentMetrics{Name: "live", Namespace: "a"})
+
+	var buf bytes.Buffer
+	tracker.Print(&buf, FormatTable, ResourceMetrics{CPU: 2000, Memory: 8 << 30}, now)
+	output := buf.String()
+
+	for _, want := range []string{"overdue", "deployed", "planned", "1.00 cores, 7.00 GB", "-2.00 cores, 5.00 GB", "4.00 cores"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Print() output missing %q:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	newReservationTracker(nil).Print(&buf, FormatTable, ResourceMetrics{}, now)
+	if !strings.Contains(buf.String(), "No reservations found") {
+		t.Errorf("Print() with no reservations = %q", buf.String())
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The published schemas, generated from the report types by the schema
+// subcommand and kept in the repository under schema/
+const (
+	reportSchemaURL     = "https://raw.githubusercontent.com/madeddie/k8s-resource-cli/main/schema/report.schema.json"
+	reportLineSchemaURL = "https://raw.githubusercontent.com/madeddie/k8s-resource-cli/main/schema/report-line.schema.json"
+	jsonSchemaDialect   = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// schemaDefNames names the report types in $defs, the names generated
+// clients end up with
+var schemaDefNames = map[reflect.Type]string{
+	reflect.TypeOf(DeploymentMetrics{}): "Workload",
+	reflect.TypeOf(ResourceMetrics{}):   "Resources",
+	reflect.TypeOf(jsonTotals{}):        "Totals",
+	reflect.TypeOf(runMetadata{}):       "Metadata",
+	reflect.TypeOf(ownerNode{}):         "Owner",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaBuilder turns Go types into JSON Schema, following the json tags the
+// way encoding/json does. Named struct types land in defs and are referenced.
+type schemaBuilder struct {
+	defs map[string]interface{}
+}
+
+func (b *schemaBuilder) typeSchema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.Struct:
+		name, ok := schemaDefNames[t]
+		if !ok {
+			return b.structSchema(t)
+		}
+		if _, done := b.defs[name]; !done {
+			b.defs[name] = nil // placeholder, so self references stop here
+			b.defs[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	}
+	panic(fmt.Sprintf("no JSON Schema for %s", t))
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.typeSchema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// totalsByGroup is a total per OS, type or namespace, keyed by the group
+func (b *schemaBuilder) totalsByGroup() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "additionalProperties": b.typeSchema(reflect.TypeOf(jsonTotals{}))}
+}
+
+// reportSchema describes the --format json document of a workload report
+func reportSchema() map[string]interface{} {
+	b := &schemaBuilder{defs: make(map[string]interface{})}
+	schema := map[string]interface{}{
+		"$schema":     jsonSchemaDialect,
+		"$id":         reportSchemaURL,
+		"title":       "k8s-resource-cli report",
+		"description": "A workload report written with --format json. max_requests of items and totals is the effective value: current requests when the workload can't scale beyond them.",
+		"type":        "object",
+		"properties": map[string]interface{}{
+			"$schema":                 map[string]interface{}{"type": "string"},
+			"metadata":                b.typeSchema(reflect.TypeOf(runMetadata{})),
+			"items":                   b.typeSchema(reflect.TypeOf([]DeploymentMetrics{})),
+			"total":                   b.typeSchema(reflect.TypeOf(jsonTotals{})),
+			"total_by_os":             b.totalsByGroup(),
+			"total_by_type":           b.totalsByGroup(),
+			"total_by_namespace":      b.totalsByGroup(),
+			"total_by_priority_class": b.totalsByGroup(),
+		},
+		"required":             []string{"items", "total"},
+		"additionalProperties": false,
+	}
+	schema["$defs"] = b.defs
+	return schema
+}
+
+// reportLineSchema describes each line of --format jsonl: a workload, or with
+// --total-only a single totals line
+func reportLineSchema() map[string]interface{} {
+	b := &schemaBuilder{defs: make(map[string]interface{})}
+	schema := map[string]interface{}{
+		"$schema":     jsonSchemaDialect,
+		"$id":         reportLineSchemaURL,
+		"title":       "k8s-resource-cli report line",
+		"description": "A line of a workload report written with --format jsonl: a workload, or with --total-only the totals.",
+		"oneOf": []interface{}{
+			b.typeSchema(reflect.TypeOf(DeploymentMetrics{})),
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"total":         b.typeSchema(reflect.TypeOf(jsonTotals{})),
+					"total_by_type": b.totalsByGroup(),
+				},
+				"required":             []string{"total"},
+				"additionalProperties": false,
+			},
+		},
+	}
+	schema["$defs"] = b.defs
+	return schema
+}
+
+// marshalSchema encodes a schema the way it is published
+func marshalSchema(schema map[string]interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaValidator checks decoded JSON against the parts of JSON Schema the
+// generated schemas use: type, properties, required, additionalProperties,
+// items, oneOf and $ref into $defs. Formats aren't checked.
+type schemaValidator struct {
+	defs map[string]interface{}
+}
+
+func newSchemaValidator(schema map[string]interface{}) *schemaValidator {
+	defs, _ := schema["$defs"].(map[string]interface{})
+	return &schemaValidator{defs: defs}
+}
+
+// Validate returns a problem per value that doesn't match, by JSON path
+func (v *schemaValidator) Validate(schema map[string]interface{}, value interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, _ := v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if def == nil {
+			return []string{fmt.Sprintf("%s: unknown schema %s", path, ref)}
+		}
+		return v.Validate(def, value, path)
+	}
+	if options, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		var closest []string
+		for _, option := range options {
+			problems := v.Validate(option.(map[string]interface{}), value, path)
+			if len(problems) == 0 {
+				matched++
+			} else if closest == nil || len(problems) < len(closest) {
+				closest = problems
+			}
+		}
+		switch {
+		case matched == 1:
+			return nil
+		case matched > 1:
+			return []string{fmt.Sprintf("%s: matches more than one of the allowed shapes", path)}
+		}
+		return closest
+	}
+
+	switch want := schema["type"]; want {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object", path)}
+		}
+		return v.validateObject(schema, object, path)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array", path)}
+		}
+		var problems []string
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		for i, item := range items {
+			problems = append(problems, v.Validate(itemSchema, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{fmt.Sprintf("%s: expected a string", path)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected a boolean", path)}
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return []string{fmt.Sprintf("%s: expected an integer", path)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: expected a number", path)}
+		}
+	}
+	return nil
+}
+
+func (v *schemaValidator) validateObject(schema, object map[string]interface{}, path string) []string {
+	var problems []string
+	required, _ := schema["required"].([]interface{})
+	for _, name := range required {
+		if _, ok := object[name.(string)]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing %s", path, name))
+		}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		child := path + "." + key
+		if property, ok := properties[key].(map[string]interface{}); ok {
+			problems = append(problems, v.Validate(property, object[key], child)...)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				problems = append(problems, fmt.Sprintf("%s: not part of the schema", child))
+			}
+		case map[string]interface{}:
+			problems = append(problems, v.Validate(extra, object[key], child)...)
+		}
+	}
+	return problems
+}
+
+// validateReport checks a --format json document, or each line of a
+// --format jsonl report, against its published schema
+func validateReport(data []byte, format string) ([]string, error) {
+	schema := reportSchema()
+	if format == FormatJSONL {
+		schema = reportLineSchema()
+	}
+	// Round trip the schema so the validator sees it as published
+	published, err := marshalSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(published, &decoded); err != nil {
+		return nil, err
+	}
+	validator := newSchemaValidator(decoded)
+
+	if format != FormatJSONL {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("not JSON: %w", err)
+		}
+		return validator.Validate(decoded, value, "$"), nil
+	}
+	var problems []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			return nil, fmt.Errorf("line %d is not JSON: %w", line, err)
+		}
+		for _, problem := range validator.Validate(decoded, value, "$") {
+			problems = append(problems, fmt.Sprintf("line %d: %s", line, problem))
+		}
+	}
+	return problems, scanner.Err()
+}
+
+// runSchemaCommand implements the "schema" subcommand: it prints the JSON
+// Schema of the report format, or checks a report against it
+func runSchemaCommand(args []string) {
+	var format string
+	var validatePath string
+
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	fs.StringVar(&format, "format", FormatJSON, "Report format to describe: json, or jsonl for a schema of each line")
+	fs.StringVar(&validatePath, "validate", "", "Check this report, or - for stdin, against the schema instead of printing it")
+	parseFlags(fs, args)
+
+	if format != FormatJSON && format != FormatJSONL {
+		fmt.Fprintf(os.Stderr, "Error: Invalid --format '%s'. Must be 'json' or 'jsonl'\n", format)
+		os.Exit(exitUsage)
+	}
+
+	if validatePath == "" {
+		schema := reportSchema()
+		if format == FormatJSONL {
+			schema = reportLineSchema()
+		}
+		data, err := marshalSchema(schema)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitUsage)
+		}
+		os.Stdout.Write(data)
+		return
+	}
+
+	var data []byte
+	var err error
+	if validatePath == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(validatePath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading report: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	problems, err := validateReport(data, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		fmt.Fprintf(os.Stderr, "Invalid report: %d problems\n", len(problems))
+		os.Exit(exitUsage)
+	}
+	fmt.Fprintln(os.Stderr, "Report is valid")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublishedSchemasUpToDate(t *testing.T) {
+	for path, schema := range map[string]map[string]interface{}{
+		"../../schema/report.schema.json":      reportSchema(),
+		"../../schema/report-line.schema.json": reportLineSchema(),
+	} {
+		want, err := marshalSchema(schema)
+		if err != nil {
+			t.Fatalf("marshalSchema() error = %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading published schema: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date; regenerate it with the schema subcommand", path)
+		}
+	}
+}
+
+func schemaTestWorkloads() []DeploymentMetrics {
+	return []DeploymentMetrics{
+		{
+			Name: "web", Namespace: "shop", Type: "Deployment", Source: SourceKubernetes, UID: "1234",
+			CurrentReplicas: 2, DesiredReplicas: 2, MaxReplicas: 4, MinReplicas: 2,
+			Usage: ResourceMetrics{CPU: 100, Memory: 1048576}, Requests: ResourceMetrics{CPU: 200, Memory: 2097152},
+			Labels: map[string]string{"team": "shop"}, ExpectedPeak: &ResourceMetrics{CPU: 400},
+			Images: []string{"nginx:1.27"}, OS: "linux",
+			Owners: []*ownerNode{{Kind: "ReplicaSet", Name: "web-7d4b", Children: []*ownerNode{{Kind: "Pod", Name: "web-7d4b-a"}}}},
+		},
+		{Name: "report", Namespace: "shop", Type: "CronJob", Source: SourceKubernetes, Suspended: true, Schedule: "0 * * * *"},
+	}
+}
+
+func TestJSONReportMatchesSchema(t *testing.T) {
+	var buf bytes.Buffer
+	meta := &runMetadata{GeneratedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), Version: "v1.0.0", Context: "prod", Flags: map[string]string{"A": "true"}}
+	p := newResultPrinter(&buf, OutputTypeRequests, false, false, FormatJSON, tableOptions{groupBy: GroupByNamespace}, meta)
+	for _, dm := range schemaTestWorkloads() {
+		p.Add(dm)
+	}
+	p.Flush()
+
+	if !strings.HasPrefix(buf.String(), `{"$schema":"`+reportSchemaURL+`",`) {
+		t.Errorf("report doesn't name its schema:\n%s", buf.String())
+	}
+	problems, err := validateReport(buf.Bytes(), FormatJSON)
+	if err != nil {
+		t.Fatalf("validateReport() error = %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("report doesn't match its schema: %v", problems)
+	}
+}
+
+func TestJSONLReportMatchesSchema(t *testing.T) {
+	for _, totalOnly := range []bool{false, true} {
+		var buf bytes.Buffer
+		p := newResultPrinter(&buf, OutputTypeRequests, false, totalOnly, FormatJSONL, tableOptions{}, nil)
+		for _, dm := range schemaTestWorkloads() {
+			p.Add(dm)
+		}
+		p.Flush()
+
+		problems, err := validateReport(buf.Bytes(), FormatJSONL)
+		if err != nil {
+			t.Fatalf("validateReport() error = %v", err)
+		}
+		if len(problems) > 0 {
+			t.Errorf("jsonl report (total-only %t) doesn't match its schema: %v", totalOnly, problems)
+		}
+	}
+}
+
+func TestValidateReportProblems(t *testing.T) {
+	report := `{"items":[{"name":"web","namespace":"shop","type":"Deployment","source":"kubernetes","current_replicas":1.5,` +
+		`"desired_replicas":1,"max_replicas":1,"ready_replicas":1,"available_replicas":1,"usage":{"cpu_millicores":1,"memory_bytes":1},"requests":{"cpu_millicores":"1","memory_bytes":1},` +
+		`"limits":{"cpu_millicores":0,"memory_bytes":0},"max_requests":{"cpu_millicores":0,"memory_bytes":0},"colour":"red"}]}`
+
+	problems, err := validateReport([]byte(report), FormatJSON)
+	if err != nil {
+		t.Fatalf("validateReport() error = %v", err)
+	}
+	want := []string{
+		"$: missing total",
+		"$.items[0].colour: not part of the schema",
+		"$.items[0].current_replicas: expected an integer",
+		"$.items[0].requests.cpu_millicores: expected an integer",
+	}
+	if strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("validateReport() =\n%s\nwant\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := validateReport([]byte("{\"total\":{}}\nnot json\n"), FormatJSONL); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("validateReport() of a broken line error = %v, want it to name line 2", err)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// reportSigner hashes everything written through it so the finished report
+// can be signed without buffering it. The Ed25519 signature covers the
+// SHA-256 digest of the report bytes.
+type reportSigner struct {
+	key    ed25519.PrivateKey
+	digest hash.Hash
+}
+
+func newReportSigner(key ed25519.PrivateKey) *reportSigner {
+	return &reportSigner{key: key, digest: sha256.New()}
+}
+
+func (s *reportSigner) Write(p []byte) (int, error) {
+	return s.digest.Write(p)
+}
+
+// WriteSignature writes the detached signature file: the report's SHA-256
+// digest and the Ed25519 signature over it, one per line
+func (s *reportSigner) WriteSignature(path string) error {
+	sum := s.digest.Sum(nil)
+	signature := ed25519.Sign(s.key, sum)
+	content := fmt.Sprintf("sha256 %s\ned25519 %s\n", hex.EncodeToString(sum), base64.StdEncoding.EncodeToString(signature))
+	return os.WriteFile(path, []byte(content), 0o644)
+}
+
+// loadSigningKey reads a PKCS#8 PEM Ed25519 private key, as written by
+// `openssl genpkey -algorithm ed25519`
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key %s: %w", path, err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an Ed25519 key", path)
+	}
+	return edKey, nil
+}
+
+// loadVerifyKey reads a PKIX PEM Ed25519 public key, as written by
+// `openssl pkey -pubout`
+func loadVerifyKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an Ed25519 key", path)
+	}
+	return edKey, nil
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
+
+// verifyReport checks a report against a signature file written by WriteSignature
+func verifyReport(report io.Reader, signatureFile string, key ed25519.PublicKey) error {
+	f, err := os.Open(signatureFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name, value, ok := strings.Cut(scanner.Text(), " "); ok {
+			fields[name] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(fields["ed25519"])
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("signature file %s has no valid ed25519 line", signatureFile)
+	}
+
+	digest := sha256.New()
+	if _, err := io.Copy(digest, report); err != nil {
+		return err
+	}
+	sum := digest.Sum(nil)
+
+	if fields["sha256"] != hex.EncodeToString(sum) {
+		return fmt.Errorf("report digest does not match the signature file")
+	}
+	if !ed25519.Verify(key, sum, signature) {
+		return fmt.Errorf("signature is not valid for this key")
+	}
+	return nil
+}
+
+// runVerifyCommand implements the "verify" subcommand, checking a saved
+// report against its detached signature
+func runVerifyCommand(args []string) {
+	var publicKey string
+	var signatureFile string
+
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.StringVar(&publicKey, "public-key", "", "Ed25519 public key (PEM) matching the --sign-key used (required)")
+	fs.StringVar(&signatureFile, "signature-file", "", "Signature file written alongside the report (required)")
+	parseFlags(fs, args)
+
+	if publicKey == "" || signatureFile == "" || fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: k8s-resource-cli verify --public-key key.pub --signature-file report.sig report\n")
+		os.Exit(exitUsage)
+	}
+
+	key, err := loadVerifyKey(publicKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading public key: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	report, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening report: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	defer report.Close()
+
+	if err := verifyReport(report, signatureFile, key); err != nil {
+		fmt.Fprintf(os.Stderr, "Verification failed: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	fmt.Println("Signature OK")
+}
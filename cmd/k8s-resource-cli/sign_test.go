@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignAndVerifyReport(t *testing.T) {
+	dir := t.TempDir()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPath := filepath.Join(dir, "key.pub")
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := loadSigningKey(keyPath)
+	if err != nil {
+		t.Fatalf("loadSigningKey() error = %v", err)
+	}
+	signer := newReportSigner(key)
+	report := "DEPLOYMENT   NAMESPACE   REPLICAS   CPU    MEMORY\nTOTAL                              200m   1.00 GB\n"
+	signer.Write([]byte(report))
+
+	sigPath := filepath.Join(dir, "report.sig")
+	if err := signer.WriteSignature(sigPath); err != nil {
+		t.Fatalf("WriteSignature() error = %v", err)
+	}
+
+	verifyKey, err := loadVerifyKey(pubPath)
+	if err != nil {
+		t.Fatalf("loadVerifyKey() error = %v", err)
+	}
+	if err := verifyReport(strings.NewReader(report), sigPath, verifyKey); err != nil {
+		t.Errorf("verifyReport() on the signed report error = %v", err)
+	}
+	if err := verifyReport(bytes.NewReader([]byte(report+"tampered")), sigPath, verifyKey); err == nil {
+		t.Error("verifyReport() on a modified report should fail")
+	}
+
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	if err := verifyReport(strings.NewReader(report), sigPath, otherKey); err == nil {
+		t.Error("verifyReport() with the wrong key should fail")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keys accepted by --sort-by
+const (
+	SortByCPU          = "cpu"
+	SortByMemory       = "memory"
+	SortByName         = "name"
+	SortByNamespace    = "namespace"
+	SortByUsagePercent = "usage-percent"
+)
+
+// sortOrder is how --sort-by orders rows. Numeric keys default to descending
+// so the biggest consumers come first, names to ascending.
+type sortOrder struct {
+	key        string
+	descending bool
+}
+
+// parseSortOrder validates --sort-by and --sort-order. An empty key means
+// rows keep the order they were collected in.
+func parseSortOrder(key, order string) (sortOrder, error) {
+	switch key {
+	case "", SortByCPU, SortByMemory, SortByName, SortByNamespace, SortByUsagePercent:
+	default:
+		return sortOrder{}, fmt.Errorf("invalid --sort-by '%s'. Must be 'cpu', 'memory', 'name', 'namespace', or 'usage-percent'", key)
+	}
+
+	s := sortOrder{key: key, descending: key != "" && key != SortByName && key != SortByNamespace}
+	switch order {
+	case "":
+	case "asc":
+		s.descending = false
+	case "desc":
+		s.descending = true
+	default:
+		return sortOrder{}, fmt.Errorf("invalid --sort-order '%s'. Must be 'asc' or 'desc'", order)
+	}
+	return s, nil
+}
+
+// sortValue is the figure rows are compared on for the numeric keys: the
+// one the output type shows, requests for combined, and for usage-percent
+// the higher of CPU and memory usage as a percentage of requests
+func sortValue(dm DeploymentMetrics, key, outputType string) float64 {
+	metrics := dm.Requests
+	switch outputType {
+	case OutputTypeUsage:
+		metrics = dm.Usage
+	case OutputTypeMaxRequests:
+		metrics = dm.EffectiveMaxRequests()
+	case OutputTypeIdle:
+		metrics = idleMetrics(dm.Usage, dm.Requests)
+	}
+
+	switch key {
+	case SortByCPU:
+		return float64(metrics.CPU)
+	case SortByMemory:
+		return float64(metrics.Memory)
+	case SortByUsagePercent:
+		var cpu, memory float64
+		if dm.Requests.CPU > 0 {
+			cpu = float64(dm.Usage.CPU) / float64(dm.Requests.CPU) * 100
+		}
+		if dm.Requests.Memory > 0 {
+			memory = float64(dm.Usage.Memory) / float64(dm.Requests.Memory) * 100
+		}
+		return max(cpu, memory)
+	}
+	return 0
+}
+
+// less orders two workloads, falling back to namespace and name so equal
+// figures still sort the same way on every run
+func (s sortOrder) less(a, b DeploymentMetrics, outputType string) bool {
+	var cmp int
+	switch s.key {
+	case SortByName:
+		cmp = strings.Compare(a.Name, b.Name)
+	case SortByNamespace:
+		cmp = strings.Compare(a.Namespace, b.Namespace)
+	default:
+		va, vb := sortValue(a, s.key, outputType), sortValue(b, s.key, outputType)
+		if va < vb {
+			cmp = -1
+		} else if va > vb {
+			cmp = 1
+		}
+	}
+	if cmp != 0 {
+		return (cmp < 0) != s.descending
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// sortingPrinter holds every workload back until Flush, then hands them to
+// the next printer in sorted order. Streaming formats lose their streaming.
+type sortingPrinter struct {
+	next        resultPrinter
+	order       sortOrder
+	outputType  string
+	deployments []DeploymentMetrics
+}
+
+func (p *sortingPrinter) Add(dm DeploymentMetrics) {
+	p.deployments = append(p.deployments, dm)
+}
+
+func (p *sortingPrinter) Flush() {
+	sort.SliceStable(p.deployments, func(i, j int) bool {
+		return p.order.less(p.deployments[i], p.deployments[j], p.outputType)
+	})
+	for _, dm := range p.deployments {
+		p.next.Add(dm)
+	}
+	p.next.Flush()
+}
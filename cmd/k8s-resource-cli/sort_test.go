@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+type recordingPrinter struct {
+	names   []string
+	flushed bool
+}
+
+func (p *recordingPrinter) Add(dm DeploymentMetrics) { p.names = append(p.names, dm.Name) }
+func (p *recordingPrinter) Flush()                   { p.flushed = true }
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		key, order string
+		want       sortOrder
+		wantErr    bool
+	}{
+		{"", "", sortOrder{}, false},
+		{"cpu", "", sortOrder{key: "cpu", descending: true}, false},
+		{"name", "", sortOrder{key: "name"}, false},
+		{"namespace", "desc", sortOrder{key: "namespace", descending: true}, false},
+		{"usage-percent", "asc", sortOrder{key: "usage-percent"}, false},
+		{"replicas", "", sortOrder{}, true},
+		{"cpu", "up", sortOrder{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.order, func(t *testing.T) {
+			got, err := parseSortOrder(tt.key, tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSortOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSortOrder() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortingPrinter(t *testing.T) {
+	deployments := []DeploymentMetrics{
+		{Name: "small", Namespace: "b", Requests: ResourceMetrics{CPU: 100, Memory: 4096}, Usage: ResourceMetrics{CPU: 90}},
+		{Name: "big", Namespace: "a", Requests: ResourceMetrics{CPU: 2000, Memory: 1024}, Usage: ResourceMetrics{CPU: 500}},
+		{Name: "medium", Namespace: "c", Requests: ResourceMetrics{CPU: 500, Memory: 2048}, Usage: ResourceMetrics{Memory: 4096}},
+		{Name: "also-medium", Namespace: "c", Requests: ResourceMetrics{CPU: 500}},
+	}
+
+	tests := []struct {
+		key, order string
+		want       []string
+	}{
+		{"cpu", "", []string{"big", "also-medium", "medium", "small"}},
+		{"cpu", "asc", []string{"small", "also-medium", "medium", "big"}},
+		{"memory", "", []string{"small", "medium", "big", "also-medium"}},
+		{"name", "", []string{"also-medium", "big", "medium", "small"}},
+		{"namespace", "", []string{"big", "small", "also-medium", "medium"}},
+		{"usage-percent", "", []string{"medium", "small", "big", "also-medium"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.order, func(t *testing.T) {
+			order, err := parseSortOrder(tt.key, tt.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := &recordingPrinter{}
+			p := &sortingPrinter{next: next, order: order, outputType: OutputTypeRequests}
+			for _, dm := range deployments {
+				p.Add(dm)
+			}
+			if len(next.names) != 0 {
+				t.Fatal("sortingPrinter passed rows on before Flush")
+			}
+			p.Flush()
+			if !reflect.DeepEqual(next.names, tt.want) || !next.flushed {
+				t.Errorf("order = %v (flushed %v), want %v", next.names, next.flushed, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+)
+
+// usageStats summarizes the usage of one resource across a workload's pods
+type usageStats struct {
+	Min, P50, Avg, P95, Max int64
+}
+
+// summarizeUsage computes the stats of per-pod values, with nearest-rank
+// percentiles so each one is the usage of an actual pod
+func summarizeUsage(values []int64) usageStats {
+	if len(values) == 0 {
+		return usageStats{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	var sum int64
+	for _, value := range sorted {
+		sum += value
+	}
+	rank := func(percentile int) int64 {
+		index := (percentile*len(sorted)+99)/100 - 1
+		return sorted[max(index, 0)]
+	}
+	return usageStats{
+		Min: sorted[0],
+		P50: rank(50),
+		Avg: sum / int64(len(sorted)),
+		P95: rank(95),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// statsPrinter prints, instead of each workload's totals, how its usage is
+// spread over its running pods, showing skew without a row per pod
+type statsPrinter struct {
+	out       io.Writer
+	workloads []DeploymentMetrics
+}
+
+func (p *statsPrinter) Add(dm DeploymentMetrics) {
+	p.workloads = append(p.workloads, dm)
+}
+
+func (p *statsPrinter) Flush() {
+	w := tabwriter.NewWriter(p.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "NAME\tNAMESPACE\tPODS\tRESOURCE\tMIN\tP50\tAVG\tP95\tMAX\n")
+	listed := 0
+	for _, dm := range p.workloads {
+		pods := ownerPods(dm.Owners)
+		if len(pods) == 0 {
+			continue
+		}
+		listed++
+		cpu := make([]int64, len(pods))
+		memory := make([]int64, len(pods))
+		for i, pod := range pods {
+			cpu[i], memory[i] = pod.Usage.CPU, pod.Usage.Memory
+		}
+		c, m := summarizeUsage(cpu), summarizeUsage(memory)
+		fmt.Fprintf(w, "%s\t%s\t%d\tcpu\t%s\t%s\t%s\t%s\t%s\n", dm.Name, dm.Namespace, len(pods),
+			formatCPU(c.Min), formatCPU(c.P50), formatCPU(c.Avg), formatCPU(c.P95), formatCPU(c.Max))
+		fmt.Fprintf(w, "\t\t\tmemory\t%s\t%s\t%s\t%s\t%s\n",
+			formatMemory(m.Min), formatMemory(m.P50), formatMemory(m.Avg), formatMemory(m.P95), formatMemory(m.Max))
+	}
+	if listed == 0 {
+		fmt.Fprintln(p.out, "No running pods found")
+		return
+	}
+	w.Flush()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSummarizeUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		want   usageStats
+	}{
+		{"none", nil, usageStats{}},
+		{"one pod", []int64{100}, usageStats{Min: 100, P50: 100, Avg: 100, P95: 100, Max: 100}},
+		{"skewed", []int64{100, 900, 100, 100}, usageStats{Min: 100, P50: 100, Avg: 300, P95: 900, Max: 900}},
+		{"ten pods", []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, usageStats{Min: 10, P50: 50, Avg: 55, P95: 100, Max: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeUsage(tt.values); got != tt.want {
+				t.Errorf("summarizeUsage(%v) = %+v, want %+v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := &statsPrinter{out: &buf}
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "shop", Owners: []*ownerNode{{Kind: "ReplicaSet", Children: []*ownerNode{
+		{Kind: "Pod", Name: "web-a", Usage: ResourceMetrics{CPU: 100, Memory: 1048576}},
+		{Kind: "Pod", Name: "web-b", Usage: ResourceMetrics{CPU: 500, Memory: 3145728}},
+	}}}})
+	// Workloads without pods of their own are left out
+	p.Add(DeploymentMetrics{Name: "etcd", Namespace: "kube-system"})
+	p.Flush()
+
+	want := "NAME   NAMESPACE   PODS   RESOURCE   MIN       P50       AVG       P95       MAX\n" +
+		"web    shop        2      cpu        100m      100m      300m      500m      500m\n" +
+		"                          memory     1.00 MB   1.00 MB   2.00 MB   3.00 MB   3.00 MB\n"
+	if buf.String() != want {
+		t.Errorf("stats output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
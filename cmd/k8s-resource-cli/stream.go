@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// streamColumnWidth is the width table columns are padded to when streaming,
+// by header; figures get streamFigureWidth. Longer values still print in full
+// and push the rest of their row out of line.
+var streamColumnWidth = map[string]int{
+	"NAME": 40, "DEPLOYMENT": 40, "TYPE": 10, "SOURCE": 10, "NAMESPACE": 20, "TARGET": 20, "REPLICAS": 8,
+}
+
+const streamFigureWidth = 12
+
+// streamPrinter writes each workload's row the moment it is collected, for
+// clusters where waiting for the whole table takes minutes. With no rows to
+// measure beforehand the columns are fixed: the workload, its namespace,
+// replicas and the figures of the output type, each padded to a set width.
+// Totals follow once everything is collected.
+type streamPrinter struct {
+	out        io.Writer
+	outputType string
+	format     string
+	otherTypes bool // cronjobs or static pods may show up, so there is a TYPE column
+	usePorter  bool
+	mixed      bool // Porter and Kubernetes rows, so there is a SOURCE column
+	colors     *colorThresholds
+	labels     []string // --label-columns
+	showLabels bool
+	headers    []string
+	widths     []int
+	figures    resourceColumns
+	count      int
+	totals     jsonTotals
+	byType     map[string]*jsonTotals
+	byOS       map[string]*jsonTotals
+}
+
+func newStreamPrinter(out io.Writer, outputType, format string, otherTypes, usePorter, mixed bool, colors *colorThresholds, labels []string, showLabels bool) *streamPrinter {
+	p := &streamPrinter{
+		out: out, outputType: outputType, format: format, otherTypes: otherTypes, usePorter: usePorter, mixed: mixed, colors: colors,
+		labels: labels, showLabels: showLabels,
+		byType: make(map[string]*jsonTotals), byOS: make(map[string]*jsonTotals),
+	}
+	if otherTypes {
+		p.headers = append(p.headers, "NAME", "TYPE")
+	} else {
+		p.headers = append(p.headers, "DEPLOYMENT")
+	}
+	if mixed {
+		p.headers = append(p.headers, "SOURCE")
+	}
+	if usePorter && !mixed {
+		p.headers = append(p.headers, "TARGET")
+	} else {
+		p.headers = append(p.headers, "NAMESPACE")
+	}
+	p.headers = append(p.headers, "REPLICAS")
+	// Whether there will be usage isn't known yet, so the output type decides
+	p.figures = resourceColumns{
+		cpu:         len(p.headers),
+		utilization: outputType == OutputTypeUsage || outputType == OutputTypeCombined || outputType == OutputTypeIdle || outputType == OutputTypeAll,
+	}
+	p.headers = append(p.headers, resourceHeaders(outputType)...)
+	if p.figures.utilization {
+		p.headers = append(p.headers, "CPU%", "MEM%")
+	}
+	p.headers = append(p.headers, labelHeaders(labels, showLabels)...)
+	for _, name := range p.headers {
+		width, ok := streamColumnWidth[name]
+		if !ok {
+			width = streamFigureWidth
+		}
+		p.widths = append(p.widths, max(width, len(header(name))))
+	}
+	return p
+}
+
+func (p *streamPrinter) Add(dm DeploymentMetrics) {
+	if p.count == 0 {
+		p.writeHeader()
+	}
+	p.count++
+	p.totals.add(dm)
+	addGroupTotals(p.byType, dm.Type, dm)
+	addGroupTotals(p.byOS, dm.OS, dm)
+
+	row := []string{displayName(dm)}
+	if p.otherTypes {
+		typ := dm.Type
+		if dm.Suspended {
+			typ += " (suspended)"
+		}
+		row = append(row, typ)
+	}
+	if p.mixed {
+		row = append(row, dm.Source)
+	}
+	replicas := fmt.Sprintf("%d/%d", dm.CurrentReplicas, dm.MaxReplicas)
+	if p.outputType == OutputTypeMaxRequests {
+		replicas = fmt.Sprintf("%d", dm.MaxReplicas)
+	}
+	row = append(row, dm.Namespace, replicas)
+	var figures jsonTotals
+	figures.add(dm)
+	row = append(row, resourceCells(p.outputType, figures)...)
+	if p.figures.utilization {
+		row = append(row, formatUtilization(dm.Usage.CPU, dm.Requests.CPU), formatUtilization(dm.Usage.Memory, dm.Requests.Memory))
+	}
+	row = append(row, labelCells(dm, p.labels, p.showLabels)...)
+	color := colorDefault
+	if p.colors != nil {
+		color = p.colors.rowColor(dm)
+	}
+	p.writeRow(row, color, false)
+}
+
+func (p *streamPrinter) Flush() {
+	if p.count == 0 {
+		progress.Clear()
+		fmt.Fprintln(p.out, "No deployments found")
+		return
+	}
+	var subtotals [][]string
+	subtotals = append(subtotals, subtotalRows(p.byType, p.outputType, len(p.headers), p.figures)...)
+	subtotals = append(subtotals, subtotalRows(p.byOS, p.outputType, len(p.headers), p.figures)...)
+	for _, subtotal := range subtotals {
+		p.writeRow(subtotal, colorDefault, true)
+	}
+	total := make([]string, len(p.headers))
+	total[0] = header("TOTAL")
+	p.figures.fill(total, p.outputType, p.totals)
+	p.writeRow(total, colorDefault, true)
+}
+
+func (p *streamPrinter) writeHeader() {
+	headers := make([]string, len(p.headers))
+	for i, name := range p.headers {
+		headers[i] = header(name)
+	}
+	p.writeRow(headers, colorDefault, false)
+	if p.format == FormatMarkdown {
+		fmt.Fprintf(p.out, "|%s\n", strings.Repeat(" --- |", len(p.headers)))
+	}
+}
+
+// writeRow writes one line, first clearing the progress line it would
+// otherwise be drawn over
+func (p *streamPrinter) writeRow(cells []string, color string, total bool) {
+	progress.Clear()
+	if p.format == FormatMarkdown {
+		if total {
+			fmt.Fprintf(p.out, "%s|\n", markdownTotalRow(cells))
+		} else {
+			fmt.Fprintf(p.out, "| %s |\n", strings.Join(cells, " | "))
+		}
+		return
+	}
+	var line strings.Builder
+	for i, cell := range cells {
+		line.WriteString(cell)
+		if i < len(cells)-1 {
+			line.WriteString(strings.Repeat(" ", max(p.widths[i]-len([]rune(cell)), 0)+3))
+		}
+	}
+	text := strings.TrimRight(line.String(), " ")
+	if p.colors != nil {
+		text = color + text + colorReset
+	}
+	fmt.Fprintln(p.out, text)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStreamPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := newStreamPrinter(&buf, OutputTypeRequests, FormatTable, true, false, false, nil, nil, false)
+
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "prod", Type: "Deployment", CurrentReplicas: 2, MaxReplicas: 4,
+		Requests: ResourceMetrics{CPU: 200, Memory: 256 * 1024 * 1024}})
+	first := "NAME                                       TYPE         NAMESPACE              REPLICAS   CPU            MEMORY\n" +
+		"web                                        Deployment   prod                   2/4        200m           256.00 MB\n"
+	if got := buf.String(); got != first {
+		t.Fatalf("after the first workload got\n%q\nwant\n%q", got, first)
+	}
+
+	p.Add(DeploymentMetrics{Name: "report", Namespace: "prod", Type: "CronJob", Suspended: true, CurrentReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 100, Memory: 128 * 1024 * 1024}})
+	p.Flush()
+	want := first +
+		"report                                     CronJob (suspended)   prod                   1/1        100m           128.00 MB\n" +
+		"TOTAL (CronJob)                                                                           100m           128.00 MB\n" +
+		"TOTAL (Deployment)                                                                        200m           256.00 MB\n" +
+		"TOTAL                                                                                     300m           384.00 MB\n"
+	if got := buf.String(); got != want {
+		t.Errorf("streamed table =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStreamPrinterMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	p := newStreamPrinter(&buf, OutputTypeUsage, FormatMarkdown, false, false, false, nil, nil, false)
+	p.Add(DeploymentMetrics{Name: "web", Namespace: "prod", CurrentReplicas: 2, MaxReplicas: 4,
+		Usage:    ResourceMetrics{CPU: 50, Memory: 64 * 1024 * 1024},
+		Requests: ResourceMetrics{CPU: 200, Memory: 256 * 1024 * 1024}})
+	p.Flush()
+
+	want := "| DEPLOYMENT | NAMESPACE | REPLICAS | CPU | MEMORY | CPU% | MEM% |\n" +
+		"| --- | --- | --- | --- | --- | --- | --- |\n" +
+		"| web | prod | 2/4 | 50m | 64.00 MB | 25% | 25% |\n" +
+		"| **TOTAL** | | | **50m** | **64.00 MB** | **25%** | **25%** |\n"
+	if got := buf.String(); got != want {
+		t.Errorf("streamed markdown =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStreamPrinterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	newStreamPrinter(&buf, OutputTypeRequests, FormatTable, false, false, false, nil, nil, false).Flush()
+	if got := buf.String(); got != "No deployments found\n" {
+		t.Errorf("empty stream = %q, want No deployments found", got)
+	}
+}
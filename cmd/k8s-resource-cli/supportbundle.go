@@ -0,0 +1,271 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// supportBundleDir is the directory every file of a bundle is unpacked into
+const supportBundleDir = "k8s-resource-cli-support"
+
+// redactedValue replaces whatever a bundle must not carry
+const redactedValue = "REDACTED"
+
+// redactedKeys are object fields whose values identify the cluster, its
+// workloads or its people rather than describe how the API behaves. The
+// resource figures the tool reads are left alone, since they are what a
+// bug report is about.
+var redactedKeys = map[string]bool{
+	"name": true, "namespace": true, "generateName": true, "uid": true, "resourceVersion": true, "selfLink": true,
+	"image": true, "command": true, "args": true, "value": true, "nodeName": true, "hostname": true, "subdomain": true,
+	"hostIP": true, "podIP": true, "ip": true, "serviceAccountName": true, "serviceAccount": true,
+	"secretName": true, "claimName": true, "containerID": true, "imageID": true, "message": true,
+}
+
+// redactedMaps are object fields whose keys are kept but values are not
+var redactedMaps = map[string]bool{"labels": true, "annotations": true, "matchLabels": true, "nodeSelector": true}
+
+// droppedKeys are object fields left out of a bundle altogether
+var droppedKeys = map[string]bool{"managedFields": true, "envFrom": true}
+
+// redactJSON returns a decoded JSON document with identifying values
+// replaced, see redactedKeys
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			switch {
+			case droppedKeys[key]:
+			case redactedKeys[key]:
+				if _, isString := value.(string); isString {
+					redacted[key] = redactedValue
+				} else if _, isList := value.([]interface{}); isList {
+					redacted[key] = []interface{}{redactedValue}
+				} else {
+					redacted[key] = redactJSON(value)
+				}
+			case redactedMaps[key]:
+				if m, ok := value.(map[string]interface{}); ok {
+					masked := make(map[string]interface{}, len(m))
+					for k := range m {
+						masked[k] = redactedValue
+					}
+					redacted[key] = masked
+				}
+			default:
+				redacted[key] = redactJSON(value)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactJSON(item)
+		}
+		return redacted
+	}
+	return v
+}
+
+// redactedSample encodes an API object as indented JSON with identifying
+// values redacted
+func redactedSample(obj interface{}) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(redactJSON(decoded), "", "  ")
+}
+
+// supportBundle collects the files of a bundle in the order they are added
+type supportBundle struct {
+	names []string
+	files map[string][]byte
+}
+
+func newSupportBundle() *supportBundle {
+	return &supportBundle{files: make(map[string][]byte)}
+}
+
+func (b *supportBundle) add(name string, data []byte) {
+	if _, ok := b.files[name]; !ok {
+		b.names = append(b.names, name)
+	}
+	b.files[name] = data
+}
+
+// archive packs the files into a gzipped tarball under supportBundleDir
+func (b *supportBundle) archive(modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range b.names {
+		data := b.files[name]
+		header := &tar.Header{Name: supportBundleDir + "/" + name, Mode: 0o644, Size: int64(len(data)), ModTime: modTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// runSupportBundleCommand implements the "support-bundle" subcommand. It
+// probes the cluster the way a report would and writes what it found,
+// without names, images or environment values, to a tarball for bug reports.
+func runSupportBundleCommand(args []string) {
+	var kubeconfig string
+	var kubeContext string
+	var namespace string
+	var outputPath string
+
+	now := time.Now()
+	fs := flag.NewFlagSet("support-bundle", flag.ContinueOnError)
+	fs.StringVar(&kubeconfig, "kubeconfig", defaultKubeconfigPath(), "Path to kubeconfig file")
+	fs.StringVar(&kubeContext, "context", "", "Kubeconfig context to use (defaults to the current context)")
+	fs.StringVar(&namespace, "namespace", "", "Namespace to sample workloads from (defaults to current context or 'default')")
+	fs.StringVar(&outputPath, "output", "k8s-resource-cli-support-"+now.UTC().Format("20060102-150405")+".tar.gz", "Where to write the bundle")
+	parseFlags(fs, args)
+
+	bundle := newSupportBundle()
+	bundle.add("version.txt", []byte(fmt.Sprintf("k8s-resource-cli %s\n%s %s/%s\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)))
+
+	config, err := buildRESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building kubeconfig: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	metricsClientset, err := versioned.NewForConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating metrics client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if namespace == "" {
+		if namespace, err = getNamespaceFromKubeconfig(kubeconfig, kubeContext); err != nil {
+			namespace = "default"
+		}
+	}
+
+	// Errors quote the API server's address, which stays out of the bundle
+	sanitize := func(err error) string {
+		return strings.ReplaceAll(err.Error(), config.Host, "API-SERVER")
+	}
+	var timings bytes.Buffer
+	tw := tabwriter.NewWriter(&timings, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "PROBE\tDURATION\tRESULT\n")
+	probe := func(name string, fn func() error) {
+		start := time.Now()
+		err := fn()
+		result := "ok"
+		if err != nil {
+			result = sanitize(err)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, time.Since(start).Round(time.Millisecond), result)
+	}
+
+	ctx := context.Background()
+	probe("server version", func() error {
+		info, err := clientset.Discovery().ServerVersion()
+		if err == nil {
+			bundle.add("server-version.txt", []byte(fmt.Sprintf("%s %s\n", info.GitVersion, info.Platform)))
+		}
+		return err
+	})
+	probe("api discovery", func() error {
+		caps, err := detectCapabilities(clientset)
+		var out bytes.Buffer
+		printCapabilities(&out, caps)
+		if err != nil {
+			fmt.Fprintf(&out, "\nDiscovery failed, all APIs assumed: %s\n", sanitize(err))
+		}
+		bundle.add("capabilities.txt", out.Bytes())
+		return err
+	})
+	sampleOptions := metav1.ListOptions{Limit: 1}
+	probe("list deployments", func() error {
+		list, err := clientset.AppsV1().Deployments(namespace).List(ctx, sampleOptions)
+		if err == nil && len(list.Items) > 0 {
+			err = addSample(bundle, "samples/deployment.json", list.Items[0])
+		}
+		return err
+	})
+	probe("list pods", func() error {
+		list, err := clientset.CoreV1().Pods(namespace).List(ctx, sampleOptions)
+		if err == nil && len(list.Items) > 0 {
+			err = addSample(bundle, "samples/pod.json", list.Items[0])
+		}
+		return err
+	})
+	probe("list horizontalpodautoscalers", func() error {
+		list, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, sampleOptions)
+		if err == nil && len(list.Items) > 0 {
+			err = addSample(bundle, "samples/hpa.json", list.Items[0])
+		}
+		return err
+	})
+	probe("list pod metrics", func() error {
+		list, err := metricsClientset.MetricsV1beta1().PodMetricses(namespace).List(ctx, sampleOptions)
+		if err == nil && len(list.Items) > 0 {
+			err = addSample(bundle, "samples/pod-metrics.json", list.Items[0])
+		}
+		return err
+	})
+	probe("list nodes", func() error {
+		_, err := clientset.CoreV1().Nodes().List(ctx, sampleOptions)
+		return err
+	})
+	tw.Flush()
+	bundle.add("timings.txt", timings.Bytes())
+
+	data, err := bundle.archive(now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating support bundle: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if err := writeFileAtomic(outputPath, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing support bundle: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s; look it over before attaching it to a bug report\n", outputPath)
+}
+
+func addSample(bundle *supportBundle, name string, obj interface{}) error {
+	data, err := redactedSample(obj)
+	if err != nil {
+		return err
+	}
+	bundle.add(name, data)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRedactedSample(t *testing.T) {
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "payments-api", Namespace: "billing", UID: "1234",
+			Labels:        map[string]string{"team": "payments"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:    "api",
+			Image:   "registry.internal.example.com/payments/api:v42",
+			Command: []string{"/api", "--db=postgres://secret"},
+			Env:     []corev1.EnvVar{{Name: "DB_PASSWORD", Value: "hunter2"}},
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("250m"),
+			}},
+		}}}}},
+	}
+
+	data, err := redactedSample(deployment)
+	if err != nil {
+		t.Fatalf("redactedSample() error = %v", err)
+	}
+	sample := string(data)
+	for _, secret := range []string{"payments-api", "billing", "1234", "payments", "registry.internal", "postgres://secret", "hunter2", "DB_PASSWORD", "managedFields"} {
+		if strings.Contains(sample, secret) {
+			t.Errorf("sample contains %q:\n%s", secret, sample)
+		}
+	}
+	for _, kept := range []string{`"team": "REDACTED"`, `"cpu": "250m"`} {
+		if !strings.Contains(sample, kept) {
+			t.Errorf("sample lacks %s:\n%s", kept, sample)
+		}
+	}
+}
+
+func TestSupportBundleArchive(t *testing.T) {
+	bundle := newSupportBundle()
+	bundle.add("version.txt", []byte("k8s-resource-cli dev\n"))
+	bundle.add("timings.txt", []byte("PROBE\n"))
+	bundle.add("version.txt", []byte("k8s-resource-cli v1.0.0\n"))
+
+	data, err := bundle.archive(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("archive() error = %v", err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("bundle is not gzipped: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	contents := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("bundle is not a tarball: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		names = append(names, header.Name)
+		contents[header.Name] = string(body)
+	}
+	want := []string{supportBundleDir + "/version.txt", supportBundleDir + "/timings.txt"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("bundle files = %v, want %v", names, want)
+	}
+	if got := contents[supportBundleDir+"/version.txt"]; got != "k8s-resource-cli v1.0.0\n" {
+		t.Errorf("version.txt = %q, want the last one added", got)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to --template files on top of the text/template builtins
+var templateFuncs = template.FuncMap{
+	"cpu":    formatCPU,
+	"memory": formatMemory,
+}
+
+// templateData is what a --template file is executed against. MaxRequests
+// on items and totals is the effective value, as in JSON output.
+type templateData struct {
+	Metadata   *runMetadata
+	Items      []DeploymentMetrics
+	Total      jsonTotals
+	OutputType string
+}
+
+func parseReportTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+}
+
+// outputTemplate parses a kubectl-style -o go-template=TEMPLATE or
+// go-template-file=PATH value. Other values are output types and give nil.
+func outputTemplate(value string) (*template.Template, error) {
+	if text, ok := strings.CutPrefix(value, "go-template="); ok {
+		return template.New("go-template").Funcs(templateFuncs).Parse(text)
+	}
+	if path, ok := strings.CutPrefix(value, "go-template-file="); ok {
+		return parseReportTemplate(path)
+	}
+	return nil, nil
+}
+
+// templatePrinter renders every collected workload through a user template
+type templatePrinter struct {
+	out  io.Writer
+	tmpl *template.Template
+	data templateData
+}
+
+func newTemplatePrinter(out io.Writer, tmpl *template.Template, outputType string, meta *runMetadata) *templatePrinter {
+	return &templatePrinter{out: out, tmpl: tmpl, data: templateData{Metadata: meta, OutputType: outputType}}
+}
+
+func (p *templatePrinter) Add(dm DeploymentMetrics) {
+	dm.MaxRequests = dm.EffectiveMaxRequests()
+	p.data.Items = append(p.data.Items, dm)
+	p.data.Total.add(dm)
+}
+
+func (p *templatePrinter) Flush() {
+	if err := p.tmpl.Execute(p.out, p.data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error rendering template: %v\n", err)
+		os.Exit(exitUsage)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplatePrinter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	report := `{{range .Items}}* {{.Namespace}}/{{.Name}}: {{cpu .Requests.CPU}}, {{memory .MaxRequests.Memory}} max
+{{end}}Total ({{.OutputType}}): {{cpu .Total.Requests.CPU}}
+`
+	if err := os.WriteFile(path, []byte(report), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := parseReportTemplate(path)
+	if err != nil {
+		t.Fatalf("parseReportTemplate() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	p := newTemplatePrinter(&buf, tmpl, OutputTypeRequests, nil)
+	p.Add(DeploymentMetrics{
+		Name: "web", Namespace: "prod", DesiredReplicas: 2, MaxReplicas: 4,
+		Requests: ResourceMetrics{CPU: 500, Memory: 1048576}, MaxRequests: ResourceMetrics{CPU: 1000, Memory: 2097152},
+	})
+	p.Add(DeploymentMetrics{
+		Name: "worker", Namespace: "prod", DesiredReplicas: 1, MaxReplicas: 1,
+		Requests: ResourceMetrics{CPU: 750, Memory: 1048576},
+	})
+	p.Flush()
+
+	want := "* prod/web: 500m, 2.00 MB max\n" +
+		"* prod/worker: 750m, 1.00 MB max\n" +
+		"Total (requests): 1.25 cores\n"
+	if buf.String() != want {
+		t.Errorf("template output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestParseReportTemplateInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(path, []byte("{{range .Items}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseReportTemplate(path); err == nil {
+		t.Error("parseReportTemplate() on invalid template should return an error")
+	}
+}
+
+func TestOutputTemplate(t *testing.T) {
+	for _, value := range []string{OutputTypeRequests, "", "go-template"} {
+		if tmpl, err := outputTemplate(value); tmpl != nil || err != nil {
+			t.Errorf("outputTemplate(%q) = %v, %v, want nil, nil", value, tmpl, err)
+		}
+	}
+	if _, err := outputTemplate("go-template={{range .Items}"); err == nil {
+		t.Error("outputTemplate() on invalid inline template should return an error")
+	}
+
+	path := filepath.Join(t.TempDir(), "names.tmpl")
+	if err := os.WriteFile(path, []byte("{{range .Items}}{{.Name}} {{end}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"go-template={{range .Items}}{{.Name}} {{end}}", "go-template-file=" + path} {
+		tmpl, err := outputTemplate(value)
+		if err != nil || tmpl == nil {
+			t.Fatalf("outputTemplate(%q) = %v, %v", value, tmpl, err)
+		}
+		var buf bytes.Buffer
+		p := newTemplatePrinter(&buf, tmpl, OutputTypeRequests, nil)
+		p.Add(DeploymentMetrics{Name: "web"})
+		p.Add(DeploymentMetrics{Name: "worker"})
+		p.Flush()
+		if buf.String() != "web worker " {
+			t.Errorf("outputTemplate(%q) output = %q, want %q", value, buf.String(), "web worker ")
+		}
+	}
+}
+
+// TestTemplateFields pins the field names documented for templates, so a
+// rename that would break users' templates fails here
+func TestTemplateFields(t *testing.T) {
+	fields := `{{range .Items}}{{.Name}} {{.Namespace}} {{.Type}} {{.Source}} {{.UID}} {{.Labels}} ` +
+		`{{.CurrentReplicas}} {{.DesiredReplicas}} {{.MaxReplicas}} {{.Usage.CPU}} {{.Requests.Memory}} {{.MaxRequests.CPU}} ` +
+		`{{.JobRuns}} {{.Suspended}} {{.Schedule}} {{.TargetCPU}} {{.TargetMemory}} {{.ScaledContainer}} {{.ScaledRequests}} ` +
+		`{{.GPUs}} {{.MachineType}} {{.OS}} {{.Owners}}{{end}}` +
+		`{{.Total.Usage.CPU}} {{.Total.Requests.Memory}} {{.Total.MaxRequests.CPU}} {{.OutputType}} {{.Metadata.Version}}`
+	tmpl, err := outputTemplate("go-template=" + fields)
+	if err != nil {
+		t.Fatalf("outputTemplate() error = %v", err)
+	}
+	data := templateData{Metadata: &runMetadata{Version: "dev"}, Items: []DeploymentMetrics{{Name: "web"}}, OutputType: OutputTypeRequests}
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		t.Errorf("template over the documented fields failed: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// othersName names the row --top collapses the remaining workloads into
+const othersName = "OTHERS"
+
+// topPrinter passes on only the n largest workloads, in the order they
+// arrived, and folds the rest into a single OTHERS row so totals still add
+// up. Size is the figure the output type shows for key, CPU unless key is
+// memory or usage-percent.
+type topPrinter struct {
+	next        resultPrinter
+	n           int
+	key         string
+	outputType  string
+	deployments []DeploymentMetrics
+}
+
+func (p *topPrinter) Add(dm DeploymentMetrics) {
+	p.deployments = append(p.deployments, dm)
+}
+
+func (p *topPrinter) Flush() {
+	key := p.key
+	if key != SortByMemory && key != SortByUsagePercent {
+		key = SortByCPU
+	}
+	bySize := make([]int, len(p.deployments))
+	for i := range bySize {
+		bySize[i] = i
+	}
+	sort.SliceStable(bySize, func(i, j int) bool {
+		return sortValue(p.deployments[bySize[i]], key, p.outputType) > sortValue(p.deployments[bySize[j]], key, p.outputType)
+	})
+	keep := make(map[int]bool)
+	for _, i := range bySize[:min(p.n, len(bySize))] {
+		keep[i] = true
+	}
+
+	var rest []DeploymentMetrics
+	for i, dm := range p.deployments {
+		if keep[i] {
+			p.next.Add(dm)
+		} else {
+			rest = append(rest, dm)
+		}
+	}
+	if len(rest) > 0 {
+		p.next.Add(collapseOthers(rest))
+	}
+	p.next.Flush()
+}
+
+// collapseOthers sums workloads into one row. The type is kept when they all
+// share it. DesiredReplicas stays zero so the summed effective max requests
+// are what max-requests reports; GPUs are per replica and can't be summed,
+// so they are left out, as are the per-OS subtotals.
+func collapseOthers(deployments []DeploymentMetrics) DeploymentMetrics {
+	others := DeploymentMetrics{
+		Name:      fmt.Sprintf("%s (%d)", othersName, len(deployments)),
+		Namespace: "-",
+		Type:      deployments[0].Type,
+		Source:    deployments[0].Source,
+	}
+	for _, dm := range deployments {
+		if dm.Type != others.Type {
+			others.Type = "Mixed"
+		}
+		if dm.Source != others.Source {
+			others.Source = "-"
+		}
+		others.CurrentReplicas += dm.CurrentReplicas
+		others.MaxReplicas += dm.MaxReplicas
+		others.Usage.add(dm.Usage)
+		others.Requests.add(dm.Requests)
+		others.Limits.add(dm.Limits)
+		others.MaxRequests.add(dm.EffectiveMaxRequests())
+	}
+	return others
+}
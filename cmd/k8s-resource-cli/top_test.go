@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopPrinter(t *testing.T) {
+	deployments := []DeploymentMetrics{
+		{Name: "a", Type: "Deployment", Requests: ResourceMetrics{CPU: 100, Memory: 4096}},
+		{Name: "b", Type: "Deployment", Requests: ResourceMetrics{CPU: 900, Memory: 1024}},
+		{Name: "c", Type: "CronJob", Requests: ResourceMetrics{CPU: 300, Memory: 2048}},
+		{Name: "d", Type: "Deployment", Requests: ResourceMetrics{CPU: 500, Memory: 512}},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		key  string
+		want []string
+	}{
+		{"cpu", 2, "", []string{"b", "d", "OTHERS (2)"}},
+		{"memory", 1, SortByMemory, []string{"a", "OTHERS (3)"}},
+		{"name key sizes by cpu", 3, SortByName, []string{"b", "c", "d", "OTHERS (1)"}},
+		{"n beyond rows", 10, "", []string{"a", "b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingPrinter{}
+			p := &topPrinter{next: next, n: tt.n, key: tt.key, outputType: OutputTypeRequests}
+			for _, dm := range deployments {
+				p.Add(dm)
+			}
+			p.Flush()
+			if !reflect.DeepEqual(next.names, tt.want) || !next.flushed {
+				t.Errorf("rows = %v (flushed %v), want %v", next.names, next.flushed, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollapseOthers(t *testing.T) {
+	others := collapseOthers([]DeploymentMetrics{
+		{Name: "web", Type: "Deployment", CurrentReplicas: 2, DesiredReplicas: 2, MaxReplicas: 4,
+			Requests: ResourceMetrics{CPU: 200}, MaxRequests: ResourceMetrics{CPU: 400}},
+		{Name: "report", Type: "CronJob", Suspended: true,
+			Requests: ResourceMetrics{CPU: 100}},
+		{Name: "api", Type: "Deployment", CurrentReplicas: 1, DesiredReplicas: 1, MaxReplicas: 1,
+			Requests: ResourceMetrics{CPU: 300}},
+	})
+
+	if others.Name != "OTHERS (3)" || others.Type != "Mixed" || others.CurrentReplicas != 3 || others.MaxReplicas != 5 {
+		t.Errorf("collapseOthers() = %+v", others)
+	}
+	if others.Requests.CPU != 600 {
+		t.Errorf("Requests.CPU = %d, want 600", others.Requests.CPU)
+	}
+	if got := others.EffectiveMaxRequests().CPU; got != 700 {
+		t.Errorf("EffectiveMaxRequests().CPU = %d, want the 700 summed per workload", got)
+	}
+}
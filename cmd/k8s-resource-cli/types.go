@@ -2,6 +2,9 @@ package main
 
 import (
 	"net/http"
+	"time"
+
+	"k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 const (
@@ -9,32 +12,126 @@ const (
 	OutputTypeRequests    = "requests"
 	OutputTypeMaxRequests = "max-requests"
 	OutputTypeCombined    = "combined"
+	OutputTypeIdle        = "idle" // requests minus usage
+	OutputTypeAll         = "all"  // usage, requests, limits and max-requests side by side
+	OutputWide            = "wide" // -o wide: requests with extra columns
 
 	FormatTable    = "table"
 	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+	FormatProm     = "prom"
+	FormatJSONL    = "jsonl"
+	FormatDOT      = "dot"
+
+	GroupByNamespace     = "namespace"
+	GroupByPriorityClass = "priority-class"
+
+	BreakdownPod = "pod"
+
+	PromByWorkload  = "workload"  // a series per workload: namespace, workload and type labels
+	PromByType      = "type"      // summed per namespace and workload type
+	PromByNamespace = "namespace" // summed per namespace
+	PromByCluster   = "cluster"   // summed over the report, no labels
+
+	SourceKubernetes = "kubernetes"
+	SourcePorter     = "porter"
 )
 
 type ResourceMetrics struct {
-	CPU    int64 // in millicores
-	Memory int64 // in bytes
+	CPU    int64 `json:"cpu_millicores"` // in millicores
+	Memory int64 `json:"memory_bytes"`   // in bytes
+}
+
+func (rm *ResourceMetrics) add(other ResourceMetrics) {
+	rm.CPU += other.CPU
+	rm.Memory += other.Memory
+}
+
+// idleMetrics is the part of the requests that usage leaves unused, negative
+// when usage exceeds the requests
+func idleMetrics(usage, requests ResourceMetrics) ResourceMetrics {
+	return ResourceMetrics{CPU: requests.CPU - usage.CPU, Memory: requests.Memory - usage.Memory}
 }
 
 type DeploymentMetrics struct {
-	Name            string
-	Namespace       string
-	Type            string // "Deployment" or "CronJob"
-	CurrentReplicas int32
-	DesiredReplicas int32
-	MaxReplicas     int32
-	Usage           ResourceMetrics
-	Requests        ResourceMetrics
-	MaxRequests     ResourceMetrics
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	Type              string            `json:"type"`          // "Deployment" or "CronJob"
+	Source            string            `json:"source"`        // SourceKubernetes or SourcePorter
+	UID               string            `json:"uid,omitempty"` // Kubernetes object UID, new whenever the object is recreated; empty in Porter mode
+	CurrentReplicas   int32             `json:"current_replicas"`
+	DesiredReplicas   int32             `json:"desired_replicas"`
+	MaxReplicas       int32             `json:"max_replicas"`
+	ReadyReplicas     int32             `json:"ready_replicas"`         // replicas passing their readiness probes
+	AvailableReplicas int32             `json:"available_replicas"`     // replicas ready for at least minReadySeconds
+	MinReplicas       int32             `json:"min_replicas,omitempty"` // HPA min replicas
+	Usage             ResourceMetrics   `json:"usage"`
+	Requests          ResourceMetrics   `json:"requests"`
+	Limits            ResourceMetrics   `json:"limits"`
+	MaxRequests       ResourceMetrics   `json:"max_requests"`
+	Labels            map[string]string `json:"labels,omitempty"`                    // workload object labels; empty in Porter mode
+	JobRuns           int               `json:"job_runs,omitempty"`                  // completed CronJob runs averaged into Requests (--job-history)
+	Suspended         bool              `json:"suspended,omitempty"`                 // CronJob with spec.suspend set
+	Paused            bool              `json:"paused,omitempty"`                    // Deployment with spec.paused set
+	Schedule          string            `json:"schedule,omitempty"`                  // CronJob spec.schedule
+	NextRun           *time.Time        `json:"next_run,omitempty"`                  // next time the CronJob schedule fires; none when suspended
+	LastSuccessfulRun *time.Time        `json:"last_successful_run,omitempty"`       // CronJob status.lastSuccessfulTime
+	TargetCPU         int32             `json:"target_cpu_utilization,omitempty"`    // HPA or Porter autoscaling target CPU utilization percentage
+	TargetMemory      int32             `json:"target_memory_utilization,omitempty"` // Porter autoscaling target memory utilization percentage
+	ScaledContainer   string            `json:"scaled_container,omitempty"`          // container of an HPA ContainerResource CPU target
+	ScaledRequests    *ResourceMetrics  `json:"scaled_container_requests,omitempty"` // per-pod requests of ScaledContainer
+	GPUs              int64             `json:"gpus_per_replica,omitempty"`          // GPUs each replica requests
+	MachineType       string            `json:"machine_type,omitempty"`              // Porter node group machine type
+	OS                string            `json:"os,omitempty"`                        // kubernetes.io/os the pods run on; empty in Porter mode
+	ExpectedPeak      *ResourceMetrics  `json:"expected_peak,omitempty"`             // expected-peak annotations on the workload
+	PodRequests       *ResourceMetrics  `json:"pod_requests,omitempty"`              // requests of one pod from the pod template
+	Unschedulable     bool              `json:"unschedulable,omitempty"`             // a pod requests more than the largest node can allocate
+	Canary            bool              `json:"canary,omitempty"`                    // canary of a progressive rollout, by label or Flagger naming
+	Proportional      bool              `json:"cluster_proportional,omitempty"`      // scaled with cluster size by a cluster-proportional-autoscaler
+	NamespaceLabels   map[string]string `json:"namespace_labels,omitempty"`          // labels of the workload's namespace picked by --namespace-labels
+	QOSClass          string            `json:"qos_class,omitempty"`                 // QoS class of the pod template
+	PriorityClass     string            `json:"priority_class,omitempty"`            // priorityClassName of the pod template
+	Containers        int               `json:"containers,omitempty"`                // containers in the pod template, not counting init containers
+	Images            []string          `json:"images,omitempty"`                    // distinct container images of the pod template
+	ImageBytes        int64             `json:"image_bytes,omitempty"`               // compressed size of Images in their registries (--image-sizes)
+	CarbonGrams       float64           `json:"carbon_gco2e,omitempty"`              // estimated emissions over --carbon-period (--carbon)
+	Nodes             int               `json:"nodes,omitempty"`                     // distinct nodes the running pods are spread over
+	Restarts          int32             `json:"restarts,omitempty"`                  // container restarts summed over the pods, init containers included
+	DependsOn         []string          `json:"depends_on,omitempty"`                // namespace/name of the workloads its depends-on annotation names
+	Owners            []*ownerNode      `json:"owners,omitempty"`                    // ReplicaSets/Jobs and Pods below the workload (--owner-graph)
+}
+
+// Degraded reports whether some of a Kubernetes Deployment's replicas
+// aren't ready. Other workloads have no readiness status to go by.
+func (dm DeploymentMetrics) Degraded() bool {
+	return dm.Source == SourceKubernetes && dm.Type == "Deployment" && dm.ReadyReplicas < dm.CurrentReplicas
+}
+
+// ScaledToZero reports whether the workload is a Deployment that asks for
+// no replicas, dormant but possibly still holding HPA max capacity
+func (dm DeploymentMetrics) ScaledToZero() bool {
+	return dm.Type == "Deployment" && dm.DesiredReplicas == 0
+}
+
+// Key uniquely identifies a workload as kind/namespace/name
+func (dm DeploymentMetrics) Key() string {
+	return dm.Type + "/" + dm.Namespace + "/" + dm.Name
+}
+
+// EffectiveMaxRequests is what the workload requests at max scale: the HPA
+// projection when it scales beyond desired replicas, nothing when it can't
+// run at all, current requests otherwise
+func (dm DeploymentMetrics) EffectiveMaxRequests() ResourceMetrics {
+	if dm.MaxReplicas > dm.DesiredReplicas || dm.MaxReplicas == 0 {
+		return dm.MaxRequests
+	}
+	return dm.Requests
 }
 
 // Porter API data structures
 type PorterApplication struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID   string `json:"id" porter:"required"`
+	Name string `json:"name" porter:"required"`
 }
 
 type PorterListApplicationsResponse struct {
@@ -51,16 +148,26 @@ type PorterApplicationDetail struct {
 type PorterService struct {
 	Name         string             `json:"name"`
 	Type         string             `json:"type"`
-	CPUCores     float64            `json:"cpu_cores"`
-	RAMMegabytes int64              `json:"ram_megabytes"`
+	CPUCores     float64            `json:"cpu_cores" porter:"required"`
+	RAMMegabytes int64              `json:"ram_megabytes" porter:"required"`
 	Instances    int32              `json:"instances"`
 	Autoscaling  *PorterAutoscaling `json:"autoscaling,omitempty"`
+	GPU          *PorterGPU         `json:"gpu,omitempty"`
+	MachineType  string             `json:"machine_type,omitempty"`
+}
+
+// PorterGPU is the GPU request of a service scheduled on a GPU node group
+type PorterGPU struct {
+	Enabled        bool  `json:"enabled"`
+	GPUCoresNvidia int64 `json:"gpu_cores_nvidia"`
 }
 
 type PorterAutoscaling struct {
-	Enabled      bool  `json:"enabled"`
-	MinInstances int32 `json:"min_instances"`
-	MaxInstances int32 `json:"max_instances"`
+	Enabled                bool  `json:"enabled"`
+	MinInstances           int32 `json:"min_instances"`
+	MaxInstances           int32 `json:"max_instances"`
+	CPUThresholdPercent    int32 `json:"cpu_threshold_percent"`
+	MemoryThresholdPercent int32 `json:"memory_threshold_percent"`
 }
 
 type PorterDeploymentTarget struct {
@@ -84,14 +191,21 @@ type PorterListClustersResponse struct {
 	Clusters []PorterCluster `json:"clusters"`
 }
 
+type PorterKubeconfigResponse struct {
+	Kubeconfig []byte `json:"kubeconfig" porter:"secret"`
+}
+
 type PorterClient struct {
 	BaseURL                 string
 	Token                   string
 	ProjectID               string
 	HTTPClient              *http.Client
-	Debug                   bool
+	UserAgent               string
+	Headers                 http.Header // extra headers sent with every request, e.g. for an auth proxy
 	deploymentTargetCache   map[string]*PorterDeploymentTarget
 	deploymentTargetsLoaded bool
 	clusterCache            map[int]*PorterCluster
 	clustersLoaded          bool
+	metricsClientCache      map[int]*versioned.Clientset
+	metricsClientErrors     map[int]error
 }
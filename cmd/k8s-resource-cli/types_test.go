@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -95,3 +96,71 @@ func TestPorterApplicationStruct(t *testing.T) {
 		t.Errorf("Name = %v, want my-app", app.Name)
 	}
 }
+
+func TestPorterAutoscalingUnmarshal(t *testing.T) {
+	var service PorterService
+	data := `{"name":"web","autoscaling":{"enabled":true,"min_instances":2,"max_instances":8,"cpu_threshold_percent":70,"memory_threshold_percent":85}}`
+	if err := json.Unmarshal([]byte(data), &service); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if service.Autoscaling == nil || service.Autoscaling.CPUThresholdPercent != 70 || service.Autoscaling.MemoryThresholdPercent != 85 {
+		t.Errorf("Autoscaling = %+v, want cpu 70%% and memory 85%% thresholds", service.Autoscaling)
+	}
+}
+
+func TestPorterGPUUnmarshal(t *testing.T) {
+	var service PorterService
+	data := `{"name":"inference","machine_type":"g4dn.xlarge","gpu":{"enabled":true,"gpu_cores_nvidia":2}}`
+	if err := json.Unmarshal([]byte(data), &service); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if service.MachineType != "g4dn.xlarge" {
+		t.Errorf("MachineType = %q, want g4dn.xlarge", service.MachineType)
+	}
+	if service.GPU == nil || !service.GPU.Enabled || service.GPU.GPUCoresNvidia != 2 {
+		t.Errorf("GPU = %+v, want 2 enabled GPUs", service.GPU)
+	}
+}
+
+func TestEffectiveMaxRequests(t *testing.T) {
+	requests := ResourceMetrics{CPU: 200, Memory: 1024}
+	maxRequests := ResourceMetrics{CPU: 500, Memory: 2560}
+
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want ResourceMetrics
+	}{
+		{"HPA scales beyond desired", DeploymentMetrics{DesiredReplicas: 2, MaxReplicas: 5, Requests: requests, MaxRequests: maxRequests}, maxRequests},
+		{"no HPA", DeploymentMetrics{DesiredReplicas: 2, MaxReplicas: 2, Requests: requests}, requests},
+		{"suspended cronjob counted as zero", DeploymentMetrics{Type: "CronJob", DesiredReplicas: 1, MaxReplicas: 0, Requests: requests, Suspended: true}, ResourceMetrics{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dm.EffectiveMaxRequests(); got != tt.want {
+				t.Errorf("EffectiveMaxRequests() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScaledToZero(t *testing.T) {
+	tests := []struct {
+		name string
+		dm   DeploymentMetrics
+		want bool
+	}{
+		{"scaled to zero with HPA max", DeploymentMetrics{Type: "Deployment", DesiredReplicas: 0, MaxReplicas: 5}, true},
+		{"running", DeploymentMetrics{Type: "Deployment", DesiredReplicas: 2, MaxReplicas: 5}, false},
+		{"cronjob between runs", DeploymentMetrics{Type: "CronJob", DesiredReplicas: 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dm.ScaledToZero(); got != tt.want {
+				t.Errorf("ScaledToZero() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
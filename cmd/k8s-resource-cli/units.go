@@ -0,0 +1,49 @@
+package main
+
+import "fmt"
+
+// Values for --units. The default keeps the long-standing output: memory
+// counted in powers of 1024 but labelled KB, MB and GB.
+const (
+	UnitsDefault = ""
+	UnitsRaw     = "raw"     // millicores and bytes, for feeding other tools
+	UnitsBinary  = "binary"  // KiB, MiB and GiB
+	UnitsDecimal = "decimal" // kB, MB and GB in powers of 1000
+)
+
+// displayUnits holds the --units value every CPU and memory figure is
+// formatted with
+var displayUnits = UnitsDefault
+
+func parseUnits(value string) (string, error) {
+	switch value {
+	case UnitsDefault, UnitsRaw, UnitsBinary, UnitsDecimal:
+		return value, nil
+	}
+	return "", fmt.Errorf("invalid --units '%s'. Must be 'raw', 'binary', or 'decimal'", value)
+}
+
+// memoryUnits is the step between memory units and their labels, smallest first
+type memoryUnits struct {
+	step   int64
+	labels [3]string
+}
+
+// unit returns the size in bytes of the i'th label
+func (m memoryUnits) unit(i int) int64 {
+	size := m.step
+	for ; i > 0; i-- {
+		size *= m.step
+	}
+	return size
+}
+
+func memoryScale() memoryUnits {
+	switch displayUnits {
+	case UnitsBinary:
+		return memoryUnits{1024, [3]string{"KiB", "MiB", "GiB"}}
+	case UnitsDecimal:
+		return memoryUnits{1000, [3]string{"kB", "MB", "GB"}}
+	}
+	return memoryUnits{1024, [3]string{"KB", "MB", "GB"}}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestFormatUnits(t *testing.T) {
+	defer func() { displayUnits = UnitsDefault }()
+
+	tests := []struct {
+		units      string
+		cpu        string
+		memory     string
+		memoryPair string
+	}{
+		{UnitsDefault, "1.50 cores", "1.50 MB", "1.00 / 1.50 MB"},
+		{UnitsRaw, "1500m", "1572864", "1048576 / 1572864"},
+		{UnitsBinary, "1.50 cores", "1.50 MiB", "1.00 / 1.50 MiB"},
+		{UnitsDecimal, "1.50 cores", "1.57 MB", "1.05 / 1.57 MB"},
+	}
+
+	for _, tt := range tests {
+		t.Run("units "+tt.units, func(t *testing.T) {
+			displayUnits = tt.units
+			if got := formatCPU(1500); got != tt.cpu {
+				t.Errorf("formatCPU() = %q, want %q", got, tt.cpu)
+			}
+			if got := formatMemory(1572864); got != tt.memory {
+				t.Errorf("formatMemory() = %q, want %q", got, tt.memory)
+			}
+			if got := formatMemoryPair(1048576, 1572864); got != tt.memoryPair {
+				t.Errorf("formatMemoryPair() = %q, want %q", got, tt.memoryPair)
+			}
+		})
+	}
+}
+
+func TestParseUnits(t *testing.T) {
+	if _, err := parseUnits("decimal"); err != nil {
+		t.Errorf("parseUnits(decimal) error = %v", err)
+	}
+	if _, err := parseUnits("si"); err == nil {
+		t.Error("parseUnits() should reject unknown units")
+	}
+}
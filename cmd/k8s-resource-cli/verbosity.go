@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Verbosity levels for -v. Each level includes the ones below it, so a
+// support bundle can stop at the detail it needs.
+const (
+	verbosityDecisions = 1 // what was skipped, assumed or fallen back on
+	verbosityTiming    = 2 // how long each collection phase took
+	verbosityTraces    = 3 // every Kubernetes and Porter API request
+	verbosityPayloads  = 4 // raw Porter API responses
+	maxVerbosity       = verbosityPayloads
+)
+
+// verbosity is the -v level of this run, set once from the command line
+var verbosity int
+
+// verbosityFlag is -v: on its own it means level 1, -v=N picks a level
+type verbosityFlag struct {
+	level *int
+}
+
+func (f verbosityFlag) String() string {
+	if f.level == nil {
+		return "0"
+	}
+	return strconv.Itoa(*f.level)
+}
+
+func (f verbosityFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*f.level = verbosityDecisions
+		return nil
+	case "false":
+		*f.level = 0
+		return nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 || level > maxVerbosity {
+		return fmt.Errorf("must be a level from 0 to %d", maxVerbosity)
+	}
+	*f.level = level
+	return nil
+}
+
+func (f verbosityFlag) IsBoolFlag() bool { return true }
+
+// quiet is --quiet: no warnings and no progress, for runs whose stderr
+// only matters when they fail
+var quiet bool
+
+// debugf writes a DEBUG line when the run is at least this verbose
+func debugf(level int, format string, args ...interface{}) {
+	if verbosity < level {
+		return
+	}
+	progress.Clear()
+	fmt.Fprintf(os.Stderr, "DEBUG - "+format+"\n", args...)
+}
+
+// warnf writes a Warning line, on a line of its own when progress is shown,
+// unless the run is quiet
+func warnf(format string, args ...interface{}) {
+	if quiet {
+		return
+	}
+	progress.Clear()
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// workerLog holds back the warnings of work running on other goroutines,
+// since partialf, warnf and debugf write to the progress line unguarded. The
+// goroutine that owns the output prints them with flush.
+type workerLog struct {
+	mu    sync.Mutex
+	lines []func()
+}
+
+func (l *workerLog) add(line func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+}
+
+func (l *workerLog) partialf(format string, args ...interface{}) {
+	l.add(func() { partialf(format, args...) })
+}
+
+func (l *workerLog) warnf(format string, args ...interface{}) {
+	l.add(func() { warnf(format, args...) })
+}
+
+func (l *workerLog) debugf(level int, format string, args ...interface{}) {
+	l.add(func() { debugf(level, format, args...) })
+}
+
+// flush prints what was logged, in order, and empties the log
+func (l *workerLog) flush() {
+	l.mu.Lock()
+	lines := l.lines
+	l.lines = nil
+	l.mu.Unlock()
+	for _, line := range lines {
+		line()
+	}
+}
+
+// timed starts timing a collection phase; calling the returned func logs
+// how long it took at verbosityTiming
+func timed(phase string) func() {
+	start := time.Now()
+	return func() {
+		debugf(verbosityTiming, "%s took %s", phase, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// tracingTransport logs each request with its status and duration at
+// verbosityTraces
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+// traceRequests wraps a transport so its requests are traced, or returns it
+// as is below verbosityTraces
+func traceRequests(next http.RoundTripper) http.RoundTripper {
+	if verbosity < verbosityTraces {
+		return next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return tracingTransport{next: next}
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		debugf(verbosityTraces, "%s %s failed after %s: %v", req.Method, req.URL, elapsed, err)
+		return resp, err
+	}
+	debugf(verbosityTraces, "%s %s %s in %s", req.Method, req.URL, resp.Status, elapsed)
+	return resp, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestVerbosityFlag(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{[]string{"-v"}, verbosityDecisions, false},
+		{[]string{"--v=3"}, verbosityTraces, false},
+		{[]string{"-v=0"}, 0, false},
+		{[]string{"-v=5"}, 0, true},
+		{[]string{"-v=high"}, 0, true},
+	}
+	for _, tt := range tests {
+		level := 0
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Var(verbosityFlag{&level}, "v", "")
+		err := fs.Parse(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if err == nil && level != tt.want {
+			t.Errorf("Parse(%v) level = %d, want %d", tt.args, level, tt.want)
+		}
+	}
+}
+
+func TestTraceRequests(t *testing.T) {
+	defer func(level int) { verbosity = level }(verbosity)
+
+	verbosity = verbosityTiming
+	if got := traceRequests(http.DefaultTransport); got != http.DefaultTransport {
+		t.Errorf("traceRequests() below -v=3 = %T, want the transport unchanged", got)
+	}
+	verbosity = verbosityTraces
+	if _, ok := traceRequests(nil).(tracingTransport); !ok {
+		t.Errorf("traceRequests() at -v=3 doesn't trace")
+	}
+}